@@ -11,7 +11,6 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
 	"github.com/weslien/grit/pkg/output"
-	"gopkg.in/yaml.v3"
 )
 
 var (
@@ -24,6 +23,7 @@ type PackageAnalysis struct {
 	Type         string            `json:"type"`
 	Version      string            `json:"version"`
 	Path         string            `json:"path"`
+	Owners       []string          `json:"owners,omitempty"`
 	Dependencies []string          `json:"dependencies"`
 	Dependents   []string          `json:"dependents"`
 	Issues       []string          `json:"issues"`
@@ -37,6 +37,7 @@ type PackageAnalysis struct {
 type WorkspaceAnalysis struct {
 	TotalPackages    int                        `json:"total_packages"`
 	PackagesByType   map[string]int            `json:"packages_by_type"`
+	PackagesByOwner  map[string]int            `json:"packages_by_owner"`
 	TotalDependencies int                      `json:"total_dependencies"`
 	CircularDeps     [][]string                `json:"circular_dependencies"`
 	OrphanPackages   []string                  `json:"orphan_packages"`
@@ -108,44 +109,52 @@ func init() {
 func performWorkspaceAnalysis(packages []grit.Config, cwd string, formatter *output.Formatter) WorkspaceAnalysis {
 	analysis := WorkspaceAnalysis{
 		PackagesByType: make(map[string]int),
+		PackagesByOwner: make(map[string]int),
 		Packages:       make(map[string]PackageAnalysis),
 		Issues:         []string{},
 		Suggestions:    []string{},
 	}
 
 	// Load root config
-	rootConfig, err := loadRootConfigForAnalysis(cwd)
+	rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
 	if err != nil && !jsonOutput {
 		formatter.Warning("Could not load root config")
 	}
 
-	// Build dependency maps
+	// Build dependency maps keyed by the shortest unambiguous package name
+	index := grit.NewIndex(packages)
 	depMap := make(map[string][]string)
 	dependentMap := make(map[string][]string)
 
 	// Analyze each package
-	for _, cfg := range packages {
-		if cfg.Package.Name == "" {
-			continue // Skip root config
-		}
+	for _, cfg := range index.Packages() {
+		name := index.Name(cfg.Package)
+		deps := resolveDependencyNames(cfg.Package, index)
 
 		analysis.TotalPackages++
-		depMap[cfg.Package.Name] = cfg.Package.Dependencies
-		analysis.TotalDependencies += len(cfg.Package.Dependencies)
+		depMap[name] = deps
+		analysis.TotalDependencies += len(deps)
 
 		// Build reverse dependency map
-		for _, dep := range cfg.Package.Dependencies {
-			dependentMap[dep] = append(dependentMap[dep], cfg.Package.Name)
+		for _, dep := range deps {
+			dependentMap[dep] = append(dependentMap[dep], name)
 		}
 
 		// Analyze individual package
-		pkgAnalysis := analyzePackage(cfg, rootConfig, cwd)
-		analysis.Packages[cfg.Package.Name] = pkgAnalysis
+		pkgAnalysis := analyzePackage(cfg, rootConfig)
+		pkgAnalysis.Name = name
+		pkgAnalysis.Dependencies = deps
+		analysis.Packages[name] = pkgAnalysis
 
 		// Count by type
 		if pkgAnalysis.Type != "" {
 			analysis.PackagesByType[pkgAnalysis.Type]++
 		}
+
+		// Count by owner
+		for _, owner := range pkgAnalysis.Owners {
+			analysis.PackagesByOwner[owner]++
+		}
 	}
 
 	// Detect circular dependencies
@@ -167,21 +176,18 @@ func performWorkspaceAnalysis(packages []grit.Config, cwd string, formatter *out
 	return analysis
 }
 
-func analyzePackage(cfg grit.Config, rootConfig *grit.RootConfig, cwd string) PackageAnalysis {
+func analyzePackage(cfg grit.Config, rootConfig *grit.RootConfig) PackageAnalysis {
 	pkgAnalysis := PackageAnalysis{
 		Name:         cfg.Package.Name,
+		Type:         cfg.Package.Type,
 		Version:      cfg.Package.Version,
 		Path:         cfg.Package.Path,
+		Owners:       cfg.Package.Owners,
 		Dependencies: cfg.Package.Dependencies,
 		Issues:       []string{},
 		Suggestions:  []string{},
 	}
 
-	// Determine package type
-	if rootConfig != nil {
-		pkgAnalysis.Type = getPackageTypeForAnalysis(cfg.Package.Path, rootConfig, cwd)
-	}
-
 	// Analyze package directory
 	pkgDir := filepath.Dir(cfg.Package.Path)
 	if stat, err := os.Stat(pkgDir); err == nil {
@@ -244,22 +250,9 @@ func analyzePackageHealth(cfg grit.Config, pkgDir string, rootConfig *grit.RootC
 
 	// Check for build configuration
 	if rootConfig != nil {
-		hasValidBuildCmd := false
-		if buildCmd, ok := cfg.Targets["build"]; ok && buildCmd != "" {
-			hasValidBuildCmd = true
-		} else {
-			// Check type-level build command
-			pkgType := getPackageTypeForAnalysis(cfg.Package.Path, rootConfig, "")
-			if typeConfig, ok := rootConfig.Types[pkgType]; ok {
-				if buildCmd, ok := typeConfig.Targets["build"]; ok && buildCmd != "" {
-					hasValidBuildCmd = true
-				}
-			}
-		}
-		
-		if !hasValidBuildCmd {
+		if _, ok := rootConfig.ResolveTarget(cfg, "build"); !ok {
 			issues = append(issues, "No build command configured")
-			suggestions = append(suggestions, "Add a build target to the package or type configuration")
+			suggestions = append(suggestions, "Add a build target to the package, type or root configuration")
 		}
 	}
 
@@ -364,6 +357,20 @@ func generateWorkspaceSuggestions(analysis WorkspaceAnalysis) ([]string, []strin
 		suggestions = append(suggestions, "Consider removing unused packages or adding them as dependencies")
 	}
 
+	// Check ownership once the workspace has started assigning owners
+	if len(analysis.PackagesByOwner) > 0 {
+		unowned := 0
+		for _, pkg := range analysis.Packages {
+			if len(pkg.Owners) == 0 {
+				unowned++
+			}
+		}
+		if unowned > 0 {
+			issues = append(issues, fmt.Sprintf("%d packages have no owners", unowned))
+			suggestions = append(suggestions, "Add owners to every package so each change has someone responsible for it")
+		}
+	}
+
 	// Check workspace structure
 	if analysis.TotalPackages > 50 {
 		suggestions = append(suggestions, "Consider using package groups or namespaces for better organization")
@@ -397,6 +404,21 @@ func displayAnalysis(analysis WorkspaceAnalysis, formatter *output.Formatter) {
 		}
 	}
 
+	// Package owners
+	if len(analysis.PackagesByOwner) > 0 {
+		owners := make([]string, 0, len(analysis.PackagesByOwner))
+		for owner := range analysis.PackagesByOwner {
+			owners = append(owners, owner)
+		}
+		sort.Strings(owners)
+
+		formatter.NewLine()
+		formatter.Info("Package Distribution by Owner:")
+		for _, owner := range owners {
+			formatter.Detail(fmt.Sprintf("• %s: %d packages", owner, analysis.PackagesByOwner[owner]))
+		}
+	}
+
 	// Issues
 	if len(analysis.Issues) > 0 {
 		formatter.NewLine()
@@ -446,6 +468,9 @@ func displayAnalysis(analysis WorkspaceAnalysis, formatter *output.Formatter) {
 			pkgAnalysis := analysis.Packages[pkg]
 			formatter.NewLine()
 			formatter.PackageInfo(pkgAnalysis.Name, pkgAnalysis.Version, pkgAnalysis.Type, pkgAnalysis.Dependencies)
+			if len(pkgAnalysis.Owners) > 0 {
+				formatter.Detail(fmt.Sprintf("Owners: %s", strings.Join(pkgAnalysis.Owners, ", ")))
+			}
 			
 			if len(pkgAnalysis.Issues) > 0 {
 				formatter.Warning("Issues:")
@@ -482,42 +507,3 @@ func outputJSON(analysis WorkspaceAnalysis) {
 	fmt.Printf("  \"orphan_packages\": %d\n", len(analysis.OrphanPackages))
 	fmt.Println("}")
 }
-
-func loadRootConfigForAnalysis(cwd string) (*grit.RootConfig, error) {
-	rootConfigPath := filepath.Join(cwd, "grit.yaml")
-	data, err := os.ReadFile(rootConfigPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var rootConfig grit.RootConfig
-	err = yaml.Unmarshal(data, &rootConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	return &rootConfig, nil
-}
-
-func getPackageTypeForAnalysis(packagePath string, rootConfig *grit.RootConfig, cwd string) string {
-	if cwd == "" {
-		var err error
-		cwd, err = os.Getwd()
-		if err != nil {
-			return ""
-		}
-	}
-
-	relPath, err := filepath.Rel(cwd, filepath.Dir(packagePath))
-	if err != nil {
-		return ""
-	}
-
-	for typeName, typeConfig := range rootConfig.Types {
-		if strings.Contains(relPath, typeConfig.PackageDir) {
-			return typeName
-		}
-	}
-
-	return ""
-}
\ No newline at end of file
@@ -1,11 +1,10 @@
 package cmd
 
 import (
-	"context"
 	"crypto/sha256"
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -15,16 +14,28 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
 	"github.com/weslien/grit/pkg/output"
-	"gopkg.in/yaml.v3"
 )
 
 var noCache bool
 var dirtyFlag bool // Add this variable declaration
+var buildFilters []string
 
 var buildCmd = &cobra.Command{
 	Use:   "build [type] [name]",
 	Short: "Build packages and their dependencies",
-	Long:  `Build packages respecting dependency order and utilizing build cache`,
+	Long: `Build packages respecting dependency order and utilizing build cache.
+
+A package can be selected either as [type] [name], as type/name or by its bare
+name when that is unambiguous. Its dependencies are built along with it.
+
+Packages can also be selected with --filter, see 'grit ls --help' for the
+filter syntax. The matching packages are built with their dependencies.
+
+Examples:
+  grit build                        # Build all packages
+  grit build lib utils              # Build lib/utils and its dependencies
+  grit build --filter tag:frontend  # Build the packages tagged frontend`,
+	Args: cobra.MaximumNArgs(2),
 	Run: func(cmd *cobra.Command, args []string) {
 		formatter := output.New()
 
@@ -45,6 +56,30 @@ var buildCmd = &cobra.Command{
 		}
 		formatter.Success(fmt.Sprintf("Loaded %d packages", len(packages)))
 
+		rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error loading root config: %v", err))
+			os.Exit(1)
+		}
+
+		index := grit.NewIndex(packages)
+		if len(args) > 0 {
+			packages, err = selectPackageWithDependencies(index, packageRefFromArgs(args))
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+				os.Exit(1)
+			}
+			formatter.Info(fmt.Sprintf("Selected %d packages", len(packages)))
+		} else if len(buildFilters) > 0 {
+			matched, err := filterPackages(index, buildFilters)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error selecting packages: %v", err))
+				os.Exit(1)
+			}
+			packages = withDependencies(index, matched)
+			formatter.Info(fmt.Sprintf("Selected %d packages matching the filter, %d with dependencies", len(matched), len(packages)))
+		}
+
 		// Define cacheDir here, before it's used in the dirty flag check
 		cacheDir := filepath.Join(cwd, ".grit", "cache")
 		if !noCache {
@@ -61,8 +96,9 @@ var buildCmd = &cobra.Command{
 			// First, build a reverse dependency map
 			reverseDeps := make(map[string][]string)
 			for _, cfg := range packages {
-				for _, depName := range cfg.Package.Dependencies {
-					reverseDeps[depName] = append(reverseDeps[depName], cfg.Package.Name)
+				depIDs, _ := index.Dependencies(cfg.Package)
+				for _, depID := range depIDs {
+					reverseDeps[depID] = append(reverseDeps[depID], cfg.Package.ID())
 				}
 			}
 
@@ -77,30 +113,30 @@ var buildCmd = &cobra.Command{
 				cfgDir := filepath.Dir(cfg.Package.Path)
 				newHash, err := calculatePackageHash(cfgDir)
 				if err != nil {
-					formatter.Warning(fmt.Sprintf("Could not calculate hash for %s: %v", cfg.Package.Name, err))
-					directlyDirty[cfg.Package.Name] = true
+					formatter.Warning(fmt.Sprintf("Could not calculate hash for %s: %v", index.Name(cfg.Package), err))
+					directlyDirty[cfg.Package.ID()] = true
 					continue
 				}
 
-				cacheFile := filepath.Join(cacheDir, cfg.Package.Name+".hash")
+				cacheFile := packageCacheFile(cacheDir, cfg.Package)
 
 				if cachedHash, err := os.ReadFile(cacheFile); err != nil {
-					directlyDirty[cfg.Package.Name] = true
+					directlyDirty[cfg.Package.ID()] = true
 				} else if string(cachedHash) != newHash {
-					directlyDirty[cfg.Package.Name] = true
+					directlyDirty[cfg.Package.ID()] = true
 				}
 			}
 
 			// Now propagate dirtiness to dependent packages
 			allDirty := make(map[string]bool)
-			for pkgName := range directlyDirty {
-				allDirty[pkgName] = true
-				propagateDirtiness(pkgName, reverseDeps, allDirty, formatter)
+			for pkgID := range directlyDirty {
+				allDirty[pkgID] = true
+				propagateDirtiness(pkgID, reverseDeps, allDirty, formatter)
 			}
 
 			// Build the final list of dirty packages
 			for _, cfg := range packages {
-				if allDirty[cfg.Package.Name] {
+				if allDirty[cfg.Package.ID()] {
 					dirtyPackages = append(dirtyPackages, cfg)
 				}
 			}
@@ -120,7 +156,7 @@ var buildCmd = &cobra.Command{
 		}
 
 		formatter.Section("Resolving Dependencies")
-		buildOrder, err := resolveDependencies(packages, formatter)
+		buildOrder, err := resolveDependencies(packages, index, formatter)
 		if err != nil {
 			formatter.Error(fmt.Sprintf("Error resolving dependencies: %v", err))
 			os.Exit(1)
@@ -129,11 +165,11 @@ var buildCmd = &cobra.Command{
 
 		// In the buildCmd.Run function, add more detailed logging
 		formatter.Section("Building Packages")
-		packageNames := getPackageNames(buildOrder)
+		packageNames := getPackageNames(buildOrder, index)
 		formatter.Detail(fmt.Sprintf("Build order: %s", strings.Join(packageNames, " → ")))
 
 		// Group packages by their dependency level
-		buildLevels := groupPackagesByLevel(buildOrder, formatter)
+		buildLevels := groupPackagesByLevel(buildOrder, index, formatter)
 		formatter.Detail(fmt.Sprintf("Build will execute in %d parallel stages", len(buildLevels)))
 
 		// Create overall progress bar
@@ -170,11 +206,11 @@ var buildCmd = &cobra.Command{
 					go func(cfg grit.Config) {
 						defer wg.Done()
 						buildStart := time.Now()
-						err := executeBuild(cfg, cacheDir, noCache, formatter, cwd)
+						err := executeBuild(cfg, rootConfig, cacheDir, noCache, formatter, cwd)
 						buildDuration := time.Since(buildStart)
 						
 						resultChan <- buildResult{
-							packageName: cfg.Package.Name,
+							packageName: index.Name(cfg.Package),
 							success:     err == nil,
 							duration:    buildDuration,
 							err:         err,
@@ -233,37 +269,45 @@ var buildCmd = &cobra.Command{
 
 func init() {
 	buildCmd.Flags().BoolVar(&noCache, "no-cache", false, "Bypass build cache")
+	buildCmd.Flags().StringArrayVar(&buildFilters, "filter", nil, "Only build packages matching the filter expression (repeatable)")
 	buildCmd.Flags().BoolVar(&dirtyFlag, "dirty", false, "Only build packages with changes") // Add this flag
 	rootCmd.AddCommand(buildCmd)
 }
 
-func resolveDependencies(packages []grit.Config, formatter *output.Formatter) ([]grit.Config, error) {
-	// Build dependency graph
+func resolveDependencies(packages []grit.Config, index *grit.Index, formatter *output.Formatter) ([]grit.Config, error) {
+	// Build dependency graph keyed by package ID
 	graph := make(map[string][]string)
 	nodeMap := make(map[string]grit.Config)
 	inDegree := make(map[string]int)
 
 	// Initialize the graph with all packages
 	for _, cfg := range packages {
-		nodeMap[cfg.Package.Name] = cfg
-		if _, exists := graph[cfg.Package.Name]; !exists {
-			graph[cfg.Package.Name] = []string{}
+		nodeMap[cfg.Package.ID()] = cfg
+		if _, exists := graph[cfg.Package.ID()]; !exists {
+			graph[cfg.Package.ID()] = []string{}
 		}
 	}
 
 	// Add dependencies to the graph
 	for _, cfg := range packages {
-		for _, depName := range cfg.Package.Dependencies {
-			// Check if the dependency exists
-			if _, exists := nodeMap[depName]; !exists {
-				// Skip missing dependencies or handle them differently
-				formatter.Warning(fmt.Sprintf("Package %s depends on %s, but it doesn't exist",
-					cfg.Package.Name, depName))
+		depIDs, depErrs := index.Dependencies(cfg.Package)
+		for _, depErr := range depErrs {
+			if errors.Is(depErr, grit.ErrAmbiguousPackage) {
+				return nil, fmt.Errorf("package %s: %w", index.Name(cfg.Package), depErr)
+			}
+			// Skip missing dependencies or handle them differently
+			formatter.Warning(fmt.Sprintf("Package %s depends on %s, but it doesn't exist",
+				index.Name(cfg.Package), depErr.Ref))
+		}
+
+		for _, depID := range depIDs {
+			// Dependencies outside the selected set were filtered out on purpose
+			if _, exists := nodeMap[depID]; !exists {
 				continue
 			}
 
-			graph[cfg.Package.Name] = append(graph[cfg.Package.Name], depName)
-			inDegree[depName]++
+			graph[cfg.Package.ID()] = append(graph[cfg.Package.ID()], depID)
+			inDegree[depID]++
 		}
 	}
 
@@ -294,10 +338,10 @@ func resolveDependencies(packages []grit.Config, formatter *output.Formatter) ([
 		formatter.Warning("Possible dependency cycle detected. Building packages in best-effort order.")
 
 		// Add remaining packages in any order
-		for name, cfg := range nodeMap {
+		for id, cfg := range nodeMap {
 			found := false
 			for _, orderedCfg := range order {
-				if orderedCfg.Package.Name == name {
+				if orderedCfg.Package.ID() == id {
 					found = true
 					break
 				}
@@ -317,7 +361,7 @@ func resolveDependencies(packages []grit.Config, formatter *output.Formatter) ([
 	return reversed, nil
 }
 
-func executeBuild(cfg grit.Config, cacheDir string, noCache bool, formatter *output.Formatter, cwd string) error {
+func executeBuild(cfg grit.Config, rootConfig *grit.RootConfig, cacheDir string, noCache bool, formatter *output.Formatter, cwd string) error {
 	// Skip if this is the root config file
 	if cfg.Package.Name == "" {
 		return nil
@@ -328,10 +372,11 @@ func executeBuild(cfg grit.Config, cacheDir string, noCache bool, formatter *out
 
 	// Calculate a hash based on the package files
 	// If we're using --dirty, we might have already calculated this hash
+	cacheFile := packageCacheFile(cacheDir, cfg.Package)
+
 	var newHash string
 	if dirtyFlag && !noCache {
 		// Try to get the hash from the dirty check
-		cacheFile := filepath.Join(cacheDir, cfg.Package.Name+".hash")
 		if cachedHash, err := os.ReadFile(cacheFile); err == nil {
 			// We have a cached hash, but we know it's dirty, so use it
 			newHash = string(cachedHash)
@@ -352,8 +397,6 @@ func executeBuild(cfg grit.Config, cacheDir string, noCache bool, formatter *out
 		}
 	}
 
-	cacheFile := filepath.Join(cacheDir, cfg.Package.Name+".hash")
-
 	if !noCache {
 		if cachedHash, err := os.ReadFile(cacheFile); err == nil {
 			if string(cachedHash) == newHash {
@@ -366,78 +409,26 @@ func executeBuild(cfg grit.Config, cacheDir string, noCache bool, formatter *out
 
 
 
-	// In the executeBuild function, fix the root config path
-	// Load the root config to get type information
-	rootConfigPath := filepath.Join(cwd, "grit.yaml")
-	formatter.Detail(fmt.Sprintf("Looking for root config at: %s", rootConfigPath))
-	rootConfigData, err := os.ReadFile(rootConfigPath)
-	if err != nil {
-		return fmt.Errorf("failed to read root config: %w", err)
-	}
-
-	var rootConfig grit.RootConfig
-	if err := yaml.Unmarshal(rootConfigData, &rootConfig); err != nil {
-		return fmt.Errorf("invalid root config: %w", err)
-	}
-
-	// Determine the package type from its path
-	var cfgType string
-	for typeName, typeConfig := range rootConfig.Types {
-		if strings.Contains(cfgDir, typeConfig.PackageDir) {
-			cfgType = typeName
-			break
-		}
-	}
+	// Resolve the build command through the package, type and root config
+	buildStart := time.Now()
+	err := runPackageTarget(cfg, rootConfig, "build", cwd, formatter)
 
-	if cfgType == "" {
-		return fmt.Errorf("could not determine package type for %s", cfg.Package.Name)
-	}
-
-	// Load the package config
-	var cfgConfig grit.Config
-	cfgConfigData, err := os.ReadFile(cfg.Package.Path)
+	record := buildRecord{Success: err == nil, Time: buildStart, Duration: time.Since(buildStart), Hash: newHash}
 	if err != nil {
-		return fmt.Errorf("failed to read package config: %w", err)
+		record.Error = err.Error()
 	}
-
-	if err := yaml.Unmarshal(cfgConfigData, &cfgConfig); err != nil {
-		return fmt.Errorf("invalid package config: %w", err)
+	if recordErr := saveBuildRecord(cacheDir, cfg.Package, record); recordErr != nil {
+		formatter.Warning(fmt.Sprintf("Could not record build result for %s: %v", cfg.Package.Name, recordErr))
 	}
-
-	// Get the build command - first try package config, then fall back to type config
-	buildCmd, ok := cfgConfig.Targets["build"]
-	if !ok || buildCmd == "" {
-		// Fall back to type config
-		typeConfig := rootConfig.Types[cfgType]
-		buildCmd, ok = typeConfig.Targets["build"]
-		if !ok || buildCmd == "" {
-			return fmt.Errorf("no build command defined for package %s or type %s", cfg.Package.Name, cfgType)
-		}
-	}
-
-	// In the executeBuild function, add timeout and better error handling for the command execution
-	formatter.Detail(fmt.Sprintf("Executing build command: %s", buildCmd))
-
-	// Execute the build command with a timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Minute)
-	defer cancel()
-
-	cmd := exec.CommandContext(ctx, "sh", "-c", buildCmd)
-	cmd.Dir = cfgDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
-			return fmt.Errorf("build command timed out after 2 minutes")
-		}
-		return fmt.Errorf("build command failed: %w", err)
+	if err != nil {
+		return err
 	}
 
 	formatter.Success(fmt.Sprintf("Built %s successfully", cfg.Package.Name))
 
 	// Save the new hash to the cache
 	if !noCache {
+		os.MkdirAll(filepath.Dir(cacheFile), 0755)
 		os.WriteFile(cacheFile, []byte(newHash), 0644)
 	}
 
@@ -493,21 +484,21 @@ func calculatePackageHash(pkgDir string) (string, error) {
 }
 
 // Helper function to get package names for logging
-func getPackageNames(configs []grit.Config) []string {
+func getPackageNames(configs []grit.Config, index *grit.Index) []string {
 	names := make([]string, 0, len(configs))
 	for _, cfg := range configs {
 		if cfg.Package.Name != "" {
-			names = append(names, cfg.Package.Name)
+			names = append(names, index.Name(cfg.Package))
 		}
 	}
 	return names
 }
 
 // Helper function to recursively propagate dirtiness to dependent packages
-func propagateDirtiness(pkgName string, reverseDeps map[string][]string, allDirty map[string]bool, formatter *output.Formatter) {
-	for _, depender := range reverseDeps[pkgName] {
+func propagateDirtiness(pkgID string, reverseDeps map[string][]string, allDirty map[string]bool, formatter *output.Formatter) {
+	for _, depender := range reverseDeps[pkgID] {
 		if !allDirty[depender] {
-			formatter.Detail(fmt.Sprintf("Package %s is dirty because it depends on %s", depender, pkgName))
+			formatter.Detail(fmt.Sprintf("Package %s is dirty because it depends on %s", depender, pkgID))
 			allDirty[depender] = true
 			// Recursively mark packages that depend on this one
 			propagateDirtiness(depender, reverseDeps, allDirty, formatter)
@@ -516,17 +507,18 @@ func propagateDirtiness(pkgName string, reverseDeps map[string][]string, allDirt
 }
 
 // Helper function to group packages by their dependency level for parallel building
-func groupPackagesByLevel(buildOrder []grit.Config, formatter *output.Formatter) [][]grit.Config {
-    // Create a map of package name to its dependencies
+func groupPackagesByLevel(buildOrder []grit.Config, index *grit.Index, formatter *output.Formatter) [][]grit.Config {
+    // Create a map of package ID to its dependencies
     dependsOn := make(map[string]map[string]bool)
     for _, cfg := range buildOrder {
         if cfg.Package.Name == "" {
             continue
         }
         
-        dependsOn[cfg.Package.Name] = make(map[string]bool)
-        for _, dep := range cfg.Package.Dependencies {
-            dependsOn[cfg.Package.Name][dep] = true
+        dependsOn[cfg.Package.ID()] = make(map[string]bool)
+        depIDs, _ := index.Dependencies(cfg.Package)
+        for _, dep := range depIDs {
+            dependsOn[cfg.Package.ID()][dep] = true
         }
     }
     
@@ -548,7 +540,7 @@ func groupPackagesByLevel(buildOrder []grit.Config, formatter *output.Formatter)
     // Initialize remaining packages
     for _, cfg := range buildOrder {
         if cfg.Package.Name != "" {
-            remaining[cfg.Package.Name] = cfg
+            remaining[cfg.Package.ID()] = cfg
         }
     }
     
@@ -557,9 +549,9 @@ func groupPackagesByLevel(buildOrder []grit.Config, formatter *output.Formatter)
         var currentLevel []grit.Config
         
         // Find packages with no remaining dependencies
-        for pkgName, cfg := range remaining {
+        for pkgID, cfg := range remaining {
             canBuild := true
-            for dep := range dependsOn[pkgName] {
+            for dep := range dependsOn[pkgID] {
                 if _, exists := remaining[dep]; exists {
                     canBuild = false
                     break
@@ -582,14 +574,14 @@ func groupPackagesByLevel(buildOrder []grit.Config, formatter *output.Formatter)
         
         // Sort the current level by dependency count (packages with more dependents first)
         sort.Slice(currentLevel, func(i, j int) bool {
-            nameI := currentLevel[i].Package.Name
-            nameJ := currentLevel[j].Package.Name
-            return len(dependedOnBy[nameI]) > len(dependedOnBy[nameJ])
+            idI := currentLevel[i].Package.ID()
+            idJ := currentLevel[j].Package.ID()
+            return len(dependedOnBy[idI]) > len(dependedOnBy[idJ])
         })
         
         // Remove the packages from remaining
         for _, cfg := range currentLevel {
-            delete(remaining, cfg.Package.Name)
+            delete(remaining, cfg.Package.ID())
         }
         
         levels = append(levels, currentLevel)
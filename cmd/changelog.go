@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	changelogFilters []string
+	changelogSince   string
+	changelogDryRun  bool
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog [package]...",
+	Short: "Generate package changelogs from commit messages",
+	Long: `Walk the git history of each package directory and add the conventional
+commits (feat, fix, perf and breaking changes) to the CHANGELOG.md of the package,
+in its Unreleased section. grit release turns that section into the one of the
+version it releases. Messages may carry the package prefix that
+grit commit writes, e.g. "utils: feat: add retries". Messages with only the
+prefix, e.g. "utils: add retries", and commit types configured in addition to
+the conventional ones are listed under Other Changes.
+
+By default only commits made since the CHANGELOG.md of a package was last
+committed are included, and commits the file already lists are skipped, so
+running it again only adds what is new. Use --since to start from another
+revision.
+
+Examples:
+  grit changelog                    # All packages
+  grit changelog utils --dry-run    # Print the new section for lib/utils
+  grit changelog --filter type:lib --since v1.0.0`,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		packages, err := selectPackages(index, args, changelogFilters)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting packages: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Changelog")
+
+		updated := 0
+		for _, cfg := range packages {
+			pkgDir := filepath.Dir(cfg.Package.Path)
+			since := changelogSince
+			if since == "" {
+				if since, err = lastChangelogCommit(cwd, pkgDir); err != nil {
+					formatter.Error(fmt.Sprintf("Error reading history of %s: %v", cfg.Package.ID(), err))
+					os.Exit(1)
+				}
+			}
+
+			commits, err := packageCommits(cwd, rootConfig.Commit, cfg.Package, since)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error reading history of %s: %v", cfg.Package.ID(), err))
+				os.Exit(1)
+			}
+			if commits, err = unlistedCommits(pkgDir, commits); err != nil {
+				formatter.Error(fmt.Sprintf("Error reading changelog of %s: %v", cfg.Package.ID(), err))
+				os.Exit(1)
+			}
+			section := grit.RenderChangelogSection(grit.UnreleasedVersion, time.Now(), commits)
+			if section == "" {
+				formatter.Detail(fmt.Sprintf("%s: no changes to add", cfg.Package.ID()))
+				continue
+			}
+
+			if changelogDryRun {
+				formatter.Section(cfg.Package.ID())
+				fmt.Println(section)
+				continue
+			}
+			if _, err := updateChangelog(pkgDir, grit.UnreleasedVersion, commits); err != nil {
+				formatter.Error(fmt.Sprintf("Error updating changelog of %s: %v", cfg.Package.ID(), err))
+				os.Exit(1)
+			}
+			formatter.Detail(fmt.Sprintf("%s: updated %s", cfg.Package.ID(), relativePath(cwd, filepath.Join(pkgDir, grit.ChangelogFileName))))
+			updated++
+		}
+
+		if !changelogDryRun {
+			formatter.Success(fmt.Sprintf("Updated %d changelogs", updated))
+		}
+	},
+}
+
+func init() {
+	changelogCmd.Flags().StringArrayVar(&changelogFilters, "filter", nil, "Only include packages matching the filter expression (repeatable)")
+	changelogCmd.Flags().StringVar(&changelogSince, "since", "", "Include commits after this revision instead of since the last changelog update")
+	changelogCmd.Flags().BoolVar(&changelogDryRun, "dry-run", false, "Print the new sections without writing them")
+	rootCmd.AddCommand(changelogCmd)
+}
+
+// The conventional commits touching a package directory after the since
+// revision, newest first, with the commit types of the commit config. Plain
+// messages count when their prefix names the package. An empty since includes
+// the whole history. Scopes naming the package itself are dropped, they add
+// nothing to its changelog.
+func packageCommits(cwd string, commitConfig grit.CommitConfig, pkg grit.Package, since string) ([]grit.ConventionalCommit, error) {
+	pkgDir := filepath.Dir(pkg.Path)
+	args := []string{"log", "--format=%H%x1f%B%x1e"}
+	if since != "" {
+		args = append(args, since+"..HEAD")
+	}
+	out, err := runGit(cwd, append(args, "--", pkgDir)...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []grit.ConventionalCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		hash, message, found := strings.Cut(strings.TrimSpace(record), "\x1f")
+		if !found {
+			continue
+		}
+		commit, ok := commitConfig.ParseCommitMessage(message)
+		if ok && commit.Type == "" && commit.Package != pkg.Name && commit.Package != pkg.ID() {
+			ok = false
+		}
+		if ok {
+			commit.Hash = hash
+			if commit.Scope == pkg.Name || commit.Scope == pkg.ID() {
+				commit.Scope = ""
+			}
+			commits = append(commits, commit)
+		}
+	}
+	return commits, nil
+}
+
+// The last commit that changed the changelog of a package, empty if there is none
+func lastChangelogCommit(cwd string, pkgDir string) (string, error) {
+	out, err := runGit(cwd, "log", "-1", "--format=%H", "--", filepath.Join(pkgDir, grit.ChangelogFileName))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// The commits the changelog in a package directory doesn't list yet
+func unlistedCommits(pkgDir string, commits []grit.ConventionalCommit) ([]grit.ConventionalCommit, error) {
+	existing, err := os.ReadFile(filepath.Join(pkgDir, grit.ChangelogFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return grit.UnlistedCommits(string(existing), commits), nil
+}
+
+// Add the commits to the changelog in a package directory, in the section of
+// version. Returns false when there was nothing to add.
+func updateChangelog(pkgDir string, version string, commits []grit.ConventionalCommit) (bool, error) {
+	path := filepath.Join(pkgDir, grit.ChangelogFileName)
+	existing, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return false, err
+	}
+	updated := grit.UpdateChangelog(string(existing), version, time.Now(), commits)
+	if updated == string(existing) {
+		return false, nil
+	}
+	return true, os.WriteFile(path, []byte(updated), 0644)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestPackageCommits(t *testing.T) {
+	dir := newGitRepo(t)
+	pkgDir := filepath.Join(dir, "packages", "lib", "utils")
+	pkg := grit.Package{Name: "utils", Type: "lib", Path: filepath.Join(pkgDir, "grit.yaml")}
+	commitFiles(t, dir, "utils: feat: add a", map[string]string{"packages/lib/utils/a.txt": "a"})
+	commitFiles(t, dir, "web: fix: unrelated", map[string]string{"packages/app/web/a.txt": "a"})
+	commitFiles(t, dir, "utils: not conventional", map[string]string{"packages/lib/utils/b.txt": "b"})
+	commitFiles(t, dir, "web: touches utils too", map[string]string{"packages/lib/utils/b.txt": "bb"})
+	commitFiles(t, dir, "not prefixed", map[string]string{"packages/lib/utils/b.txt": "bbb"})
+
+	since, err := lastChangelogCommit(dir, pkgDir)
+	require.NoError(t, err)
+	assert.Empty(t, since)
+
+	commits, err := packageCommits(dir, grit.CommitConfig{}, pkg, since)
+	require.NoError(t, err)
+	require.Len(t, commits, 2, "plain messages count when prefixed with the package")
+	assert.Equal(t, "", commits[0].Type)
+	assert.Equal(t, "not conventional", commits[0].Subject)
+	assert.Equal(t, "feat", commits[1].Type)
+	assert.Equal(t, "add a", commits[1].Subject)
+	assert.Len(t, commits[1].Hash, 40)
+
+	// Only commits after the last changelog update are included
+	require.NoError(t, os.WriteFile(filepath.Join(pkgDir, "CHANGELOG.md"), []byte("# Changelog\n\n## 0.1.0\n"), 0644))
+	commitFiles(t, dir, "docs: changelog", nil)
+	commitFiles(t, dir, "fix(io)!: close files", map[string]string{"packages/lib/utils/c.txt": "c"})
+	commitFiles(t, dir, "feat(utils): add d", map[string]string{"packages/lib/utils/d.txt": "d"})
+
+	since, err = lastChangelogCommit(dir, pkgDir)
+	require.NoError(t, err)
+	commits, err = packageCommits(dir, grit.CommitConfig{}, pkg, since)
+	require.NoError(t, err)
+	require.Len(t, commits, 2)
+	assert.Empty(t, commits[0].Scope, "the package scope is dropped")
+	assert.Equal(t, "io", commits[1].Scope)
+	assert.True(t, commits[1].Breaking)
+
+	// Configured commit types are recognized
+	commitFiles(t, dir, "utils: security: pin versions", map[string]string{"packages/lib/utils/e.txt": "e"})
+	commits, err = packageCommits(dir, grit.CommitConfig{Types: []string{"feat", "fix", "security"}}, pkg, since)
+	require.NoError(t, err)
+	require.Len(t, commits, 3)
+	assert.Equal(t, "security", commits[0].Type)
+	assert.Equal(t, "pin versions", commits[0].Subject)
+
+	data, err := os.ReadFile(filepath.Join(pkgDir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Changelog\n\n## 0.1.0\n", string(data))
+}
+
+func TestChangelogAcrossRelease(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "utils: feat: add utils", map[string]string{
+		"packages/lib/utils/grit.yaml": "package:\n  name: utils\n  version: 0.1.0\n",
+	})
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{"lib": {PackageDir: "packages/lib"}}}
+	pkgDir := filepath.Join(dir, "packages", "lib", "utils")
+
+	// What grit changelog does for a package
+	collect := func() {
+		packages, err := grit.NewPackageManager(dir).LoadPackagesWithConfig(rootConfig)
+		require.NoError(t, err)
+		since, err := lastChangelogCommit(dir, pkgDir)
+		require.NoError(t, err)
+		commits, err := packageCommits(dir, rootConfig.Commit, packages[0].Package, since)
+		require.NoError(t, err)
+		commits, err = unlistedCommits(pkgDir, commits)
+		require.NoError(t, err)
+		_, err = updateChangelog(pkgDir, grit.UnreleasedVersion, commits)
+		require.NoError(t, err)
+	}
+
+	collect()
+	commitFiles(t, dir, "utils: docs: changelog", nil)
+	commitFiles(t, dir, "utils: fix: handle empty input", map[string]string{"packages/lib/utils/a.txt": "a"})
+	collect()
+	data, err := os.ReadFile(filepath.Join(pkgDir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Equal(t, 1, strings.Count(string(data), "## Unreleased"), "a second run adds to the section")
+	assert.Contains(t, string(data), "- add utils")
+	assert.Contains(t, string(data), "- handle empty input")
+	commitFiles(t, dir, "utils: docs: changelog", nil)
+
+	// The release names the Unreleased section after the new version
+	packages, err := grit.NewPackageManager(dir).LoadPackagesWithConfig(rootConfig)
+	require.NoError(t, err)
+	index := grit.NewIndex(packages)
+	plan, err := planRelease(dir, index, rootConfig.Commit, packages[0], false)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	require.NoError(t, applyRelease(dir, plan))
+
+	// Changes after the release go into a new Unreleased section
+	commitFiles(t, dir, "utils: fix: close files", map[string]string{"packages/lib/utils/b.txt": "b"})
+	collect()
+	data, err = os.ReadFile(filepath.Join(pkgDir, "CHANGELOG.md"))
+	require.NoError(t, err)
+	changelog := string(data)
+	assert.Equal(t, 1, strings.Count(changelog, "## "+plan.to), "the released version has one section")
+	unreleased := strings.Index(changelog, "## Unreleased")
+	released := strings.Index(changelog, "## "+plan.to+" (")
+	require.True(t, unreleased >= 0 && released > unreleased, changelog)
+	assert.Contains(t, changelog[unreleased:released], "- close files")
+	assert.NotContains(t, changelog[released:], "close files")
+	assert.Contains(t, changelog[released:], "- add utils")
+	assert.Contains(t, changelog[released:], "- handle empty input")
+}
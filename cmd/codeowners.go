@@ -0,0 +1,146 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	codeownersCheck bool
+	codeownersFile  string
+)
+
+var codeownersCmd = &cobra.Command{
+	Use:   "codeowners",
+	Short: "Generate the CODEOWNERS file from the package owners",
+	Long: `Generate the rules of the CODEOWNERS file from the owners of each package.
+
+Every package directory is assigned to the owners listed in its grit.yaml, or to
+the owners of its type when it lists none. Types with owners also get a rule for
+their package directory. The rules are kept in a block marked by grit, so rules
+written by hand around it are preserved.
+
+The file is the existing one of .github/CODEOWNERS, CODEOWNERS and
+docs/CODEOWNERS in the root of the repository, .github/CODEOWNERS otherwise.
+
+Examples:
+  grit codeowners           # Update the CODEOWNERS file
+  grit codeowners --check   # Exit non-zero if the file is out of date, e.g. in CI`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		formatter.Header("GRIT Codeowners")
+
+		path, err := codeownersPath(cwd, codeownersFile)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the CODEOWNERS file: %v", err))
+			os.Exit(1)
+		}
+		prefix, err := runGit(cwd, "rev-parse", "--show-prefix")
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the workspace in the repository: %v", err))
+			os.Exit(1)
+		}
+
+		rules, unowned := codeownersRules(cwd, strings.TrimSpace(prefix), index, rootConfig)
+		for _, name := range unowned {
+			formatter.Warning(fmt.Sprintf("Package %s has no owners", name))
+		}
+
+		existing, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			formatter.Error(fmt.Sprintf("Error reading %s: %v", path, err))
+			os.Exit(1)
+		}
+		updated := grit.UpdateCodeowners(string(existing), grit.RenderCodeowners(rules))
+		relPath := relativePath(cwd, path)
+		if updated == string(existing) {
+			formatter.Success(fmt.Sprintf("%s is up to date", relPath))
+			return
+		}
+
+		if codeownersCheck {
+			fmt.Print(unifiedDiff(relPath, string(existing), updated))
+			formatter.NewLine()
+			formatter.Error(fmt.Sprintf("%s is out of date, run 'grit codeowners' to update it", relPath))
+			os.Exit(1)
+		}
+
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			formatter.Error(fmt.Sprintf("Error creating %s: %v", filepath.Dir(path), err))
+			os.Exit(1)
+		}
+		if err := os.WriteFile(path, []byte(updated), 0644); err != nil {
+			formatter.Error(fmt.Sprintf("Error writing %s: %v", path, err))
+			os.Exit(1)
+		}
+		formatter.Success(fmt.Sprintf("Updated %s with %d rules", relPath, len(rules)))
+	},
+}
+
+func init() {
+	codeownersCmd.Flags().BoolVar(&codeownersCheck, "check", false, "Only check that the file is up to date, exit non-zero if it isn't")
+	codeownersCmd.Flags().StringVar(&codeownersFile, "file", "", "Path of the CODEOWNERS file, instead of the one in the repository root")
+	rootCmd.AddCommand(codeownersCmd)
+}
+
+// The CODEOWNERS file to update: the given file, or the file GitHub reads in the
+// root of the repository
+func codeownersPath(cwd string, file string) (string, error) {
+	if file != "" {
+		return filepath.Join(cwd, file), nil
+	}
+	root, err := runGit(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	root = strings.TrimSpace(root)
+	for _, candidate := range grit.CodeownersPaths {
+		path := filepath.Join(root, filepath.FromSlash(candidate))
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		}
+	}
+	return filepath.Join(root, filepath.FromSlash(grit.CodeownersPaths[0])), nil
+}
+
+// The CODEOWNERS rules of the types and packages of the workspace, with paths
+// relative to the repository root. prefix is the path of the workspace in the
+// repository. Also returns the names of the packages without owners.
+func codeownersRules(cwd string, prefix string, index *grit.Index, rootConfig *grit.RootConfig) ([]grit.CodeownersRule, []string) {
+	var rules []grit.CodeownersRule
+
+	typeNames := make([]string, 0, len(rootConfig.Types))
+	for name := range rootConfig.Types {
+		typeNames = append(typeNames, name)
+	}
+	sort.Strings(typeNames)
+	for _, name := range typeNames {
+		typeConfig := rootConfig.Types[name]
+		if len(typeConfig.Owners) > 0 && typeConfig.PackageDir != "" {
+			rules = append(rules, grit.CodeownersRule{Dir: path.Join(prefix, filepath.ToSlash(typeConfig.PackageDir)), Owners: typeConfig.Owners})
+		}
+	}
+
+	var unowned []string
+	for _, cfg := range index.Packages() {
+		owners := rootConfig.PackageOwners(cfg)
+		if len(owners) == 0 {
+			unowned = append(unowned, index.Name(cfg.Package))
+			continue
+		}
+		dir := relativePath(cwd, filepath.Dir(cfg.Package.Path))
+		rules = append(rules, grit.CodeownersRule{Dir: path.Join(prefix, filepath.ToSlash(dir)), Owners: owners})
+	}
+	return rules, unowned
+}
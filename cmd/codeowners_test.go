@@ -0,0 +1,41 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestCodeownersRules(t *testing.T) {
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"lib": {PackageDir: "packages/lib", Owners: []string{"@org/libs"}},
+		"app": {PackageDir: "packages/app"},
+	}}
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "utils", Type: "lib", Path: "/repo/ws/packages/lib/utils/grit.yaml"}},
+		{Package: grit.Package{Name: "web", Type: "app", Path: "/repo/ws/packages/app/web/grit.yaml", Owners: []string{"@org/web"}}},
+		{Package: grit.Package{Name: "admin", Type: "app", Path: "/repo/ws/packages/app/admin/grit.yaml"}},
+	})
+
+	rules, unowned := codeownersRules("/repo/ws", "ws/", index, rootConfig)
+	assert.Equal(t, []grit.CodeownersRule{
+		{Dir: "ws/packages/lib", Owners: []string{"@org/libs"}},
+		{Dir: "ws/packages/lib/utils", Owners: []string{"@org/libs"}},
+		{Dir: "ws/packages/app/web", Owners: []string{"@org/web"}},
+	}, rules)
+	assert.Equal(t, []string{"admin"}, unowned)
+
+	// A package at the root of a workspace in a subdirectory of the repository
+	index = grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "tool", Path: "/repo/ws/grit.yaml", Owners: []string{"@org/tools"}}},
+	})
+	rules, _ = codeownersRules("/repo/ws", "ws/", index, &grit.RootConfig{})
+	assert.Equal(t, []grit.CodeownersRule{{Dir: "ws", Owners: []string{"@org/tools"}}}, rules)
+	assert.Contains(t, grit.RenderCodeowners(rules), "\n/ws/ @org/tools\n")
+
+	// Without a prefix the package owns the whole repository
+	rules, _ = codeownersRules("/repo/ws", "", index, &grit.RootConfig{})
+	assert.Equal(t, []grit.CodeownersRule{{Dir: ".", Owners: []string{"@org/tools"}}}, rules)
+	assert.Contains(t, grit.RenderCodeowners(rules), "\n* @org/tools\n")
+}
@@ -11,13 +11,82 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
 	"github.com/weslien/grit/pkg/output"
+	"gopkg.in/yaml.v3"
 )
 
-// Fix the formatter initialization and add the loadPackages function
+var (
+	commitMessage  string
+	commitMessages string
+	commitYes      bool
+	commitOnly     []string
+	commitStaged   bool
+
+	commitConventional bool
+	commitType         string
+	commitBreaking     bool
+)
+
+// Where commit messages come from when grit commit runs without prompts
+type commitOptions struct {
+	message  string            // Message for every commit, from -m
+	messages map[string]string // Messages by package ID, with "." for repository changes
+	yes      bool              // Don't offer to show diffs
+	staged   bool              // Only commit what is staged
+
+	conventional bool   // Write conventional commit messages
+	commitType   string // Type for messages that don't have one
+	breaking     bool   // Mark every commit as a breaking change
+	config       grit.CommitConfig
+}
+
+// Key of the repository level changes in a messages file
+const repoMessageKey = "."
+
+func (o commitOptions) nonInteractive() bool {
+	return o.message != "" || o.messages != nil
+}
+
+// The message for a package ID or the repository, false when it has to be asked
+// for. Without prompts an empty message means the commit is skipped.
+func (o commitOptions) messageFor(id string) (string, bool) {
+	if message, ok := o.messages[id]; ok {
+		return message, true
+	}
+	return o.message, o.nonInteractive()
+}
+
 var commitCmd = &cobra.Command{
 	Use:   "commit",
 	Short: "Commit changes in packages",
-	Long:  `Iterate through packages with changes, summarize changes, and commit them individually.`,
+	Long: `Iterate through packages with changes, summarize changes, and commit them individually.
+
+Each package commit message is prefixed with the package name. For scripts and
+CI the messages can be given up front, in which case nothing is read from stdin:
+-m uses the same message for every commit, and --messages reads a YAML file
+mapping packages to messages, with "." for changes outside packages. Packages
+without a message are skipped. The command exits with an error if any commit
+fails.
+
+In conventional mode, enabled with --conventional or commit.conventional in the
+root grit.yaml, messages are written as conventional commits with the package as
+scope, e.g. "feat(utils): add retries". The type is asked for unless the message
+starts with one or --type is given, and breaking changes are marked with ! and a
+BREAKING CHANGE footer. Messages are checked against the allowed commit.types
+and the commit.pattern regular expression.
+
+When asking for messages, grit commit first asks which files of the package to
+commit: all of them, some by their number in the summary, or changes picked with
+git add -p. With --staged only what is already staged is committed, split by
+package, and unstaged changes are left alone.
+
+Examples:
+  grit commit                                  # Prompt for each package
+  grit commit -m "update dependencies" --yes
+  grit commit --messages messages.yaml
+  grit commit --only type:lib -m "fix lint"    # Only commit lib packages
+  grit commit --type fix -m "handle empty input"
+  grit commit --staged                         # Only commit staged changes`,
+	Args: cobra.NoArgs,
 	Run: func(cmd *cobra.Command, args []string) {
 		formatter := output.New()
 		formatter.Section("Grit Commit")
@@ -29,335 +98,295 @@ var commitCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
+		rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Failed to load root config: %v", err))
+			os.Exit(1)
+		}
+
 		// Load packages
 		pm := grit.NewPackageManager(cwd)
-		packages, err := pm.LoadPackages()
+		packages, err := pm.LoadPackagesWithConfig(rootConfig)
 		if err != nil {
 			formatter.Error(fmt.Sprintf("Failed to load packages: %v", err))
 			os.Exit(1)
 		}
-		
-		// Find packages with changes
-		packagesWithChanges := findPackagesWithChanges(packages, formatter)
-		
-		// Check for non-package changes
-		hasRepoChanges := checkForRepoChanges(packages, cwd, formatter)
+		index := grit.NewIndex(packages)
+
+		opts := commitOptions{
+			message:      commitMessage,
+			yes:          commitYes,
+			conventional: commitConventional || commitType != "" || rootConfig.Commit.Conventional,
+			commitType:   commitType,
+			staged:       commitStaged,
+			breaking:     commitBreaking,
+			config:       rootConfig.Commit,
+		}
+		if commitMessages != "" {
+			if opts.messages, err = loadCommitMessages(commitMessages, index); err != nil {
+				formatter.Error(fmt.Sprintf("Failed to read commit messages: %v", err))
+				os.Exit(1)
+			}
+		}
+
+		// Find the changes and the package each one belongs to
+		changes, err := workspaceStatus(cwd, index)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Failed to check git status: %v", err))
+			os.Exit(1)
+		}
+		if opts.staged {
+			changes = changes.filter(func(entry statusEntry) bool {
+				return entry.staged() && !entry.unmerged()
+			})
+		}
+		packagesWithChanges := changes.packages
+		hasRepoChanges := len(changes.repo) > 0
+		formatter.Info(fmt.Sprintf("Found %d packages with changes", len(packagesWithChanges)))
+
+		// Only commit the selected packages
+		if len(commitOnly) > 0 {
+			filters, err := grit.ParseFilters(commitOnly)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Invalid --only filter: %v", err))
+				os.Exit(1)
+			}
+			packagesWithChanges = grit.FilterPackages(packagesWithChanges, filters)
+			hasRepoChanges = false
+		}
 
 		if len(packagesWithChanges) == 0 && !hasRepoChanges {
 			formatter.Success("No changes to commit")
 			return
 		}
 
-		// Process packages with changes
+		// Process packages with changes. Packages are referred to by their bare
+		// name unless another type has a package with the same name. Prompts
+		// are read a line at a time, so the input after them is left for git
+		// add -p, which reads from stdin itself.
+		reader := newPromptReader(os.Stdin)
+		failed := 0
 		for _, pkg := range packagesWithChanges {
-			commitPackageChanges(pkg, cwd, formatter)
+			if err := commitPackageChanges(pkg, index.Name(pkg.Package), changes, opts, reader, formatter); err != nil {
+				failed++
+			}
 		}
 
 		// Process repo-level changes if any
 		if hasRepoChanges {
-			commitRepoChanges(cwd, formatter)
+			if err := commitRepoChanges(changes, opts, reader, formatter); err != nil {
+				failed++
+			}
 		}
 
+		if failed > 0 {
+			formatter.Error(fmt.Sprintf("%d commits failed", failed))
+			os.Exit(1)
+		}
 		formatter.Success("Commit process completed")
 	},
 }
 
 func init() {
+	commitCmd.Flags().StringVarP(&commitMessage, "message", "m", "", "Commit message for every package, prefixed with the package name")
+	commitCmd.Flags().StringVar(&commitMessages, "messages", "", "YAML file mapping packages to commit messages")
+	commitCmd.Flags().BoolVarP(&commitYes, "yes", "y", false, "Don't offer to show the diffs")
+	commitCmd.Flags().StringArrayVar(&commitOnly, "only", nil, "Only commit packages matching the filter expression (repeatable)")
+	commitCmd.Flags().BoolVar(&commitStaged, "staged", false, "Only commit staged changes, leaving unstaged changes alone")
+	commitCmd.Flags().BoolVar(&commitConventional, "conventional", false, "Write conventional commit messages with the package as scope")
+	commitCmd.Flags().StringVar(&commitType, "type", "", "Conventional commit type for messages that don't have one, e.g. feat or fix")
+	commitCmd.Flags().BoolVar(&commitBreaking, "breaking", false, "Mark the commits as breaking changes")
 	rootCmd.AddCommand(commitCmd)
 }
 
-// Find packages with changes
-func findPackagesWithChanges(packages []grit.Config, formatter *output.Formatter) []grit.Config {
-	var packagesWithChanges []grit.Config
-	
-	for _, cfg := range packages {
-		if cfg.Package.Name == "" {
-			continue // Skip root config
-		}
-		
-		pkgPath := filepath.Dir(cfg.Package.Path)
-		
-		// Check if package has changes
-		cmd := exec.Command("git", "status", "--porcelain", pkgPath)
-		output, err := cmd.Output()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to check git status for %s: %v", cfg.Package.Name, err))
+// Read a YAML file mapping package references to commit messages. The result is
+// keyed by package ID.
+func loadCommitMessages(path string, index *grit.Index) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]string
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	messages := make(map[string]string, len(raw))
+	for ref, message := range raw {
+		if ref == repoMessageKey {
+			messages[ref] = strings.TrimSpace(message)
 			continue
 		}
-		
-		if len(output) > 0 {
-			packagesWithChanges = append(packagesWithChanges, cfg)
+		cfg, err := index.Lookup(ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
 		}
+		messages[cfg.Package.ID()] = strings.TrimSpace(message)
 	}
-	
-	formatter.Info(fmt.Sprintf("Found %d packages with changes", len(packagesWithChanges)))
-	return packagesWithChanges
+	return messages, nil
 }
 
-// Check for changes outside of packages
-func checkForRepoChanges(packages []grit.Config, cwd string, formatter *output.Formatter) bool {
-	// Get all changes
-	cmd := exec.Command("git", "status", "--porcelain")
-	output, err := cmd.Output()
+// Commit the changes of a package
+func commitPackageChanges(pkg grit.Config, pkgName string, changes *workspaceChanges, opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) error {
+	formatter.Section(fmt.Sprintf("Package: %s", pkgName))
+	showChanges(changes.root, changes.byID[pkg.Package.ID()], opts, reader, formatter)
+	entries, indexOnly, err := selectChanges(changes.root, changes.byID[pkg.Package.ID()], opts, reader, formatter)
 	if err != nil {
-		formatter.Warning(fmt.Sprintf("Failed to check git status: %v", err))
-		return false
+		formatter.Error(fmt.Sprintf("Failed to select changes for %s: %v", pkgName, err))
+		return err
 	}
-	
-	if len(output) == 0 {
-		return false
+	if len(entries) == 0 {
+		formatter.Info("Nothing selected, skipping commit for this package")
+		return nil
 	}
-	
-	// Create a map of package paths
-	packagePaths := make(map[string]bool)
-	for _, cfg := range packages {
-		if cfg.Package.Name != "" {
-			pkgPath := filepath.Dir(cfg.Package.Path)
-			relPath, err := filepath.Rel(cwd, pkgPath)
-			if err == nil {
-				packagePaths[relPath] = true
-			}
-		}
+
+	commitMsg, err := commitMessageFor(pkg.Package.ID(), pkgName, pkgName, opts, reader, formatter)
+	if err != nil {
+		formatter.Error(fmt.Sprintf("Invalid commit message for %s: %v", pkgName, err))
+		return err
 	}
-	
-	// Check if there are changes outside package paths
-	lines := strings.Split(string(output), "\n")
-	for _, line := range lines {
-		if len(line) > 3 {
-			filePath := strings.TrimSpace(line[3:])
-			inPackage := false
-			
-			for pkgPath := range packagePaths {
-				if strings.HasPrefix(filePath, pkgPath) {
-					inPackage = true
-					break
-				}
-			}
-			
-			if !inPackage {
-				return true
-			}
-		}
+	if commitMsg == "" {
+		formatter.Info("Skipping commit for this package")
+		return nil
+	}
+
+	if err := commitSelection(changes.root, commitMsg, entries, indexOnly); err != nil {
+		formatter.Error(fmt.Sprintf("Failed to commit changes for %s: %v", pkgName, err))
+		return err
 	}
-	
-	return false
+	formatter.Success(fmt.Sprintf("Committed changes for %s", pkgName))
+	return nil
 }
 
-// Commit changes for a specific package
-func commitPackageChanges(pkg grit.Config, cwd string, formatter *output.Formatter) {
-	pkgPath := filepath.Dir(pkg.Package.Path)
-	
-	formatter.Section(fmt.Sprintf("Package: %s", pkg.Package.Name))
-	
-	// Show summary of changes first
-	cmd := exec.Command("git", "status", "-s", pkgPath)
-	statusOutput, err := cmd.Output()
+// Commit the changes outside of packages
+func commitRepoChanges(changes *workspaceChanges, opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) error {
+	formatter.Section("Repository Changes")
+	showChanges(changes.root, changes.repo, opts, reader, formatter)
+	entries, indexOnly, err := selectChanges(changes.root, changes.repo, opts, reader, formatter)
 	if err != nil {
-		formatter.Warning(fmt.Sprintf("Failed to get status for %s: %v", pkg.Package.Name, err))
-	} else if len(statusOutput) > 0 {
-		formatter.Detail("Summary of changes:")
-		fmt.Println(string(statusOutput))
+		formatter.Error(fmt.Sprintf("Failed to select repository changes: %v", err))
+		return err
 	}
-	
-	// Ask if user wants to see the complete diff
-	reader := bufio.NewReader(os.Stdin)
-	formatter.Info("View complete diff? (y/n):")
-	viewDiff, _ := reader.ReadString('\n')
-	viewDiff = strings.TrimSpace(viewDiff)
-	
-	if strings.ToLower(viewDiff) == "y" || strings.ToLower(viewDiff) == "yes" {
-		// First, temporarily add all files in the package to the index
-		// This allows us to see the diff for new files too
-		tempAddCmd := exec.Command("git", "add", "-N", pkgPath)
-		tempAddCmd.Run() // Ignore errors, we'll still try to show what we can
-		
-		// Show diff for all files (including new ones)
-		diffCmd := exec.Command("git", "diff", pkgPath)
-		diffCmd.Stdout = os.Stdout
-		diffCmd.Stderr = os.Stderr
-		diffCmd.Stdin = os.Stdin
-		
-		formatter.Detail("Changes:")
-		err := diffCmd.Run()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to display diff for %s: %v", pkg.Package.Name, err))
-		}
-		
-		// Also show staged changes if any
-		stagedCmd := exec.Command("git", "diff", "--cached", pkgPath)
-		stagedCmd.Stdout = os.Stdout
-		stagedCmd.Stderr = os.Stderr
-		stagedCmd.Stdin = os.Stdin
-		
-		formatter.Detail("Staged changes:")
-		err = stagedCmd.Run()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to display staged changes for %s: %v", pkg.Package.Name, err))
-		}
-		
-		// Show untracked files
-		untrackedCmd := exec.Command("git", "ls-files", "--others", "--exclude-standard", pkgPath)
-		untrackedOutput, err := untrackedCmd.Output()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to get untracked files for %s: %v", pkg.Package.Name, err))
-		} else if len(untrackedOutput) > 0 {
-			formatter.Detail("Untracked files:")
-			fmt.Println(string(untrackedOutput))
-			
-			// For each untracked file, show its content
-			files := strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n")
-			for _, file := range files {
-				if file == "" {
-					continue
-				}
-				
-				formatter.Detail(fmt.Sprintf("Content of new file: %s", file))
-				catCmd := exec.Command("cat", file)
-				catCmd.Stdout = os.Stdout
-				catCmd.Stderr = os.Stderr
-				catCmd.Run() // Ignore errors
-				fmt.Println() // Add a newline after file content
-			}
-		}
-		
-		// Reset any temporary adds we did
-		resetCmd := exec.Command("git", "reset", pkgPath)
-		resetCmd.Run() // Ignore errors
+	if len(entries) == 0 {
+		formatter.Info("Nothing selected, skipping commit for repository changes")
+		return nil
 	}
-	
-	// Ask for commit message
-	formatter.Info(fmt.Sprintf("Enter commit message for %s (or 'skip' to skip):", pkg.Package.Name))
-	message, _ := reader.ReadString('\n')
-	message = strings.TrimSpace(message)
-	
-	if message == "skip" {
-		formatter.Info("Skipping commit for this package")
-		return
-	}
-	
-	// Commit changes
-	cmd = exec.Command("git", "add", pkgPath)
-	err = cmd.Run()
+
+	message, err := commitMessageFor(repoMessageKey, "", "repository changes", opts, reader, formatter)
 	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to stage changes for %s: %v", pkg.Package.Name, err))
-		return
+		formatter.Error(fmt.Sprintf("Invalid commit message for repository changes: %v", err))
+		return err
 	}
-	
-	commitMsg := fmt.Sprintf("%s: %s", pkg.Package.Name, message)
-	cmd = exec.Command("git", "commit", "-m", commitMsg)
-	err = cmd.Run()
-	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to commit changes for %s: %v", pkg.Package.Name, err))
-		return
+	if message == "" {
+		formatter.Info("Skipping commit for repository changes")
+		return nil
+	}
+
+	if err := commitSelection(changes.root, message, entries, indexOnly); err != nil {
+		formatter.Error(fmt.Sprintf("Failed to commit repository changes: %v", err))
+		return err
 	}
-	
-	formatter.Success(fmt.Sprintf("Committed changes for %s", pkg.Package.Name))
+	formatter.Success("Committed repository changes")
+	return nil
 }
 
-// Commit changes at the repo level
-func commitRepoChanges(cwd string, formatter *output.Formatter) {
-	formatter.Section("Repository Changes")
-	
-	// Show summary of changes first
-	cmd := exec.Command("git", "status", "-s")
-	statusOutput, err := cmd.Output()
-	if err != nil {
-		formatter.Warning(fmt.Sprintf("Failed to get repo status: %v", err))
-	} else if len(statusOutput) > 0 {
-		formatter.Detail("Summary of changes:")
-		fmt.Println(string(statusOutput))
+// The changes to commit and whether only their staged content is committed.
+// Files are picked when the commit message is asked for as well.
+func selectChanges(root string, entries []statusEntry, opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) ([]statusEntry, bool, error) {
+	if opts.staged || opts.nonInteractive() {
+		return entries, opts.staged, nil
 	}
-	
-	// Ask if user wants to see the complete diff
-	reader := bufio.NewReader(os.Stdin)
-	formatter.Info("View complete diff? (y/n):")
-	viewDiff, _ := reader.ReadString('\n')
-	viewDiff = strings.TrimSpace(viewDiff)
-	
-	if strings.ToLower(viewDiff) == "y" || strings.ToLower(viewDiff) == "yes" {
-		// First, temporarily add all files to the index
-		// This allows us to see the diff for new files too
-		tempAddCmd := exec.Command("git", "add", "-N", ".")
-		tempAddCmd.Run() // Ignore errors, we'll still try to show what we can
-		
-		// Show diff for all files (including new ones)
-		diffCmd := exec.Command("git", "diff")
-		diffCmd.Stdout = os.Stdout
-		diffCmd.Stderr = os.Stderr
-		diffCmd.Stdin = os.Stdin
-		
-		formatter.Detail("Changes:")
-		err := diffCmd.Run()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to display repo diff: %v", err))
-		}
-		
-		// Also show staged changes if any
-		stagedCmd := exec.Command("git", "diff", "--cached")
-		stagedCmd.Stdout = os.Stdout
-		stagedCmd.Stderr = os.Stderr
-		stagedCmd.Stdin = os.Stdin
-		
-		formatter.Detail("Staged changes:")
-		err = stagedCmd.Run()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to display staged repo changes: %v", err))
+	selected, patch, err := pickFiles(root, entries, reader, formatter)
+	if err != nil || !patch {
+		return selected, false, err
+	}
+
+	// Nothing to commit when no change was picked
+	if ok, err := hasStagedChanges(root, changedPaths(selected)); err != nil || !ok {
+		return nil, true, err
+	}
+	return selected, true, nil
+}
+
+// Commit the changes, or only what the index holds for them
+func commitSelection(root string, message string, entries []statusEntry, indexOnly bool) error {
+	if indexOnly {
+		return commitIndexPaths(root, message, changedPaths(entries))
+	}
+	return commitChanges(root, message, entries)
+}
+
+// Stage and commit exactly the files of the changes
+func commitChanges(root string, message string, entries []statusEntry) error {
+	var unstaged []string
+	for _, entry := range entries {
+		if entry.unstaged() {
+			unstaged = append(unstaged, entry.Path)
 		}
-		
-		// Show untracked files
-		untrackedCmd := exec.Command("git", "ls-files", "--others", "--exclude-standard")
-		untrackedOutput, err := untrackedCmd.Output()
-		if err != nil {
-			formatter.Warning(fmt.Sprintf("Failed to get untracked repo files: %v", err))
-		} else if len(untrackedOutput) > 0 {
-			formatter.Detail("Untracked files:")
-			fmt.Println(string(untrackedOutput))
-			
-			// For each untracked file, show its content
-			files := strings.Split(strings.TrimSpace(string(untrackedOutput)), "\n")
-			for _, file := range files {
-				if file == "" {
-					continue
-				}
-				
-				formatter.Detail(fmt.Sprintf("Content of new file: %s", file))
-				catCmd := exec.Command("cat", file)
-				catCmd.Stdout = os.Stdout
-				catCmd.Stderr = os.Stderr
-				catCmd.Run() // Ignore errors
-				fmt.Println() // Add a newline after file content
-			}
+	}
+	if len(unstaged) > 0 {
+		if err := stagePaths(root, unstaged); err != nil {
+			return err
 		}
-		
-		// Reset any temporary adds we did
-		resetCmd := exec.Command("git", "reset")
-		resetCmd.Run() // Ignore errors
 	}
-	
-	// Ask for commit message
-	formatter.Info("Enter commit message for repository changes (or 'skip' to skip):")
-	message, _ := reader.ReadString('\n')
-	message = strings.TrimSpace(message)
-	
-	if message == "skip" {
-		formatter.Info("Skipping commit for repository changes")
-		return
+	return commitPaths(root, message, changedPaths(entries))
+}
+
+// Print a summary of the changes and offer to show the complete diff
+func showChanges(root string, entries []statusEntry, opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) {
+	formatter.Detail("Summary of changes:")
+	for i, entry := range entries {
+		fmt.Printf("%3d  %s\n", i+1, entry)
 	}
-	
-	// Commit changes
-	cmd = exec.Command("git", "add", ".")
-	err = cmd.Run()
-	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to stage repository changes: %v", err))
+	fmt.Println()
+
+	if !askViewDiff(opts, reader, formatter) {
 		return
 	}
-	
-	cmd = exec.Command("git", "commit", "-m", message)
-	err = cmd.Run()
-	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to commit repository changes: %v", err))
-		return
+
+	var tracked, untracked []string
+	for _, entry := range entries {
+		if entry.untracked() {
+			untracked = append(untracked, entry.Path)
+		} else {
+			tracked = append(tracked, entry.paths()...)
+		}
 	}
-	
-	formatter.Success("Committed repository changes")
-}
\ No newline at end of file
+
+	if len(tracked) > 0 {
+		for _, diff := range []struct {
+			title string
+			args  []string
+		}{{"Changes:", []string{"diff"}}, {"Staged changes:", []string{"diff", "--cached"}}} {
+			formatter.Detail(diff.title)
+			args := append([]string{"--literal-pathspecs"}, diff.args...)
+			cmd := exec.Command("git", append(append(args, "--"), tracked...)...)
+			cmd.Dir = root
+			cmd.Stdout = os.Stdout
+			cmd.Stderr = os.Stderr
+			if err := cmd.Run(); err != nil {
+				formatter.Warning(fmt.Sprintf("Failed to display diff: %v", err))
+			}
+		}
+	}
+
+	// Show the content of new files
+	for _, path := range untracked {
+		formatter.Detail(fmt.Sprintf("Content of new file: %s", path))
+		content, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+		if err != nil {
+			formatter.Warning(fmt.Sprintf("Failed to read %s: %v", path, err))
+			continue
+		}
+		os.Stdout.Write(content)
+		fmt.Println() // Add a newline after file content
+	}
+}
+
+// Offer to show the complete diff, unless prompts are disabled
+func askViewDiff(opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) bool {
+	if opts.yes || opts.nonInteractive() {
+		return false
+	}
+	return confirm("View complete diff? (y/n):", reader, formatter)
+}
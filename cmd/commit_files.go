@@ -0,0 +1,129 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/weslien/grit/pkg/output"
+)
+
+// Ask which of the changed files of a package to commit: all of them, some by
+// their number in the summary, or hunks picked with git add -p. The returned
+// entries are nil when the package is skipped, and patch is true when the
+// selection was staged with git add -p and only the index is to be committed.
+func pickFiles(root string, entries []statusEntry, reader *bufio.Reader, formatter *output.Formatter) (selected []statusEntry, patch bool, err error) {
+	for {
+		formatter.Info("Files to commit: [a]ll, numbers like 1,3-4, [p]atch or [s]kip (default all):")
+		input, _ := reader.ReadString('\n')
+		input = strings.TrimSpace(input)
+		switch strings.ToLower(input) {
+		case "", "a", "all":
+			return entries, false, nil
+		case "s", "skip":
+			return nil, false, nil
+		case "p", "patch":
+			tracked, err := addPatch(root, entries)
+			if err != nil {
+				return nil, false, err
+			}
+			if tracked == nil {
+				formatter.Warning("No tracked files to pick changes from, select new files by number")
+				continue
+			}
+			return tracked, true, nil
+		}
+
+		indexes, err := parseSelection(input, len(entries))
+		if err != nil {
+			formatter.Error(err.Error())
+			continue
+		}
+		for _, i := range indexes {
+			selected = append(selected, entries[i])
+		}
+		return selected, false, nil
+	}
+}
+
+// A reader for prompt answers that doesn't read past the end of the line it
+// returns, so commands run in between, like git add -p, can read the rest of the
+// input from the same stream
+func newPromptReader(r io.Reader) *bufio.Reader {
+	return bufio.NewReader(byteReader{r})
+}
+
+// Reads at most a byte at a time, so a bufio.Reader on top of it never buffers
+// more than it was asked for
+type byteReader struct {
+	r io.Reader
+}
+
+func (b byteReader) Read(p []byte) (int, error) {
+	if len(p) > 1 {
+		p = p[:1]
+	}
+	return b.r.Read(p)
+}
+
+// Run git add -p on the tracked files of the changes and return their entries.
+// New files can't be staged in parts, so they are left out.
+func addPatch(root string, entries []statusEntry) ([]statusEntry, error) {
+	var tracked []statusEntry
+	for _, entry := range entries {
+		if !entry.untracked() {
+			tracked = append(tracked, entry)
+		}
+	}
+	if len(tracked) == 0 {
+		return nil, nil
+	}
+
+	cmd := exec.Command("git", append([]string{"--literal-pathspecs", "add", "-p", "--"}, changedPaths(tracked)...)...)
+	cmd.Dir = root
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git add -p: %w", err)
+	}
+	return tracked, nil
+}
+
+// Parse a selection of the numbers 1 to n like "1,3-4 6" into zero based
+// indexes, in the order given and without duplicates
+func parseSelection(input string, n int) ([]int, error) {
+	var indexes []int
+	seen := make(map[int]bool)
+	fields := strings.FieldsFunc(input, func(r rune) bool { return r == ',' || r == ' ' })
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("no files selected")
+	}
+	for _, field := range fields {
+		from, to, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid selection %q, expected numbers like 1,3-4", field)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid selection %q, expected numbers like 1,3-4", field)
+			}
+		}
+		if first < 1 || last > n || first > last {
+			return nil, fmt.Errorf("selection %q is out of range, files are numbered 1 to %d", field, n)
+		}
+		for i := first; i <= last; i++ {
+			if !seen[i] {
+				seen[i] = true
+				indexes = append(indexes, i-1)
+			}
+		}
+	}
+	return indexes, nil
+}
@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+// Get the message for a commit of a package, or of the repository when scope is
+// empty. Returns an empty message when the commit is skipped. Messages typed in
+// that fail validation are asked for again.
+func commitMessageFor(key string, scope string, label string, opts commitOptions, reader *bufio.Reader, formatter *output.Formatter) (string, error) {
+	message, given := opts.messageFor(key)
+	for {
+		commitType := opts.commitType
+		if !given {
+			if opts.conventional && commitType == "" {
+				commitType = promptCommitType(opts.config.CommitTypes(), reader, formatter)
+			}
+			formatter.Info(fmt.Sprintf("Enter commit message for %s (or 'skip' to skip):", label))
+			message, _ = reader.ReadString('\n')
+			message = strings.TrimSpace(message)
+		}
+		if message == "skip" || message == "" {
+			return "", nil
+		}
+
+		composed, err := composeCommitMessage(message, scope, commitType, opts, !given, reader, formatter)
+		if err == nil || given {
+			return composed, err
+		}
+		formatter.Error(err.Error())
+	}
+}
+
+// Turn a message into the commit message. Package commits are prefixed with the
+// package name, or in conventional mode get the package as their scope and the
+// commit type unless the message starts with a type of its own.
+func composeCommitMessage(message string, scope string, commitType string, opts commitOptions, interactive bool, reader *bufio.Reader, formatter *output.Formatter) (string, error) {
+	if !opts.conventional {
+		if scope == "" {
+			return message, nil
+		}
+		return fmt.Sprintf("%s: %s", scope, message), nil
+	}
+
+	commit, ok := opts.config.ParseCommitHeader(message)
+	if !ok {
+		header, body, _ := strings.Cut(message, "\n")
+		if commitType == "" {
+			return "", fmt.Errorf("%w: %q has no commit type, use --type or write it as type: subject", grit.ErrInvalidCommitMessage, header)
+		}
+		commit = grit.ConventionalCommit{Type: commitType, Subject: strings.TrimSpace(header), Body: strings.TrimSpace(body)}
+	}
+	if commit.Scope == "" {
+		commit.Scope = scope
+	}
+	if opts.breaking {
+		commit.Breaking = true
+	}
+
+	if interactive && !commit.Breaking && confirm("Is this a breaking change? (y/N):", reader, formatter) {
+		commit.Breaking = true
+		formatter.Info("Describe the breaking change (optional):")
+		note, _ := reader.ReadString('\n')
+		if note = strings.TrimSpace(note); note != "" {
+			commit.Body = strings.TrimSpace(commit.Body + "\n\nBREAKING CHANGE: " + note)
+		}
+	}
+
+	composed := commit.String()
+	if err := opts.config.ValidateCommitMessage(composed); err != nil {
+		return "", err
+	}
+	return composed, nil
+}
+
+// Ask for a commit type by number or name until an allowed one is given
+func promptCommitType(types []string, reader *bufio.Reader, formatter *output.Formatter) string {
+	for {
+		formatter.Info("Commit type:")
+		for i, commitType := range types {
+			formatter.Detail(fmt.Sprintf("%d) %s", i+1, commitType))
+		}
+		answer, err := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if n, convErr := strconv.Atoi(answer); convErr == nil && n >= 1 && n <= len(types) {
+			return types[n-1]
+		}
+		if containsString(types, answer) {
+			return answer
+		}
+		if err != nil {
+			// Out of input, leave the type to validation
+			return answer
+		}
+		formatter.Warning(fmt.Sprintf("Unknown commit type %q", answer))
+	}
+}
+
+func confirm(question string, reader *bufio.Reader, formatter *output.Formatter) bool {
+	formatter.Info(question)
+	answer, _ := reader.ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
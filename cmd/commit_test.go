@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestLoadCommitMessages(t *testing.T) {
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "utils", Type: "lib"}},
+		{Package: grit.Package{Name: "web", Type: "app"}},
+	})
+	path := filepath.Join(t.TempDir(), "messages.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("utils: add retries\napp/web: |\n  fix layout\n\n  Closes #12\n.: update readme\n"), 0644))
+
+	messages, err := loadCommitMessages(path, index)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"lib/utils": "add retries",
+		"app/web":   "fix layout\n\nCloses #12",
+		".":         "update readme",
+	}, messages)
+
+	require.NoError(t, os.WriteFile(path, []byte("missing: message\n"), 0644))
+	_, err = loadCommitMessages(path, index)
+	assert.ErrorIs(t, err, grit.ErrPackageNotFound)
+}
+
+func TestCommitOptionsMessageFor(t *testing.T) {
+	message, ok := commitOptions{}.messageFor("lib/utils")
+	assert.False(t, ok, "interactive without messages")
+	assert.Empty(t, message)
+
+	opts := commitOptions{message: "bump", messages: map[string]string{"lib/utils": "add retries"}}
+	message, ok = opts.messageFor("lib/utils")
+	assert.True(t, ok)
+	assert.Equal(t, "add retries", message)
+	message, ok = opts.messageFor("app/web")
+	assert.True(t, ok)
+	assert.Equal(t, "bump", message)
+
+	// Packages missing from a messages file are skipped
+	opts = commitOptions{messages: map[string]string{"lib/utils": "add retries"}}
+	message, ok = opts.messageFor("app/web")
+	assert.True(t, ok)
+	assert.Empty(t, message)
+}
+
+func TestParseSelection(t *testing.T) {
+	indexes, err := parseSelection("3, 1-2 2", 4)
+	require.NoError(t, err)
+	assert.Equal(t, []int{2, 0, 1}, indexes)
+
+	for _, input := range []string{"", "0", "5", "2-1", "a", "1-x"} {
+		_, err := parseSelection(input, 4)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestPromptReader(t *testing.T) {
+	input := strings.NewReader("p\ny\nn\nfix things\n")
+	reader := newPromptReader(input)
+	answer, err := reader.ReadString('\n')
+	require.NoError(t, err)
+	assert.Equal(t, "p\n", answer)
+
+	// The answers for git add -p are still in the input
+	rest, err := io.ReadAll(input)
+	require.NoError(t, err)
+	assert.Equal(t, "y\nn\nfix things\n", string(rest))
+}
+
+func TestComposeCommitMessage(t *testing.T) {
+	compose := func(message string, scope string, commitType string, opts commitOptions) (string, error) {
+		return composeCommitMessage(message, scope, commitType, opts, false, nil, nil)
+	}
+
+	message, err := compose("add retries", "utils", "", commitOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "utils: add retries", message)
+	message, err = compose("update readme", "", "", commitOptions{})
+	require.NoError(t, err)
+	assert.Equal(t, "update readme", message)
+
+	opts := commitOptions{conventional: true}
+	message, err = compose("feat: add retries", "utils", "", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "feat(utils): add retries", message)
+	message, err = compose("handle empty input\n\nCloses #3", "utils", "fix", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "fix(utils): handle empty input\n\nCloses #3", message)
+	message, err = compose("fix(io): close files", "utils", "", commitOptions{conventional: true, breaking: true})
+	require.NoError(t, err)
+	assert.Equal(t, "fix(io)!: close files", message)
+	message, err = compose("docs: update readme", "", "", opts)
+	require.NoError(t, err)
+	assert.Equal(t, "docs: update readme", message)
+
+	_, err = compose("add retries", "utils", "", opts)
+	assert.ErrorIs(t, err, grit.ErrInvalidCommitMessage)
+	_, err = compose("add retries", "utils", "wip", opts)
+	assert.ErrorIs(t, err, grit.ErrInvalidCommitMessage)
+	opts.config.Pattern = `^\w+\([\w/-]+\)!?: [a-z]`
+	_, err = compose("docs: update readme", "", "", opts)
+	assert.ErrorIs(t, err, grit.ErrInvalidCommitMessage)
+}
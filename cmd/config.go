@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"gopkg.in/yaml.v3"
+)
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the workspace configuration",
+}
+
+var configShowCmd = &cobra.Command{
+	Use:   "show [type|package]",
+	Short: "Print the effective configuration",
+	Long: `Print the fully merged configuration, with included files and type inheritance applied.
+
+Without arguments the root config is printed. Given the name of a type, the
+effective config of that type is printed. Otherwise the argument is resolved as
+a package (name or type/name) and its config is printed together with every
+target it can run and the level each target is defined at.
+
+Examples:
+  grit config show              # Effective root config
+  grit config show lib          # Effective config of the lib type
+  grit config show lib/utils    # Effective config of a package`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("failed to get current directory: %w", err)
+		}
+
+		rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
+		if err != nil {
+			return fmt.Errorf("failed to load config: %w", err)
+		}
+
+		var data []byte
+		if len(args) == 0 {
+			data, err = grit.EncodeConfig(rootConfig)
+		} else if typeConfig, exists := rootConfig.Types[args[0]]; exists {
+			data, err = grit.EncodeConfig(map[string]grit.TypeConfig{args[0]: typeConfig})
+		} else {
+			packages, loadErr := grit.NewPackageManager(cwd).LoadPackagesWithConfig(rootConfig)
+			if loadErr != nil {
+				return fmt.Errorf("failed to load packages: %w", loadErr)
+			}
+			cfg, lookupErr := grit.NewIndex(packages).Lookup(args[0])
+			if lookupErr != nil {
+				return fmt.Errorf("'%s' is neither a type nor a package: %w", args[0], lookupErr)
+			}
+			data, err = encodeEffectivePackageConfig(rootConfig, cfg)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to encode config: %w", err)
+		}
+
+		fmt.Fprint(cmd.OutOrStdout(), string(data))
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// Encode a package config with its resolved targets, each annotated with the
+// level it was resolved from
+func encodeEffectivePackageConfig(rootConfig *grit.RootConfig, cfg grit.Config) ([]byte, error) {
+	pkgNode := &yaml.Node{}
+	if err := pkgNode.Encode(cfg.Package); err != nil {
+		return nil, err
+	}
+
+	targetsNode := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+	for _, target := range rootConfig.ResolveTargets(cfg) {
+		targetsNode.Content = append(targetsNode.Content,
+			&yaml.Node{Kind: yaml.ScalarNode, Value: target.Name},
+			&yaml.Node{Kind: yaml.ScalarNode, Value: target.Command, LineComment: "from " + string(target.Source)},
+		)
+	}
+
+	doc := &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map", Content: []*yaml.Node{
+		{Kind: yaml.ScalarNode, Value: "package"}, pkgNode,
+		{Kind: yaml.ScalarNode, Value: "type"}, {Kind: yaml.ScalarNode, Value: cfg.Package.Type},
+		{Kind: yaml.ScalarNode, Value: "path"}, {Kind: yaml.ScalarNode, Value: cfg.Package.Path},
+		{Kind: yaml.ScalarNode, Value: "targets"}, targetsNode,
+	}}
+	return grit.EncodeConfig(doc)
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var addDepCmd = &cobra.Command{
+	Use:   "add-dep <package> <dependency>...",
+	Short: "Add dependencies to a package",
+	Long: `Add one or more dependencies to a package and update its grit.yaml.
+
+Packages are referenced by bare name or type/name. Before anything is written
+each dependency is checked: it must exist, the can_depend_on rules of the
+package's type must allow it, and it must not introduce a dependency cycle.
+
+Examples:
+  grit add-dep web utils               # app/web depends on lib/utils
+  grit add-dep app/web lib/utils core`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		cfg, err := index.Lookup(args[0])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+			os.Exit(1)
+		}
+
+		deps, added, err := planAddDependencies(index, rootConfig, cfg.Package, args[1:])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Can't add dependency to %s: %v", cfg.Package.ID(), err))
+			os.Exit(1)
+		}
+		if len(added) == 0 {
+			formatter.Info(fmt.Sprintf("%s already depends on %s", cfg.Package.ID(), strings.Join(args[1:], ", ")))
+			return
+		}
+
+		if err := writeDependencies(cfg.Package.Path, deps); err != nil {
+			formatter.Error(fmt.Sprintf("Error updating %s: %v", relativePath(cwd, cfg.Package.Path), err))
+			os.Exit(1)
+		}
+		formatter.Success(fmt.Sprintf("%s now depends on %s", cfg.Package.ID(), strings.Join(added, ", ")))
+	},
+}
+
+var removeDepCmd = &cobra.Command{
+	Use:   "remove-dep <package> <dependency>...",
+	Short: "Remove dependencies from a package",
+	Long: `Remove one or more dependencies from a package and update its grit.yaml.
+
+A dependency can be given by bare name or type/name, regardless of how it is
+written in grit.yaml. References to packages that no longer exist can be
+removed by the name they are written as.
+
+Examples:
+  grit remove-dep web utils
+  grit remove-dep app/web lib/utils`,
+	Args: cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, _ := loadWorkspaceIndex(formatter)
+		cfg, err := index.Lookup(args[0])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+			os.Exit(1)
+		}
+
+		deps, removed, err := planRemoveDependencies(index, cfg.Package, args[1:])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Can't remove dependency from %s: %v", cfg.Package.ID(), err))
+			os.Exit(1)
+		}
+
+		if err := writeDependencies(cfg.Package.Path, deps); err != nil {
+			formatter.Error(fmt.Sprintf("Error updating %s: %v", relativePath(cwd, cfg.Package.Path), err))
+			os.Exit(1)
+		}
+		formatter.Success(fmt.Sprintf("%s no longer depends on %s", cfg.Package.ID(), strings.Join(removed, ", ")))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(addDepCmd)
+	rootCmd.AddCommand(removeDepCmd)
+}
+
+// Validate new dependencies and return the updated dependency list together with
+// the IDs of the packages that were added. Dependencies are written by their
+// type/name ID, which stays unambiguous when another type later gets a package
+// of the same name; ones the package already has are skipped.
+func planAddDependencies(index *grit.Index, rootConfig *grit.RootConfig, pkg grit.Package, refs []string) ([]string, []string, error) {
+	deps := append([]string{}, pkg.Dependencies...)
+	existing, _ := index.Dependencies(pkg)
+
+	var added []string
+	for _, ref := range refs {
+		dep, err := index.Lookup(ref)
+		if err != nil {
+			return nil, nil, err
+		}
+		if containsString(existing, dep.Package.ID()) {
+			continue
+		}
+		if err := index.CheckDependency(rootConfig, pkg, dep.Package); err != nil {
+			return nil, nil, err
+		}
+		deps = append(deps, dep.Package.ID())
+		existing = append(existing, dep.Package.ID())
+		added = append(added, dep.Package.ID())
+	}
+	return deps, added, nil
+}
+
+// Return the dependency list without the referenced packages, and the references
+// that were removed. Every reference must match a dependency of the package.
+func planRemoveDependencies(index *grit.Index, pkg grit.Package, refs []string) ([]string, []string, error) {
+	deps := append([]string{}, pkg.Dependencies...)
+
+	var removed []string
+	for _, ref := range refs {
+		// Match by package identity, so lib/utils removes a dependency written as utils
+		target := ref
+		if dep, err := index.Lookup(ref); err == nil {
+			target = dep.Package.ID()
+		}
+
+		remaining := deps[:0:0]
+		found := false
+		for _, written := range deps {
+			id := written
+			if dep, err := index.Lookup(written); err == nil {
+				id = dep.Package.ID()
+			}
+			if id == target || written == ref {
+				found = true
+				continue
+			}
+			remaining = append(remaining, written)
+		}
+		if !found {
+			return nil, nil, fmt.Errorf("%s is not a dependency", ref)
+		}
+		deps = remaining
+		removed = append(removed, ref)
+	}
+	return deps, removed, nil
+}
+
+// Update the dependencies in a package grit.yaml, keeping its formatting
+func writeDependencies(path string, deps []string) error {
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		return err
+	}
+	if err := doc.Set([]string{"package", "dependencies"}, deps); err != nil {
+		return err
+	}
+	return doc.Save()
+}
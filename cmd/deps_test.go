@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestPlanDependencies(t *testing.T) {
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"app": {CanDependOn: []string{"lib"}},
+	}}
+	web := grit.Package{Name: "web", Type: "app", Dependencies: []string{"utils", "ghost"}}
+	index := grit.NewIndex([]grit.Config{
+		{Package: web},
+		{Package: grit.Package{Name: "utils", Type: "lib"}},
+		{Package: grit.Package{Name: "core", Type: "lib"}},
+		{Package: grit.Package{Name: "core", Type: "service"}},
+		{Package: grit.Package{Name: "helpers", Type: "lib"}},
+	})
+
+	// New dependencies are written by ID, even when the bare name is unambiguous
+	deps, added, err := planAddDependencies(index, rootConfig, web, []string{"lib/utils", "lib/core", "helpers"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lib/core", "lib/helpers"}, added)
+	assert.Equal(t, []string{"utils", "ghost", "lib/core", "lib/helpers"}, deps)
+
+	_, _, err = planAddDependencies(index, rootConfig, web, []string{"service/core"})
+	assert.ErrorIs(t, err, grit.ErrDependencyNotAllowed)
+	_, _, err = planAddDependencies(index, rootConfig, web, []string{"core"})
+	assert.ErrorIs(t, err, grit.ErrAmbiguousPackage)
+
+	deps, removed, err := planRemoveDependencies(index, web, []string{"lib/utils", "ghost"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"lib/utils", "ghost"}, removed)
+	assert.Empty(t, deps)
+
+	_, _, err = planRemoveDependencies(index, web, []string{"lib/core"})
+	assert.Error(t, err)
+}
+
+func TestWriteDependencies(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grit.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("package:\n  name: web # the web app\n  dependencies:\n    - utils # shared helpers\n"), 0644))
+
+	require.NoError(t, writeDependencies(path, []string{"utils", "core"}))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package:\n  name: web # the web app\n  dependencies:\n    - utils # shared helpers\n    - core\n", string(data))
+}
@@ -33,6 +33,7 @@ var dirtyCmd = &cobra.Command{
 			os.Exit(1)
 		}
 		formatter.Success(fmt.Sprintf("Loaded %d packages", len(packages)))
+		index := grit.NewIndex(packages)
 
 		formatter.Section("Checking for Changes")
 		
@@ -49,19 +50,19 @@ var dirtyCmd = &cobra.Command{
 			cfgDir := filepath.Dir(cfg.Package.Path)
 			newHash, err := calculatePackageHash(cfgDir)
 			if err != nil {
-				formatter.Warning(fmt.Sprintf("Could not calculate hash for %s: %v", cfg.Package.Name, err))
+				formatter.Warning(fmt.Sprintf("Could not calculate hash for %s: %v", index.Name(cfg.Package), err))
 				dirtyPackages = append(dirtyPackages, cfg) // Include if we can't determine
 				continue
 			}
 			
-			cacheFile := filepath.Join(cacheDir, cfg.Package.Name+".hash")
+			cacheFile := packageCacheFile(cacheDir, cfg.Package)
 			isDirty := false
 			
 			if cachedHash, err := os.ReadFile(cacheFile); err != nil {
-				formatter.Detail(fmt.Sprintf("%s: No cache found", cfg.Package.Name))
+				formatter.Detail(fmt.Sprintf("%s: No cache found", index.Name(cfg.Package)))
 				isDirty = true
 			} else if string(cachedHash) != newHash {
-				formatter.Detail(fmt.Sprintf("%s: Files changed", cfg.Package.Name))
+				formatter.Detail(fmt.Sprintf("%s: Files changed", index.Name(cfg.Package)))
 				isDirty = true
 			}
 			
@@ -76,7 +77,7 @@ var dirtyCmd = &cobra.Command{
 		} else {
 			formatter.Info(fmt.Sprintf("Found %d dirty packages:", len(dirtyPackages)))
 			for _, pkg := range dirtyPackages {
-				formatter.Detail(pkg.Package.Name)
+				formatter.Detail(index.Name(pkg.Package))
 			}
 		}
 	},
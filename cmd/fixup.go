@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var fixupCheck bool
+
+// A config file rewrite planned by fixup
+type fileFix struct {
+	path   string
+	before []byte
+	after  []byte
+	notes  []string
+}
+
+type fixupPlan struct {
+	dirs  []string
+	files []fileFix
+}
+
+var fixupCmd = &cobra.Command{
+	Use:   "fixup",
+	Short: "Repair and normalize the workspace",
+	Long: `Reconcile the workspace with its configuration:
+- Create missing standard directories (src, .prompt, .mod, .dev, .ops) for each package
+- Register types for directories under packages/ that no type covers
+- Remove dependencies on packages that don't exist
+- Fill in missing package versions
+- Normalize the formatting and key order of grit.yaml files
+
+Examples:
+  grit fixup           # Apply all fixes
+  grit fixup --check   # Print what would change and exit non-zero if anything would`,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Fixup")
+		formatter.Section("Checking Workspace")
+
+		plan, err := planFixup(cwd, formatter)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error checking workspace: %v", err))
+			os.Exit(1)
+		}
+
+		if len(plan.dirs) == 0 && len(plan.files) == 0 {
+			formatter.Success("Workspace is up to date")
+			return
+		}
+
+		printFixupPlan(plan, cwd, formatter)
+
+		if fixupCheck {
+			formatter.NewLine()
+			formatter.Error("Workspace needs fixup, run 'grit fixup' to apply the changes")
+			os.Exit(1)
+		}
+
+		formatter.Section("Applying Fixes")
+		if err := applyFixupPlan(plan); err != nil {
+			formatter.Error(fmt.Sprintf("Error applying fixes: %v", err))
+			os.Exit(1)
+		}
+		formatter.Success(fmt.Sprintf("Created %d directories and updated %d files", len(plan.dirs), len(plan.files)))
+	},
+}
+
+func init() {
+	fixupCmd.Flags().BoolVar(&fixupCheck, "check", false, "Only report what would change, exit non-zero if the workspace needs fixup")
+	rootCmd.AddCommand(fixupCmd)
+}
+
+func planFixup(cwd string, formatter *output.Formatter) (*fixupPlan, error) {
+	plan := &fixupPlan{}
+
+	rootConfigPath := filepath.Join(cwd, "grit.yaml")
+	rootBefore, err := os.ReadFile(rootConfigPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read root config, run 'grit init' first: %w", err)
+	}
+
+	// Fixes are written to the root config document, while the effective config
+	// with includes and inheritance applied is used to discover packages
+	rootDoc, err := grit.LoadDocument(rootConfigPath)
+	if err != nil {
+		return nil, err
+	}
+	rootConfig, err := grit.LoadConfig(rootConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Register types for directories under packages/ that no type covers
+	var rootNotes []string
+	entries, err := os.ReadDir(filepath.Join(cwd, "packages"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if !entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		typeName := entry.Name()
+		if rootConfig.TypeForPath(filepath.Join("packages", typeName)) != "" {
+			continue
+		}
+		if _, exists := rootConfig.Types[typeName]; exists {
+			formatter.Warning(fmt.Sprintf("Type %s exists but doesn't cover packages/%s", typeName, typeName))
+			continue
+		}
+
+		if err := rootDoc.Set([]string{"types", typeName}, defaultTypeConfig(typeName)); err != nil {
+			return nil, err
+		}
+		rootConfig.Types[typeName] = defaultTypeConfig(typeName)
+		rootNotes = append(rootNotes, fmt.Sprintf("register type %s", typeName))
+		for _, dir := range typeDirs(typeName) {
+			plan.addDir(filepath.Join(cwd, dir))
+		}
+	}
+
+	// Discover packages with the updated types so newly registered ones are included
+	pm := grit.NewPackageManager(cwd)
+	packages, err := pm.LoadPackagesWithConfig(rootConfig)
+	if err != nil {
+		return nil, err
+	}
+	index := grit.NewIndex(packages)
+	formatter.Success(fmt.Sprintf("Loaded %d packages", len(index.Packages())))
+
+	var rootSchema interface{} = grit.RootConfig{}
+	for _, cfg := range index.Packages() {
+		var notes []string
+		pkgDir := filepath.Dir(cfg.Package.Path)
+
+		for _, subdir := range packageSubdirs {
+			plan.addDir(filepath.Join(pkgDir, subdir))
+		}
+
+		// A package at the workspace root is fixed in the root config document,
+		// so the fixes of both end up in the one file
+		doc := rootDoc
+		if isRootPackage(cwd, cfg.Package) {
+			rootSchema = grit.RootPackageConfig{}
+		} else if doc, err = grit.LoadDocument(cfg.Package.Path); err != nil {
+			return nil, err
+		}
+
+		if cfg.Package.Version == "" {
+			if err := doc.Set([]string{"package", "version"}, "0.1.0"); err != nil {
+				return nil, err
+			}
+			notes = append(notes, "set missing version")
+		}
+
+		deps := cfg.Package.Dependencies
+		_, depErrs := index.Dependencies(cfg.Package)
+		for _, depErr := range depErrs {
+			if !errors.Is(depErr, grit.ErrPackageNotFound) {
+				formatter.Warning(fmt.Sprintf("Package %s: %v", index.Name(cfg.Package), depErr))
+				continue
+			}
+			deps = removeString(deps, depErr.Ref)
+			notes = append(notes, fmt.Sprintf("remove missing dependency %s", depErr.Ref))
+		}
+		if len(deps) != len(cfg.Package.Dependencies) {
+			if err := doc.Set([]string{"package", "dependencies"}, deps); err != nil {
+				return nil, err
+			}
+		}
+
+		if doc == rootDoc {
+			rootNotes = append(rootNotes, notes...)
+			continue
+		}
+		before, err := os.ReadFile(cfg.Package.Path)
+		if err != nil {
+			return nil, err
+		}
+		doc.Normalize(grit.Config{})
+		after, err := doc.Bytes()
+		if err != nil {
+			return nil, err
+		}
+		plan.addFile(cfg.Package.Path, before, after, notes)
+	}
+
+	rootDoc.Normalize(rootSchema)
+	rootAfter, err := rootDoc.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	plan.addFile(rootConfigPath, rootBefore, rootAfter, rootNotes)
+
+	return plan, nil
+}
+
+// Plan a directory for creation if it doesn't exist yet
+func (p *fixupPlan) addDir(dir string) {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		p.dirs = append(p.dirs, dir)
+	}
+}
+
+// Plan a file rewrite if the content changes
+func (p *fixupPlan) addFile(path string, before []byte, after []byte, notes []string) {
+	if string(before) == string(after) {
+		return
+	}
+	if len(notes) == 0 {
+		notes = []string{"normalize formatting"}
+	}
+	p.files = append(p.files, fileFix{path: path, before: before, after: after, notes: notes})
+}
+
+func printFixupPlan(plan *fixupPlan, cwd string, formatter *output.Formatter) {
+	if len(plan.dirs) > 0 {
+		formatter.Section("Missing Directories")
+		for _, dir := range plan.dirs {
+			formatter.Detail(relativePath(cwd, dir))
+		}
+	}
+
+	if len(plan.files) > 0 {
+		formatter.Section("Config Changes")
+		for _, fix := range plan.files {
+			relPath := relativePath(cwd, fix.path)
+			formatter.Info(fmt.Sprintf("%s: %s", relPath, strings.Join(fix.notes, ", ")))
+			fmt.Print(unifiedDiff(relPath, string(fix.before), string(fix.after)))
+		}
+	}
+}
+
+func applyFixupPlan(plan *fixupPlan) error {
+	for _, dir := range plan.dirs {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return fmt.Errorf("failed to create directory %s: %w", dir, err)
+		}
+	}
+	for _, fix := range plan.files {
+		if err := os.WriteFile(fix.path, fix.after, 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", fix.path, err)
+		}
+	}
+	return nil
+}
+
+// Path relative to the workspace root for display, falling back to the path itself
+func relativePath(cwd string, path string) string {
+	if rel, err := filepath.Rel(cwd, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+// Return a copy of values without any occurrence of value
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			result = append(result, v)
+		}
+	}
+	return result
+}
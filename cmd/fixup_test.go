@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/output"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	assert.Empty(t, unifiedDiff("a.yaml", "same\n", "same\n"))
+
+	diff := unifiedDiff("a.yaml", "one\ntwo\nthree\n", "one\n2\nthree\nfour\n")
+	assert.Equal(t, `--- a/a.yaml
++++ b/a.yaml
+@@ -1,3 +1,4 @@
+ one
+-two
++2
+ three
++four
+`, diff)
+}
+
+func TestPlanFixup(t *testing.T) {
+	root := t.TempDir()
+	write := func(path string, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(root, path)), 0755))
+		require.NoError(t, os.WriteFile(filepath.Join(root, path), []byte(content), 0644))
+	}
+	write("grit.yaml", "types:\n  lib:\n    package_dir: packages/lib\n")
+	write("packages/lib/core/grit.yaml", "package:\n  name: core\n  version: 1.0.0\n  dependencies: []\ntargets: {}\n")
+	write("packages/app/web/grit.yaml", "package:\n  name: web\n  dependencies: [core, ghost]\n")
+	for _, dir := range packageSubdirs {
+		require.NoError(t, os.MkdirAll(filepath.Join(root, "packages/lib/core", dir), 0755))
+	}
+
+	plan, err := planFixup(root, output.New())
+	require.NoError(t, err)
+
+	// The app type is registered and the web package picked up with it
+	assert.Contains(t, plan.dirs, filepath.Join(root, ".prompt", "app"))
+	assert.Contains(t, plan.dirs, filepath.Join(root, "packages/app/web/src"))
+	assert.NotContains(t, plan.dirs, filepath.Join(root, "packages/lib/core/src"))
+
+	files := make(map[string]fileFix)
+	for _, fix := range plan.files {
+		rel, _ := filepath.Rel(root, fix.path)
+		files[rel] = fix
+	}
+	require.Contains(t, files, "grit.yaml")
+	assert.Contains(t, string(files["grit.yaml"].after), "package_dir: packages/app")
+	assert.NotContains(t, files, filepath.Join("packages/lib/core/grit.yaml"))
+
+	web := files[filepath.Join("packages/app/web/grit.yaml")]
+	assert.Equal(t, "set missing version, remove missing dependency ghost", strings.Join(web.notes, ", "))
+	assert.Contains(t, string(web.after), "version: 0.1.0")
+	assert.NotContains(t, string(web.after), "ghost")
+}
+
+func TestPlanFixupRootPackage(t *testing.T) {
+	root := t.TempDir()
+	data := "version: 1\nrepo:\n  name: utils\ntypes:\n  lib:\n    package_dir: .\npackage:\n  name: utils\n  dependencies: [ghost]\n"
+	require.NoError(t, os.WriteFile(filepath.Join(root, "grit.yaml"), []byte(data), 0644))
+
+	plan, err := planFixup(root, output.New())
+	require.NoError(t, err)
+
+	// The root config and package fixes are planned as one change of grit.yaml
+	require.Len(t, plan.files, 1)
+	fix := plan.files[0]
+	assert.Equal(t, filepath.Join(root, "grit.yaml"), fix.path)
+	assert.Equal(t, "set missing version, remove missing dependency ghost", strings.Join(fix.notes, ", "))
+	assert.Equal(t, "version: 1\nrepo:\n  name: utils\npackage:\n  name: utils\n  version: 0.1.0\n  dependencies: []\ntypes:\n  lib:\n    package_dir: .\n", string(fix.after))
+}
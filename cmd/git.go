@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// Run git in dir and return its output, with the error output of git in the
+// error when it fails
+func runGit(dir string, args ...string) (string, error) {
+	return runGitEnv(dir, nil, "", args...)
+}
+
+// Run git like runGit with additional environment variables, writing input to
+// its stdin unless it is empty
+func runGitEnv(dir string, env []string, input string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		// Name the subcommand, skipping global options
+		name := args[0]
+		for _, arg := range args {
+			if !strings.HasPrefix(arg, "-") {
+				name = arg
+				break
+			}
+		}
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %s", name, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", name, err)
+	}
+	return string(out), nil
+}
+
+// Files below dir changed since HEAD branched off the base revision, including
+// uncommitted and untracked files. Changes made on base after the branch point
+// don't count, like with base...HEAD. Paths are relative to dir.
+func changedFilesSince(dir string, base string) ([]string, error) {
+	mergeBase, err := runGit(dir, "merge-base", base, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	diff, err := runGit(dir, "diff", "--name-only", "--relative", "-z", strings.TrimSpace(mergeBase), "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := runGit(dir, "ls-files", "--others", "--exclude-standard", "-z")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range strings.Split(diff+untracked, "\x00") {
+		if path != "" {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Create a git repository in a temporary directory, skipping the test when git
+// is not available
+func newGitRepo(t *testing.T) string {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git is not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	git(t, dir, "config", "user.email", "dev@example.com")
+	git(t, dir, "config", "user.name", "Dev")
+	git(t, dir, "config", "commit.gpgsign", "false")
+	return dir
+}
+
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	out, err := runGit(dir, args...)
+	require.NoError(t, err)
+	return out
+}
+
+// Write the files and commit them with the message
+func commitFiles(t *testing.T, dir string, message string, files map[string]string) {
+	t.Helper()
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", message)
+}
+
+func TestChangedFilesSince(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{"a/one.txt": "1", "b/two.txt": "2"})
+	base := git(t, dir, "rev-parse", "HEAD")[:40]
+
+	commitFiles(t, dir, "change", map[string]string{"a/one.txt": "changed"})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "b", "two.txt"), []byte("uncommitted"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "new file.txt"), []byte("untracked"), 0644))
+
+	files, err := changedFilesSince(dir, base)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/one.txt", "b/two.txt", "new file.txt"}, files)
+
+	_, err = changedFilesSince(dir, "no-such-ref")
+	assert.Error(t, err)
+
+	// Changes made on the base after the branch point don't count
+	git(t, dir, "checkout", "-q", "-b", "main-line", base)
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "c"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "c", "three.txt"), []byte("3"), 0644))
+	git(t, dir, "add", "c/three.txt")
+	git(t, dir, "commit", "-q", "-m", "on main", "--", "c/three.txt")
+	git(t, dir, "checkout", "-q", "-")
+	files, err = changedFilesSince(dir, "main-line")
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"a/one.txt", "b/two.txt", "new file.txt"}, files)
+}
@@ -3,20 +3,19 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
 	"github.com/weslien/grit/pkg/output"
-	"gopkg.in/yaml.v3"
 )
 
 var (
 	outputFormat string
 	outputFile   string
 	showTypes    bool
+	graphFilters []string
 )
 
 var graphCmd = &cobra.Command{
@@ -30,7 +29,10 @@ Examples:
   grit graph                    # Show dependency tree in terminal
   grit graph --format dot       # Output DOT format for Graphviz
   grit graph --output deps.dot  # Save DOT format to file
-  grit graph --types            # Include package types in output`,
+  grit graph --types            # Include package types in output
+  grit graph --filter tag:web   # Only packages tagged web and their dependencies
+
+Package owners are shown next to each package that has them.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		formatter := output.New()
 
@@ -50,31 +52,30 @@ Examples:
 			os.Exit(1)
 		}
 		formatter.Success(fmt.Sprintf("Loaded %d packages", len(packages)))
+		index := grit.NewIndex(packages)
 
-		// Build dependency map
+		selected := index.Packages()
+		if len(graphFilters) > 0 {
+			matched, err := filterPackages(index, graphFilters)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error selecting packages: %v", err))
+				os.Exit(1)
+			}
+			selected = withDependencies(index, matched)
+		}
+
+		// Build dependency map keyed by the shortest unambiguous package name
 		depMap := make(map[string][]string)
 		packageTypes := make(map[string]string)
 		packageVersions := make(map[string]string)
-
-		// Load root config for type information
-		rootConfig, err := loadRootConfigForGraph(cwd)
-		if err != nil {
-			formatter.Warning("Could not load root config, package types will not be shown")
-		}
-
-		for _, cfg := range packages {
-			if cfg.Package.Name == "" {
-				continue // Skip root config
-			}
-
-			depMap[cfg.Package.Name] = cfg.Package.Dependencies
-			packageVersions[cfg.Package.Name] = cfg.Package.Version
-
-			// Determine package type
-			if rootConfig != nil {
-				pkgType := getPackageType(cfg.Package.Path, rootConfig, cwd)
-				packageTypes[cfg.Package.Name] = pkgType
-			}
+		packageOwners := make(map[string][]string)
+
+		for _, cfg := range selected {
+			name := index.Name(cfg.Package)
+			depMap[name] = resolveDependencyNames(cfg.Package, index)
+			packageVersions[name] = cfg.Package.Version
+			packageTypes[name] = cfg.Package.Type
+			packageOwners[name] = cfg.Package.Owners
 		}
 
 		if len(depMap) == 0 {
@@ -84,13 +85,13 @@ Examples:
 
 		switch outputFormat {
 		case "dot":
-			err := generateDotGraph(depMap, packageTypes, packageVersions, outputFile, formatter)
+			err := generateDotGraph(depMap, packageTypes, packageVersions, packageOwners, outputFile, formatter)
 			if err != nil {
 				formatter.Error(fmt.Sprintf("Error generating DOT graph: %v", err))
 				os.Exit(1)
 			}
 		case "tree", "":
-			generateTreeGraph(depMap, packageTypes, packageVersions, formatter)
+			generateTreeGraph(depMap, packageTypes, packageVersions, packageOwners, formatter)
 		default:
 			formatter.Error(fmt.Sprintf("Unknown output format: %s", outputFormat))
 			os.Exit(1)
@@ -102,10 +103,11 @@ func init() {
 	graphCmd.Flags().StringVarP(&outputFormat, "format", "f", "tree", "Output format (tree, dot)")
 	graphCmd.Flags().StringVarP(&outputFile, "output", "o", "", "Output file (default: stdout)")
 	graphCmd.Flags().BoolVar(&showTypes, "types", false, "Show package types in output")
+	graphCmd.Flags().StringArrayVar(&graphFilters, "filter", nil, "Only show packages matching the filter expression and their dependencies (repeatable)")
 	rootCmd.AddCommand(graphCmd)
 }
 
-func generateTreeGraph(depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, formatter *output.Formatter) {
+func generateTreeGraph(depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, packageOwners map[string][]string, formatter *output.Formatter) {
 	formatter.Section("Package Dependencies")
 
 	// Sort packages for consistent output
@@ -140,7 +142,7 @@ func generateTreeGraph(depMap map[string][]string, packageTypes map[string]strin
 		if i > 0 {
 			formatter.NewLine()
 		}
-		displayPackageTree(root, depMap, packageTypes, packageVersions, formatter, "", make(map[string]bool))
+		displayPackageTree(root, depMap, packageTypes, packageVersions, packageOwners, formatter, "", make(map[string]bool))
 	}
 
 	// Show statistics
@@ -186,7 +188,7 @@ func generateTreeGraph(depMap map[string][]string, packageTypes map[string]strin
 	}
 }
 
-func displayPackageTree(pkg string, depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, formatter *output.Formatter, prefix string, visited map[string]bool) {
+func displayPackageTree(pkg string, depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, packageOwners map[string][]string, formatter *output.Formatter, prefix string, visited map[string]bool) {
 	if visited[pkg] {
 		fmt.Printf("%s├─ %s (circular reference)\n", prefix, pkg)
 		return
@@ -205,6 +207,9 @@ func displayPackageTree(pkg string, depMap map[string][]string, packageTypes map
 			pkgDisplay += fmt.Sprintf(" v%s", version)
 		}
 	}
+	if owners := packageOwners[pkg]; len(owners) > 0 {
+		pkgDisplay += fmt.Sprintf(" [%s]", strings.Join(owners, ", "))
+	}
 
 	fmt.Printf("%s├─ %s\n", prefix, pkgDisplay)
 
@@ -218,11 +223,11 @@ func displayPackageTree(pkg string, depMap map[string][]string, packageTypes map
 			newPrefix = prefix + "│  "
 		}
 		
-		displayPackageTree(dep, depMap, packageTypes, packageVersions, formatter, newPrefix, visited)
+		displayPackageTree(dep, depMap, packageTypes, packageVersions, packageOwners, formatter, newPrefix, visited)
 	}
 }
 
-func generateDotGraph(depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, outputFile string, formatter *output.Formatter) error {
+func generateDotGraph(depMap map[string][]string, packageTypes map[string]string, packageVersions map[string]string, packageOwners map[string][]string, outputFile string, formatter *output.Formatter) error {
 	var output strings.Builder
 	
 	output.WriteString("digraph dependencies {\n")
@@ -248,6 +253,9 @@ func generateDotGraph(depMap map[string][]string, packageTypes map[string]string
 				label += "\\n(" + pkgType + ")"
 			}
 		}
+		if owners := packageOwners[pkg]; len(owners) > 0 {
+			label += "\\n" + strings.Join(owners, ", ")
+		}
 
 		color := "lightgray"
 		if pkgType, ok := packageTypes[pkg]; ok {
@@ -287,33 +295,16 @@ func generateDotGraph(depMap map[string][]string, packageTypes map[string]string
 	return nil
 }
 
-func loadRootConfigForGraph(cwd string) (*grit.RootConfig, error) {
-	rootConfigPath := filepath.Join(cwd, "grit.yaml")
-	data, err := os.ReadFile(rootConfigPath)
-	if err != nil {
-		return nil, err
-	}
-
-	var rootConfig grit.RootConfig
-	err = yaml.Unmarshal(data, &rootConfig)
-	if err != nil {
-		return nil, err
-	}
-
-	return &rootConfig, nil
-}
-
-func getPackageType(packagePath string, rootConfig *grit.RootConfig, cwd string) string {
-	relPath, err := filepath.Rel(cwd, filepath.Dir(packagePath))
-	if err != nil {
-		return ""
-	}
-
-	for typeName, typeConfig := range rootConfig.Types {
-		if strings.Contains(relPath, typeConfig.PackageDir) {
-			return typeName
+// Map the dependencies of a package to the names used as graph keys. References
+// that don't resolve are kept as written so they still show up in the output.
+func resolveDependencyNames(pkg grit.Package, index *grit.Index) []string {
+	names := make([]string, 0, len(pkg.Dependencies))
+	for _, ref := range pkg.Dependencies {
+		if dep, err := index.Lookup(ref); err == nil {
+			names = append(names, index.Name(dep.Package))
+		} else {
+			names = append(names, ref)
 		}
 	}
-
-	return ""
+	return names
 }
\ No newline at end of file
@@ -0,0 +1,378 @@
+package cmd
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	hooksForce     bool
+	hooksWorkspace string
+)
+
+// The git hooks grit can install
+var gritHooks = []string{"pre-commit", "commit-msg"}
+
+// Line that marks a hook as written by grit
+const hookMarker = "# Installed by grit hooks install"
+
+var hooksCmd = &cobra.Command{
+	Use:   "hooks",
+	Short: "Manage the git hooks of the workspace",
+}
+
+var hooksInstallCmd = &cobra.Command{
+	Use:   "install [hook]...",
+	Short: "Install the pre-commit and commit-msg hooks",
+	Long: `Install git hooks that run grit on every commit.
+
+The pre-commit hook runs the targets listed under hooks.pre_commit in the root
+grit.yaml, lint by default, on the packages with staged changes. Packages that
+don't define a target are skipped. The commit-msg hook checks that the message
+names every package with staged changes, as the package prefix grit commit
+writes or as the scope of a conventional commit, and in conventional mode that
+it is a valid conventional commit.
+
+Hooks are written to core.hooksPath if it is set and to .git/hooks otherwise.
+Existing hooks that weren't installed by grit are only replaced with --force,
+which keeps them next to the new hook with a .bak suffix.
+
+Examples:
+  grit hooks install                # Install both hooks
+  grit hooks install pre-commit
+  grit hooks install --force        # Replace existing hooks`,
+	Args: validHookArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+		formatter.Header("GRIT Hooks")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+		dir, err := hooksDir(cwd)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the git hooks directory: %v", err))
+			os.Exit(1)
+		}
+		prefix, err := runGit(cwd, "rev-parse", "--show-prefix")
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the workspace in the repository: %v", err))
+			os.Exit(1)
+		}
+
+		script := hookScript(gritCommand(), strings.TrimSuffix(strings.TrimSpace(prefix), "/"))
+		for _, hook := range hookArgs(args) {
+			backup, err := installHook(dir, hook, script, hooksForce)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error installing %s hook: %v", hook, err))
+				os.Exit(1)
+			}
+			if backup != "" {
+				formatter.Warning(fmt.Sprintf("Moved the existing %s hook to %s", hook, backup))
+			}
+			formatter.Success(fmt.Sprintf("Installed %s hook in %s", hook, dir))
+		}
+	},
+}
+
+var hooksUninstallCmd = &cobra.Command{
+	Use:   "uninstall [hook]...",
+	Short: "Remove the hooks installed by grit",
+	Long: `Remove the git hooks installed by grit hooks install. Hooks that grit didn't
+write are left alone, and hooks that were replaced with --force are restored.`,
+	Args: validHookArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+		formatter.Header("GRIT Hooks")
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+		dir, err := hooksDir(cwd)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the git hooks directory: %v", err))
+			os.Exit(1)
+		}
+
+		for _, hook := range hookArgs(args) {
+			removed, err := uninstallHook(dir, hook)
+			switch {
+			case err != nil:
+				formatter.Error(fmt.Sprintf("Error removing %s hook: %v", hook, err))
+				os.Exit(1)
+			case removed:
+				formatter.Success(fmt.Sprintf("Removed %s hook", hook))
+			default:
+				formatter.Info(fmt.Sprintf("No %s hook installed by grit", hook))
+			}
+		}
+	},
+}
+
+var hooksRunCmd = &cobra.Command{
+	Use:    "run <hook> [args]...",
+	Short:  "Run a hook, called by the installed git hooks",
+	Hidden: true,
+	Args:   cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		// Git runs hooks in the root of the repository, which may be above
+		// the workspace. Paths given to the hook are relative to it.
+		hookArgs := args[1:]
+		for i, arg := range hookArgs {
+			if abs, err := filepath.Abs(arg); err == nil {
+				hookArgs[i] = abs
+			}
+		}
+		if hooksWorkspace != "" {
+			if err := os.Chdir(hooksWorkspace); err != nil {
+				formatter.Error(fmt.Sprintf("Error changing to the workspace: %v", err))
+				os.Exit(1)
+			}
+		}
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+
+		changes, err := workspaceStatus(cwd, index)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error checking git status: %v", err))
+			os.Exit(1)
+		}
+		changes = changes.filter(func(entry statusEntry) bool {
+			return entry.staged() && !entry.unmerged()
+		})
+
+		switch args[0] {
+		case "pre-commit":
+			err = runPreCommitHook(cwd, changes.packages, rootConfig, formatter)
+		case "commit-msg":
+			if len(hookArgs) == 0 {
+				err = errors.New("missing commit message file")
+				break
+			}
+			var message []byte
+			if message, err = os.ReadFile(hookArgs[0]); err == nil {
+				err = checkCommitMessage(string(message), changes.packages, index, rootConfig.Commit)
+			}
+		default:
+			err = fmt.Errorf("unknown hook %s, expected one of %s", args[0], strings.Join(gritHooks, ", "))
+		}
+		if err != nil {
+			formatter.Error(err.Error())
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	hooksInstallCmd.Flags().BoolVarP(&hooksForce, "force", "f", false, "Replace existing hooks, keeping them with a .bak suffix")
+	hooksRunCmd.Flags().StringVar(&hooksWorkspace, "workspace", "", "Workspace directory, relative to the repository root")
+	hooksCmd.AddCommand(hooksInstallCmd)
+	hooksCmd.AddCommand(hooksUninstallCmd)
+	hooksCmd.AddCommand(hooksRunCmd)
+	rootCmd.AddCommand(hooksCmd)
+}
+
+func validHookArgs(cmd *cobra.Command, args []string) error {
+	for _, arg := range args {
+		if !containsString(gritHooks, arg) {
+			return fmt.Errorf("unknown hook %s, expected one of %s", arg, strings.Join(gritHooks, ", "))
+		}
+	}
+	return nil
+}
+
+// The hooks named on the command line, all of them by default
+func hookArgs(args []string) []string {
+	if len(args) == 0 {
+		return gritHooks
+	}
+	return args
+}
+
+// The directory git runs hooks from: core.hooksPath, relative to the root of
+// the repository, or the hooks directory of the repository
+func hooksDir(cwd string) (string, error) {
+	if out, err := runGit(cwd, "config", "core.hooksPath"); err == nil && strings.TrimSpace(out) != "" {
+		dir := strings.TrimSpace(out)
+		if strings.HasPrefix(dir, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(home, dir[2:])
+		}
+		if !filepath.IsAbs(dir) {
+			root, err := runGit(cwd, "rev-parse", "--show-toplevel")
+			if err != nil {
+				return "", err
+			}
+			dir = filepath.Join(strings.TrimSpace(root), dir)
+		}
+		return dir, nil
+	}
+
+	gitDir, err := runGit(cwd, "rev-parse", "--git-common-dir")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(gitDir)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(cwd, dir)
+	}
+	return filepath.Join(dir, "hooks"), nil
+}
+
+// The command hooks run grit with: grit itself when it is on the PATH, otherwise
+// the path of the running executable
+func gritCommand() string {
+	if _, err := exec.LookPath("grit"); err == nil {
+		return "grit"
+	}
+	if exe, err := os.Executable(); err == nil {
+		return shellQuote(exe)
+	}
+	return "grit"
+}
+
+// The script of a hook. workspace is the path of the workspace in the
+// repository, empty when it is the root.
+func hookScript(grit string, workspace string) string {
+	run := grit + " hooks run"
+	if workspace != "" {
+		run += " --workspace " + shellQuote(workspace)
+	}
+	return fmt.Sprintf("#!/bin/sh\n%s, remove with grit hooks uninstall\nexec %s \"$(basename \"$0\")\" \"$@\"\n", hookMarker, run)
+}
+
+// Quote a string for sh
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func isGritHook(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && bytes.Contains(data, []byte(hookMarker))
+}
+
+// Write a hook. An existing hook that grit didn't write is only replaced with
+// force and then moved to a .bak file, whose path is returned.
+func installHook(dir string, hook string, script string, force bool) (backup string, err error) {
+	path := filepath.Join(dir, hook)
+	if _, err := os.Lstat(path); err == nil && !isGritHook(path) {
+		if !force {
+			return "", fmt.Errorf("%s already exists, use --force to replace it", path)
+		}
+		backup = path + ".bak"
+		if err := os.Rename(path, backup); err != nil {
+			return "", err
+		}
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return backup, err
+	}
+	return backup, os.WriteFile(path, []byte(script), 0755)
+}
+
+// Remove a hook written by grit and restore the hook it replaced, if any
+func uninstallHook(dir string, hook string) (bool, error) {
+	path := filepath.Join(dir, hook)
+	if !isGritHook(path) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	if _, err := os.Lstat(path + ".bak"); err == nil {
+		return true, os.Rename(path+".bak", path)
+	}
+	return true, nil
+}
+
+// Run the configured targets on the packages with staged changes. Packages that
+// don't define a target are skipped.
+func runPreCommitHook(cwd string, packages []grit.Config, rootConfig *grit.RootConfig, formatter *output.Formatter) error {
+	failed := 0
+	for _, cfg := range packages {
+		for _, target := range rootConfig.Hooks.PreCommitTargets() {
+			err := runPackageTarget(cfg, rootConfig, target, cwd, formatter)
+			if errors.Is(err, errTargetNotDefined) {
+				continue
+			}
+			if err != nil {
+				formatter.Error(fmt.Sprintf("%s: %v", cfg.Package.ID(), err))
+				failed++
+			}
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("pre-commit: %d checks failed", failed)
+	}
+	return nil
+}
+
+// Messages git writes itself, which aren't checked
+var generatedMessagePrefixes = []string{"Merge ", "Revert \"", "fixup! ", "squash! ", "amend! "}
+
+// Check that a commit message names each of the packages with staged changes,
+// and in conventional mode that it is a conventional commit
+func checkCommitMessage(message string, packages []grit.Config, index *grit.Index, commit grit.CommitConfig) error {
+	// Drop the comments git adds to the message
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		if !strings.HasPrefix(line, "#") {
+			lines = append(lines, line)
+		}
+	}
+	message = strings.TrimSpace(strings.Join(lines, "\n"))
+	if message == "" {
+		return nil
+	}
+	for _, prefix := range generatedMessagePrefixes {
+		if strings.HasPrefix(message, prefix) {
+			return nil
+		}
+	}
+
+	named := make(map[string]bool)
+	for _, ref := range commit.References(message) {
+		if cfg, err := index.Lookup(ref); err == nil {
+			named[cfg.Package.ID()] = true
+		}
+	}
+	var missing []string
+	for _, cfg := range packages {
+		if !named[cfg.Package.ID()] {
+			missing = append(missing, index.Name(cfg.Package))
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: the message doesn't name the changed packages %s as its prefix or scope, grit commit commits each package separately",
+			grit.ErrInvalidCommitMessage, strings.Join(missing, ", "))
+	}
+
+	if commit.Conventional {
+		// Validate the message without the package prefix
+		if parsed, ok := commit.ParseCommitMessage(message); ok && parsed.Package != "" {
+			if _, err := index.Lookup(parsed.Package); err == nil {
+				_, message, _ = strings.Cut(message, ": ")
+			}
+		}
+		return commit.ValidateCommitMessage(message)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestInstallHook(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "hooks")
+	script := hookScript("grit", "tools/it's")
+	assert.Equal(t, "#!/bin/sh\n"+hookMarker+", remove with grit hooks uninstall\nexec grit hooks run --workspace 'tools/it'\\''s' \"$(basename \"$0\")\" \"$@\"\n", script)
+
+	_, err := installHook(dir, "pre-commit", script, false)
+	require.NoError(t, err)
+	info, err := os.Stat(filepath.Join(dir, "pre-commit"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+
+	// Reinstalling replaces the grit hook
+	backup, err := installHook(dir, "pre-commit", script, false)
+	require.NoError(t, err)
+	assert.Empty(t, backup)
+
+	// Other hooks are only replaced with force, and restored on uninstall
+	own := filepath.Join(dir, "commit-msg")
+	require.NoError(t, os.WriteFile(own, []byte("#!/bin/sh\nexit 0\n"), 0755))
+	_, err = installHook(dir, "commit-msg", script, false)
+	assert.Error(t, err)
+	backup, err = installHook(dir, "commit-msg", script, true)
+	require.NoError(t, err)
+	assert.Equal(t, own+".bak", backup)
+	assert.True(t, isGritHook(own))
+
+	removed, err := uninstallHook(dir, "commit-msg")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	data, err := os.ReadFile(own)
+	require.NoError(t, err)
+	assert.Equal(t, "#!/bin/sh\nexit 0\n", string(data))
+	removed, err = uninstallHook(dir, "commit-msg")
+	require.NoError(t, err)
+	assert.False(t, removed, "hooks not written by grit are kept")
+
+	removed, err = uninstallHook(dir, "pre-commit")
+	require.NoError(t, err)
+	assert.True(t, removed)
+	assert.NoFileExists(t, filepath.Join(dir, "pre-commit"))
+}
+
+func TestHooksDir(t *testing.T) {
+	dir := newGitRepo(t)
+	hooks, err := hooksDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".git", "hooks"), hooks)
+
+	git(t, dir, "config", "core.hooksPath", ".githooks")
+	hooks, err = hooksDir(dir)
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, ".githooks"), hooks)
+}
+
+func TestCheckCommitMessage(t *testing.T) {
+	utils := grit.Config{Package: grit.Package{Name: "utils", Type: "lib"}}
+	web := grit.Config{Package: grit.Package{Name: "web", Type: "app"}}
+	index := grit.NewIndex([]grit.Config{utils, web})
+	config := grit.CommitConfig{}
+
+	for _, message := range []string{
+		"utils: add retries",
+		"lib/utils: add retries\n\n# Please enter the commit message",
+		"feat(utils,web): share config",
+		"Merge branch 'main'",
+		"fixup! something",
+		"# Only comments\n",
+	} {
+		assert.NoError(t, checkCommitMessage(message, []grit.Config{utils}, index, config), message)
+	}
+	assert.NoError(t, checkCommitMessage("update readme", nil, index, config))
+
+	for _, message := range []string{"add retries", "web: add retries", "feat(io): close files"} {
+		assert.ErrorIs(t, checkCommitMessage(message, []grit.Config{utils}, index, config), grit.ErrInvalidCommitMessage, message)
+	}
+	assert.ErrorIs(t, checkCommitMessage("utils: add retries", []grit.Config{utils, web}, index, config), grit.ErrInvalidCommitMessage)
+
+	// Conventional mode checks the message without the package prefix
+	config.Conventional = true
+	assert.NoError(t, checkCommitMessage("utils: feat: add retries", []grit.Config{utils}, index, config))
+	assert.NoError(t, checkCommitMessage("feat(utils): add retries", []grit.Config{utils}, index, config))
+	assert.ErrorIs(t, checkCommitMessage("utils: add retries", []grit.Config{utils}, index, config), grit.ErrInvalidCommitMessage)
+}
@@ -2,22 +2,52 @@ package cmd
 
 import (
 	"fmt"
+	"io"
+	"net/url"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
 	"github.com/weslien/grit/pkg/output"
-	"gopkg.in/yaml.v3"
+)
+
+var (
+	importWithHistory bool
+	importGitRef      string
+	importSubdir      string
 )
 
 var importCmd = &cobra.Command{
 	Use:   "import [source] [type] [name]",
-	Short: "Import code from a GitHub repo or local path",
-	Long:  `Create a new package by importing code from a GitHub repository or local path.`,
-	Args:  cobra.ExactArgs(3),
+	Short: "Import code from a git repository, archive or local path",
+	Long: `Create a new package by importing code from a git repository, a tar or zip
+archive or a local path.
+
+A git repository is given by any URL git can fetch, like https://, ssh://,
+file:// or git@host:org/repo, or by the path of a bare repository. A local
+directory is copied with its uncommitted changes, unless --ref is given. Archives
+are read from a path or an http(s) URL, leaving out the directory holding all
+their files, as in the archives GitHub creates.
+
+--ref imports a branch, tag or commit instead of the default branch, and
+--subdir only imports a directory of the source.
+
+With --history the source, any git repository URL or path, is imported with its
+history. Each of its commits is rewritten to hold its files in the package
+directory and the result is merged into the current branch, so git log and git
+blame work for the imported code. The package's grit.yaml is part of the merge
+commit, and nothing is changed when the import fails.
+
+A source with a grit.yaml of its own is refused rather than overwritten.
+
+Examples:
+  grit import https://github.com/org/utils lib utils
+  grit import git@github.com:org/tools.git lib utils --ref v1.2.0 --subdir utils
+  grit import ./utils-1.0.tar.gz lib utils
+  grit import ../utils lib utils --history`,
+	Args: cobra.ExactArgs(3),
 	Run: func(cmd *cobra.Command, args []string) {
 		formatter := output.New()
 		formatter.Section("Grit Import")
@@ -34,15 +64,8 @@ var importCmd = &cobra.Command{
 		}
 
 		// Load root config
-		rootConfigPath := filepath.Join(cwd, "grit.yaml")
-		rootConfigData, err := os.ReadFile(rootConfigPath)
+		rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
 		if err != nil {
-			formatter.Error(fmt.Sprintf("Failed to read root config: %v", err))
-			os.Exit(1)
-		}
-
-		var rootConfig grit.RootConfig
-		if err := yaml.Unmarshal(rootConfigData, &rootConfig); err != nil {
 			formatter.Error(fmt.Sprintf("Invalid root config: %v", err))
 			os.Exit(1)
 		}
@@ -63,169 +86,291 @@ var importCmd = &cobra.Command{
 			os.Exit(1)
 		}
 
-		// Create the package directory
-		if err := os.MkdirAll(pkgDir, 0755); err != nil {
-			formatter.Error(fmt.Sprintf("Failed to create package directory: %v", err))
+		kind := sourceKind(source, importGitRef)
+		if importGitRef != "" && kind != sourceGit {
+			formatter.Error("--ref requires a git repository")
 			os.Exit(1)
 		}
-
-		// Import the source
-		if strings.HasPrefix(source, "https://github.com/") || strings.HasPrefix(source, "git@github.com:") {
-			importFromGitHub(source, pkgDir, formatter)
-		} else {
-			importFromLocalPath(source, pkgDir, formatter)
+		if importWithHistory {
+			if kind == sourceArchive {
+				formatter.Error("Archives have no history to import, --history requires a git repository")
+				os.Exit(1)
+			}
+			if err := importPackageHistory(cwd, source, pkgDir, pkgName, pkgType, rootConfig, formatter); err != nil {
+				formatter.Error(fmt.Sprintf("Failed to import '%s': %v", source, err))
+				os.Exit(1)
+			}
+			formatter.Success(fmt.Sprintf("Successfully imported '%s' with its history as package '%s' of type '%s'", source, pkgName, pkgType))
+			return
 		}
 
-		// Create the package config file
-		createPackageConfig(pkgDir, pkgName, pkgType, formatter)
-
+		if err := importPackage(source, kind, pkgDir, pkgName, pkgType, formatter); err != nil {
+			formatter.Error(fmt.Sprintf("Failed to import '%s': %v", source, err))
+			os.Exit(1)
+		}
 		formatter.Success(fmt.Sprintf("Successfully imported '%s' as package '%s' of type '%s'", source, pkgName, pkgType))
 	},
 }
 
 func init() {
+	importCmd.Flags().BoolVar(&importWithHistory, "history", false, "Import the history of the source git repository")
+	importCmd.Flags().StringVar(&importGitRef, "ref", "", "Branch, tag or commit of the source git repository to import")
+	importCmd.Flags().StringVar(&importSubdir, "subdir", "", "Only import this directory of the source")
 	rootCmd.AddCommand(importCmd)
 }
 
-// Import code from a GitHub repository
-func importFromGitHub(repo string, pkgDir string, formatter *output.Formatter) {
-	formatter.Info(fmt.Sprintf("Cloning from GitHub: %s", repo))
+// Import a git repository with its history into the package directory, adding
+// the package config in the merge commit
+func importPackageHistory(cwd string, source string, pkgDir string, pkgName string, pkgType string, rootConfig *grit.RootConfig, formatter *output.Formatter) error {
+	formatter.Info(fmt.Sprintf("Importing the history of %s", source))
 
-	// Create a temporary directory for the clone
-	tempDir, err := os.MkdirTemp("", "grit-import-*")
+	root, err := runGit(cwd, "rev-parse", "--show-toplevel")
 	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to create temporary directory: %v", err))
-		os.Exit(1)
+		return fmt.Errorf("importing history requires a git repository: %w", err)
 	}
-	defer os.RemoveAll(tempDir)
+	root = strings.TrimSpace(root)
+	relDir, err := filepath.Rel(root, pkgDir)
+	if err != nil {
+		return fmt.Errorf("failed to find the package directory in the repository: %w", err)
+	}
+	relDir = filepath.ToSlash(relDir)
+
+	config, err := packageConfigData(pkgName)
+	if err != nil {
+		return err
+	}
+	// HEAD is moved once, to a merge commit that includes the package config,
+	// so the repository is unchanged when the import fails
+	message := importCommitMessage(rootConfig, pkgName, fmt.Sprintf("import %s with its history", source))
+	if err := importHistory(root, source, importGitRef, importSubdir, relDir, message, config); err != nil {
+		return fmt.Errorf("failed to import history: %w", err)
+	}
+	formatter.Success(fmt.Sprintf("Created package config at %s", filepath.Join(pkgDir, "grit.yaml")))
+	return nil
+}
 
-	// Clone the repository
-	cmd := exec.Command("git", "clone", "--depth=1", repo, tempDir)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	if err := cmd.Run(); err != nil {
-		formatter.Error(fmt.Sprintf("Failed to clone repository: %v", err))
-		os.Exit(1)
+// Import a source as the package at pkgDir. The package is assembled in a
+// temporary directory next to pkgDir and renamed into place once complete, so
+// a failed import leaves nothing behind.
+func importPackage(source string, kind importSourceKind, pkgDir string, pkgName string, pkgType string, formatter *output.Formatter) error {
+	created, err := mkdirAllCreated(filepath.Dir(pkgDir))
+	if err != nil {
+		return fmt.Errorf("failed to create package directory: %w", err)
+	}
+	stageDir, err := os.MkdirTemp(filepath.Dir(pkgDir), ".grit-import-*")
+	if err != nil {
+		if created != "" {
+			os.RemoveAll(created)
+		}
+		return fmt.Errorf("failed to create temporary directory: %w", err)
 	}
 
-	// Remove .git directory
-	os.RemoveAll(filepath.Join(tempDir, ".git"))
+	err = func() error {
+		var skipped []string
+		var err error
+		switch kind {
+		case sourceGit:
+			formatter.Info(fmt.Sprintf("Fetching from git: %s", source))
+			skipped, err = exportGitTree(source, importGitRef, importSubdir, stageDir)
+		case sourceArchive:
+			formatter.Info(fmt.Sprintf("Extracting archive: %s", source))
+			skipped, err = importFromArchive(source, importSubdir, stageDir)
+		default:
+			var subdir string
+			if subdir, err = cleanSubdir(importSubdir); err != nil {
+				return err
+			}
+			path := filepath.Join(source, filepath.FromSlash(subdir))
+			formatter.Info(fmt.Sprintf("Importing from local path: %s", path))
+			skipped, err = importFromLocalPath(path, stageDir)
+		}
+		if err != nil {
+			return err
+		}
+		for _, path := range skipped {
+			formatter.Warning(fmt.Sprintf("Skipped %s, which is not a regular file, directory or symlink", path))
+		}
 
-	// Copy files from temp dir to package dir
-	copyDir(tempDir, pkgDir, formatter)
+		if err := createPackageConfig(stageDir, pkgName, pkgType); err != nil {
+			return err
+		}
+		// MkdirTemp creates the directory accessible to the owner only
+		if err := os.Chmod(stageDir, 0755); err != nil {
+			return err
+		}
+		if err := os.Rename(stageDir, pkgDir); err != nil {
+			return err
+		}
+		formatter.Success(fmt.Sprintf("Created package config at %s", filepath.Join(pkgDir, "grit.yaml")))
+		return nil
+	}()
+	if err != nil {
+		os.RemoveAll(stageDir)
+		if created != "" {
+			os.RemoveAll(created)
+		}
+	}
+	return err
 }
 
-// Import code from a local path
-func importFromLocalPath(path string, pkgDir string, formatter *output.Formatter) {
-	formatter.Info(fmt.Sprintf("Importing from local path: %s", path))
-
-	// Check if the source path exists
-	if _, err := os.Stat(path); os.IsNotExist(err) {
-		formatter.Error(fmt.Sprintf("Source path '%s' does not exist", path))
-		os.Exit(1)
+// Create dir and its missing parents like os.MkdirAll. Returns the outermost
+// directory created, or an empty string when dir already existed.
+func mkdirAllCreated(dir string) (string, error) {
+	var created string
+	for parent := dir; ; parent = filepath.Dir(parent) {
+		if _, err := os.Lstat(parent); err == nil || filepath.Dir(parent) == parent {
+			break
+		}
+		created = parent
 	}
+	return created, os.MkdirAll(dir, 0755)
+}
 
-	// Copy files from source path to package dir
-	copyDir(path, pkgDir, formatter)
+// Import code from a tar or zip archive, downloading it first if it's a URL
+func importFromArchive(source string, subdir string, dst string) ([]string, error) {
+	archive := source
+	if u, err := url.Parse(source); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		tempDir, err := os.MkdirTemp("", "grit-import-*")
+		if err != nil {
+			return nil, fmt.Errorf("failed to create temporary directory: %w", err)
+		}
+		defer os.RemoveAll(tempDir)
+		if archive, err = downloadArchive(source, tempDir); err != nil {
+			return nil, fmt.Errorf("failed to download archive: %w", err)
+		}
+	} else if err == nil && u.Scheme == "file" {
+		archive = u.Path
+	}
+	return extractArchive(archive, archiveFormat(source), subdir, dst, true)
 }
 
-// Copy directory contents recursively
-func copyDir(src string, dst string, formatter *output.Formatter) {
-	// Get file info
-	info, err := os.Stat(src)
+// Import code from a local directory, or a single file, into dst
+func importFromLocalPath(path string, dst string) ([]string, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("source path '%s' does not exist", path)
+	}
 	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to get source info: %v", err))
-		os.Exit(1)
+		return nil, err
 	}
-
-	// If source is a file, just copy it
 	if !info.IsDir() {
-		copyFile(src, dst, formatter)
-		return
-	}
-
-	// Create destination directory
-	if err := os.MkdirAll(dst, info.Mode()); err != nil {
-		formatter.Error(fmt.Sprintf("Failed to create destination directory: %v", err))
-		os.Exit(1)
+		return nil, copyFile(path, filepath.Join(dst, filepath.Base(path)), info.Mode())
 	}
+	return copyDir(path, dst)
+}
 
-	// Read directory contents
+// Copy the contents of directory src into dst recursively, keeping file modes
+// and symlinks and leaving out .git. Returns the entries that were skipped,
+// like sockets and devices.
+func copyDir(src string, dst string) ([]string, error) {
 	entries, err := os.ReadDir(src)
 	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to read source directory: %v", err))
-		os.Exit(1)
+		return nil, err
 	}
 
-	// Copy each entry
+	var skipped []string
 	for _, entry := range entries {
-		srcPath := filepath.Join(src, entry.Name())
-		dstPath := filepath.Join(dst, entry.Name())
-
-		// Skip .git directories
 		if entry.Name() == ".git" {
 			continue
 		}
+		srcPath := filepath.Join(src, entry.Name())
+		dstPath := filepath.Join(dst, entry.Name())
 
-		if entry.IsDir() {
-			// Recursively copy subdirectory
-			copyDir(srcPath, dstPath, formatter)
-		} else {
-			// Copy file
-			copyFile(srcPath, dstPath, formatter)
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+		switch mode := info.Mode(); {
+		case mode.IsDir():
+			if err := os.Mkdir(dstPath, mode.Perm()|0700); err != nil {
+				return nil, err
+			}
+			nested, err := copyDir(srcPath, dstPath)
+			if err != nil {
+				return nil, err
+			}
+			for _, path := range nested {
+				skipped = append(skipped, filepath.Join(entry.Name(), path))
+			}
+		case mode&os.ModeSymlink != 0:
+			target, err := os.Readlink(srcPath)
+			if err != nil {
+				return nil, err
+			}
+			if err := os.Symlink(target, dstPath); err != nil {
+				return nil, err
+			}
+		case mode.IsRegular():
+			if err := copyFile(srcPath, dstPath, mode); err != nil {
+				return nil, err
+			}
+		default:
+			skipped = append(skipped, entry.Name())
 		}
 	}
+	return skipped, nil
 }
 
-// Copy a single file
-func copyFile(src string, dst string, formatter *output.Formatter) {
-	// Read source file
-	data, err := os.ReadFile(src)
+// Copy a single file, keeping its permissions
+func copyFile(src string, dst string, mode os.FileMode) error {
+	in, err := os.Open(src)
 	if err != nil {
-		formatter.Warning(fmt.Sprintf("Failed to read source file %s: %v", src, err))
-		return
+		return err
 	}
+	defer in.Close()
 
-	// Write to destination file
-	if err := os.WriteFile(dst, data, 0644); err != nil {
-		formatter.Warning(fmt.Sprintf("Failed to write destination file %s: %v", dst, err))
-		return
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
 	}
+	return out.Close()
 }
 
-// Create the package config file (grit.yaml)
-func createPackageConfig(pkgDir string, pkgName string, pkgType string, formatter *output.Formatter) {
-	formatter.Info("Creating package configuration")
+// Create the package config file (grit.yaml). A grit.yaml imported with the
+// package is not overwritten.
+func createPackageConfig(pkgDir string, pkgName string, pkgType string) error {
+	data, err := packageConfigData(pkgName)
+	if err != nil {
+		return err
+	}
+
+	// Write to file
+	configPath := filepath.Join(pkgDir, "grit.yaml")
+	file, err := os.OpenFile(configPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if os.IsExist(err) {
+		return fmt.Errorf("the source has a grit.yaml of its own")
+	}
+	if err != nil {
+		return fmt.Errorf("failed to write package config: %w", err)
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to write package config: %w", err)
+	}
+	return file.Close()
+}
 
-	// Create a basic package config
+// The content of the config file of a new package
+func packageConfigData(pkgName string) ([]byte, error) {
+	// Create a basic package config, targets are inherited from the type and root config
 	config := grit.Config{
-		Targets: map[string]string{
-			"build": "echo 'Implement build logic'",
-			"test":  "echo 'Implement test logic'",
-		},
-		Types: map[string]grit.TypeConfig{},
+		Version: grit.SchemaVersion,
+		Targets: map[string]string{},
 		Package: grit.Package{
 			Name:         pkgName,
 			Version:      "0.1.0",
 			Dependencies: []string{},
-			Hash:         "",
-			Path:         "",
 		},
 	}
 
 	// Marshal to YAML
-	data, err := yaml.Marshal(config)
+	data, err := grit.EncodeConfig(config)
 	if err != nil {
-		formatter.Error(fmt.Sprintf("Failed to create package config: %v", err))
-		os.Exit(1)
-	}
-
-	// Write to file
-	configPath := filepath.Join(pkgDir, "grit.yaml")
-	if err := os.WriteFile(configPath, data, 0644); err != nil {
-		formatter.Error(fmt.Sprintf("Failed to write package config: %v", err))
-		os.Exit(1)
+		return nil, fmt.Errorf("failed to create package config: %w", err)
 	}
-
-	formatter.Success(fmt.Sprintf("Created package config at %s", configPath))
+	return data, nil
 }
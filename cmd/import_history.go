@@ -0,0 +1,174 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+// Temporary ref the history of an imported repository is fetched to
+const importRef = "refs/grit/import"
+
+// Import ref of a git repository, HEAD when empty, with its history into dir,
+// relative to the root of the repository. Every commit of the source is
+// rewritten to hold its files below dir, and the rewritten history is merged
+// into HEAD with a merge commit, so git log and git blame follow the imported
+// files back to their origin. With subdir only that directory of the source is
+// imported, with the history of the commits that changed it. config, unless
+// nil, is added as the grit.yaml of dir in the merge commit. Changes staged in
+// the index are left alone.
+func importHistory(root string, source string, ref string, subdir string, dir string, message string, config []byte) error {
+	subdir, err := cleanSubdir(subdir)
+	if err != nil {
+		return err
+	}
+	if err := fetchRef(root, source, ref, importRef, false); err != nil {
+		return err
+	}
+	defer runGit(root, "update-ref", "-d", importRef)
+
+	rev := importRef
+	if subdir != "." {
+		tip, _, err := splitHistory(root, importRef, subdir)
+		if err != nil {
+			return err
+		}
+		rev = tip
+	}
+
+	tip, err := rewriteHistoryInto(root, rev, dir)
+	if err != nil {
+		return err
+	}
+	return mergeImportedHistory(root, dir, tip, message, config)
+}
+
+// Rewrite the history of rev so every commit holds its files below dir, with
+// the same authors, dates and messages. Returns the new tip.
+func rewriteHistoryInto(root string, rev string, dir string) (string, error) {
+	out, err := runGit(root, "rev-list", "--reverse", "--topo-order", "--parents", rev)
+	if err != nil {
+		return "", err
+	}
+
+	rewritten := make(map[string]string)
+	var tip string
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		tree, err := runGit(root, "rev-parse", fields[0]+"^{tree}")
+		if err != nil {
+			return "", err
+		}
+		nested, err := nestTree(root, strings.TrimSpace(tree), dir)
+		if err != nil {
+			return "", err
+		}
+
+		var parents []string
+		for _, parent := range fields[1:] {
+			parents = append(parents, rewritten[parent])
+		}
+		if tip, err = rewriteCommit(root, fields[0], nested, parents); err != nil {
+			return "", err
+		}
+		rewritten[fields[0]] = tip
+	}
+	if tip == "" {
+		return "", fmt.Errorf("%s has no commits", rev)
+	}
+	return tip, nil
+}
+
+// Write the trees that hold tree at dir and return the outermost one
+func nestTree(root string, tree string, dir string) (string, error) {
+	parts := strings.Split(path.Clean(dir), "/")
+	for i := len(parts) - 1; i >= 0; i-- {
+		out, err := runGitEnv(root, nil, fmt.Sprintf("040000 tree %s\t%s\x00", tree, parts[i]), "mktree", "-z")
+		if err != nil {
+			return "", err
+		}
+		tree = strings.TrimSpace(out)
+	}
+	return tree, nil
+}
+
+// Merge the imported history into HEAD. The merge commit is built from HEAD,
+// the imported directory and its grit.yaml in a temporary index, then HEAD is
+// moved to it and dir is checked out. HEAD is only moved once, and moved back
+// when the checkout fails, so a failed import leaves the repository unchanged.
+func mergeImportedHistory(root string, dir string, tip string, message string, config []byte) error {
+	env, cleanup, err := tempIndex(root)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// read-tree refuses to overwrite entries that exist below dir already
+	if _, err := runGitEnv(root, env, "", "read-tree", "--prefix="+dir+"/", tip+":"+dir); err != nil {
+		return err
+	}
+	if config != nil {
+		configPath := dir + "/grit.yaml"
+		if _, err := runGit(root, "--literal-pathspecs", "cat-file", "-e", tip+":"+configPath); err == nil {
+			return fmt.Errorf("the source has a grit.yaml of its own")
+		}
+		blob, err := runGitEnv(root, nil, string(config), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return err
+		}
+		if _, err := runGitEnv(root, env, "", "update-index", "--add", "--cacheinfo", "100644,"+strings.TrimSpace(blob)+","+configPath); err != nil {
+			return err
+		}
+	}
+	tree, err := runGitEnv(root, env, "", "write-tree")
+	if err != nil {
+		return err
+	}
+
+	args := []string{"commit-tree", strings.TrimSpace(tree)}
+	head, headErr := runGit(root, "rev-parse", "--verify", "-q", "HEAD")
+	head = strings.TrimSpace(head)
+	if headErr == nil {
+		args = append(args, "-p", head)
+	}
+	commit, err := runGitEnv(root, nil, message, append(args, "-p", tip)...)
+	if err != nil {
+		return err
+	}
+	commit = strings.TrimSpace(commit)
+
+	update := []string{"update-ref", "-m", "grit import", "HEAD", commit}
+	if headErr == nil {
+		update = append(update, head)
+	}
+	if _, err := runGit(root, update...); err != nil {
+		return err
+	}
+	if _, err := runGit(root, "--literal-pathspecs", "checkout", "HEAD", "--", dir); err != nil {
+		if headErr == nil {
+			runGit(root, "update-ref", "-m", "grit import: roll back", "HEAD", head, commit)
+		} else {
+			runGit(root, "update-ref", "-d", "HEAD", commit)
+		}
+		// The directory didn't exist before the import
+		os.RemoveAll(filepath.Join(root, filepath.FromSlash(dir)))
+		return err
+	}
+	return nil
+}
+
+// The message of a commit made by grit import, in the style grit commit writes
+func importCommitMessage(rootConfig *grit.RootConfig, pkgName string, message string) string {
+	opts := commitOptions{conventional: rootConfig.Commit.Conventional, commitType: "chore", config: rootConfig.Commit}
+	if composed, err := composeCommitMessage(message, pkgName, "chore", opts, false, nil, nil); err == nil {
+		return composed
+	}
+	return fmt.Sprintf("%s: %s", pkgName, message)
+}
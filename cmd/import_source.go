@@ -0,0 +1,435 @@
+package cmd
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where grit import reads the files of a package from
+type importSourceKind int
+
+const (
+	sourceDir     importSourceKind = iota // A local directory or file, copied as it is
+	sourceGit                             // A git repository, by URL or path
+	sourceArchive                         // A tar or zip archive, by URL or path
+)
+
+// Namespace the branches and tags of a source are fetched to when a ref can't
+// be fetched by name
+const importAllRefs = "refs/grit/import-all/"
+
+var abbreviatedCommit = regexp.MustCompile(`^[0-9a-fA-F]{4,40}$`)
+
+// The kind of an import source. A local directory is copied with its
+// uncommitted changes, unless it is a bare repository or a ref is given.
+func sourceKind(source string, ref string) importSourceKind {
+	if archiveFormat(source) != "" {
+		return sourceArchive
+	}
+	if isGitURL(source) {
+		return sourceGit
+	}
+	if info, err := os.Stat(source); err == nil && info.IsDir() {
+		if ref != "" {
+			return sourceGit
+		}
+		if bare, err := runGit(source, "rev-parse", "--is-bare-repository"); err == nil && strings.TrimSpace(bare) == "true" {
+			return sourceGit
+		}
+	}
+	return sourceDir
+}
+
+// Whether source is a URL, like https://host/repo.git or file:///srv/repo, or an
+// scp-like address, like git@github.com:org/repo.git
+func isGitURL(source string) bool {
+	if strings.Contains(source, "://") {
+		return true
+	}
+	// A single letter before the colon is a Windows drive
+	host, _, found := strings.Cut(source, ":")
+	return found && len(host) > 1 && !strings.ContainsAny(host, `/\`)
+}
+
+// The format of an archive by its file name or URL: "zip", "tgz", "tar" or ""
+// when source isn't an archive
+func archiveFormat(source string) string {
+	name := source
+	if u, err := url.Parse(source); err == nil && u.Scheme != "" && strings.Contains(source, "://") {
+		name = u.Path
+	}
+	name = strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(name, ".zip"):
+		return "zip"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "tgz"
+	case strings.HasSuffix(name, ".tar"):
+		return "tar"
+	}
+	return ""
+}
+
+// The source as git fetches it. A relative path is relative to the current
+// directory rather than the repository git runs in.
+func gitSource(source string) string {
+	if _, err := os.Stat(source); err == nil {
+		if abs, err := filepath.Abs(source); err == nil {
+			return abs
+		}
+	}
+	return source
+}
+
+// Fetch ref of source, HEAD when empty, to the ref dst of the repository at
+// repo. An abbreviated commit, which git can't fetch by name, is looked up in
+// the branches and tags of the source.
+func fetchRef(repo string, source string, ref string, dst string, shallow bool) error {
+	source = gitSource(source)
+	if ref == "" {
+		ref = "HEAD"
+	}
+	args := []string{"fetch", "-q", "--no-tags"}
+	if shallow {
+		args = append(args, "--depth=1")
+	}
+	_, fetchErr := runGit(repo, append(args, source, "+"+ref+":"+dst)...)
+	if fetchErr == nil || !abbreviatedCommit.MatchString(ref) {
+		return fetchErr
+	}
+
+	if _, err := runGit(repo, "fetch", "-q", "--no-tags", source, "+refs/heads/*:"+importAllRefs+"heads/*", "+refs/tags/*:"+importAllRefs+"tags/*"); err != nil {
+		return err
+	}
+	defer deleteRefs(repo, importAllRefs)
+	commit, err := runGit(repo, "rev-parse", "--verify", "-q", ref+"^{commit}")
+	if err != nil {
+		return fetchErr
+	}
+	commit = strings.TrimSpace(commit)
+	// The commit must come from the source, not the repository fetched into
+	if contains, err := runGit(repo, "for-each-ref", "--count=1", "--contains", commit, importAllRefs); err != nil || contains == "" {
+		return fetchErr
+	}
+	_, err = runGit(repo, "update-ref", dst, commit)
+	return err
+}
+
+// Delete the refs below prefix
+func deleteRefs(repo string, prefix string) error {
+	refs, err := runGit(repo, "for-each-ref", "--format=delete %(refname)", prefix)
+	if err != nil || refs == "" {
+		return err
+	}
+	_, err = runGitEnv(repo, nil, refs, "update-ref", "--stdin")
+	return err
+}
+
+// Write the files of ref of a git repository, or of subdir in it, to dst. The
+// tree is checked out through a temporary index rather than exported with git
+// archive, so export-ignore and export-subst attributes of the source don't
+// change the files. Returns the submodules, which are skipped.
+func exportGitTree(source string, ref string, subdir string, dst string) ([]string, error) {
+	clean, err := cleanSubdir(subdir)
+	if err != nil {
+		return nil, err
+	}
+	if dst, err = filepath.Abs(dst); err != nil {
+		return nil, err
+	}
+	tempDir, err := os.MkdirTemp("", "grit-import-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tempDir)
+
+	repo := filepath.Join(tempDir, "repo.git")
+	if _, err := runGit(tempDir, "init", "-q", "--bare", repo); err != nil {
+		return nil, err
+	}
+	if err := fetchRef(repo, source, ref, importRef, true); err != nil {
+		return nil, err
+	}
+	tree := importRef + "^{tree}"
+	if clean != "." {
+		tree = importRef + ":" + clean
+	}
+	if kind, err := runGit(repo, "cat-file", "-t", tree); err != nil || strings.TrimSpace(kind) != "tree" {
+		return nil, fmt.Errorf("the source has no directory %s", subdir)
+	}
+
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tempDir, "index")}
+	if _, err := runGitEnv(repo, env, "", "read-tree", tree); err != nil {
+		return nil, err
+	}
+	if _, err := runGitEnv(repo, env, "", "--work-tree="+dst, "checkout-index", "-a", "-f"); err != nil {
+		return nil, err
+	}
+
+	// checkout-index leaves out submodules
+	out, err := runGitEnv(repo, env, "", "ls-files", "-s", "-z")
+	if err != nil {
+		return nil, err
+	}
+	var skipped []string
+	for _, entry := range strings.Split(out, "\x00") {
+		if info, name, ok := strings.Cut(entry, "\t"); ok && strings.HasPrefix(info, "160000 ") {
+			skipped = append(skipped, name)
+		}
+	}
+	return skipped, nil
+}
+
+// Client for archive downloads, with a timeout so an unresponsive server doesn't
+// hang the import
+var downloadClient = &http.Client{Timeout: 10 * time.Minute}
+
+// Download an archive to dir and return its path
+func downloadArchive(source string, dir string) (string, error) {
+	resp, err := downloadClient.Get(source)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("downloading %s: %s", source, resp.Status)
+	}
+
+	file, err := os.Create(filepath.Join(dir, "source."+archiveFormat(source)))
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	if _, err := io.Copy(file, resp.Body); err != nil {
+		return "", err
+	}
+	return file.Name(), file.Close()
+}
+
+// Write the files of an archive, or of subdir in it, to dst. With stripTop, a
+// directory holding every entry of the archive, as in the archives GitHub
+// creates of a repository, is left out. Returns the entries that were skipped.
+func extractArchive(archive string, format string, subdir string, dst string, stripTop bool) ([]string, error) {
+	var prefix string
+	if stripTop {
+		top, err := archiveTopDir(archive, format)
+		if err != nil {
+			return nil, err
+		}
+		prefix = top
+	}
+	clean, err := cleanSubdir(subdir)
+	if err != nil {
+		return nil, err
+	}
+	if clean != "." {
+		prefix = path.Join(prefix, clean)
+	}
+
+	var skipped []string
+	links := make(map[string]string)
+	found := false
+	err = walkArchive(archive, format, func(name string, mode fs.FileMode, link string, r io.Reader) error {
+		rel, ok := archiveRel(name, prefix)
+		if !ok {
+			return nil
+		}
+		found = true
+		if rel == "" {
+			return nil
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+
+		switch {
+		case mode.IsDir():
+			return os.MkdirAll(target, 0755)
+		case mode.IsRegular():
+			if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+				return err
+			}
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode.Perm()|0200)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(file, r); err != nil {
+				file.Close()
+				return err
+			}
+			return file.Close()
+		case mode&fs.ModeSymlink != 0:
+			// Symlinks are created last, so no file is written through one
+			links[rel] = link
+			return nil
+		default:
+			skipped = append(skipped, rel)
+			return nil
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !found && prefix != "" {
+		return nil, fmt.Errorf("the source has no directory %s", subdir)
+	}
+
+	rels := make([]string, 0, len(links))
+	for rel := range links {
+		rels = append(rels, rel)
+	}
+	sort.Strings(rels)
+	for _, rel := range rels {
+		if hasSymlinkParent(dst, rel) {
+			skipped = append(skipped, rel)
+			continue
+		}
+		target := filepath.Join(dst, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return nil, err
+		}
+		os.Remove(target)
+		if err := os.Symlink(links[rel], target); err != nil {
+			return nil, err
+		}
+	}
+	return skipped, nil
+}
+
+// The subdirectory of a source to import, cleaned and with forward slashes, "."
+// for all of it
+func cleanSubdir(subdir string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(subdir))
+	if path.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("subdirectory %s is outside of the source", subdir)
+	}
+	return clean, nil
+}
+
+// Whether a directory between dst and the entry rel in it is a symlink, which
+// could point outside of dst
+func hasSymlinkParent(dst string, rel string) bool {
+	dir := dst
+	parts := strings.Split(rel, "/")
+	for _, part := range parts[:len(parts)-1] {
+		dir = filepath.Join(dir, part)
+		if info, err := os.Lstat(dir); err == nil && info.Mode()&fs.ModeSymlink != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// The path of an archive entry relative to the directory prefix, with entries
+// that try to escape it cleaned up. Returns false for entries outside prefix.
+func archiveRel(name string, prefix string) (string, bool) {
+	clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+	if prefix == "" {
+		return clean, true
+	}
+	if clean == prefix {
+		return "", true
+	}
+	rel, ok := strings.CutPrefix(clean, prefix+"/")
+	return rel, ok
+}
+
+// The directory holding every entry of an archive, if there is one
+func archiveTopDir(archive string, format string) (string, error) {
+	var top string
+	single := true
+	err := walkArchive(archive, format, func(name string, mode fs.FileMode, link string, r io.Reader) error {
+		clean := strings.TrimPrefix(path.Clean("/"+name), "/")
+		first, _, nested := strings.Cut(clean, "/")
+		if clean == "" || (!nested && !mode.IsDir()) || (top != "" && first != top) {
+			single = false
+		}
+		top = first
+		return nil
+	})
+	if err != nil || !single {
+		return "", err
+	}
+	return top, nil
+}
+
+// Call fn with the name, mode, symlink target and content of each entry of a zip
+// or tar archive. Hard links have an irregular mode.
+func walkArchive(archive string, format string, fn func(name string, mode fs.FileMode, link string, r io.Reader) error) error {
+	if format == "zip" {
+		zr, err := zip.OpenReader(archive)
+		if err != nil {
+			return err
+		}
+		defer zr.Close()
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			if err != nil {
+				return err
+			}
+			// Zip archives store the target of a symlink as its content
+			var link string
+			if f.Mode()&fs.ModeSymlink != 0 {
+				target, err := io.ReadAll(rc)
+				if err != nil {
+					rc.Close()
+					return err
+				}
+				link = string(target)
+			}
+			err = fn(f.Name, f.Mode(), link, rc)
+			rc.Close()
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var r io.Reader = file
+	if format == "tgz" {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		// git archive stores the commit in a global header
+		if hdr.Typeflag == tar.TypeXGlobalHeader {
+			continue
+		}
+		mode := hdr.FileInfo().Mode()
+		if hdr.Typeflag == tar.TypeLink {
+			mode |= fs.ModeIrregular
+		}
+		if err := fn(hdr.Name, mode, hdr.Linkname, tr); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/output"
+)
+
+func TestImportHistory(t *testing.T) {
+	source := newGitRepo(t)
+	commitFiles(t, source, "add main", map[string]string{"main.go": "package main"})
+	commitFiles(t, source, "add docs", map[string]string{"docs/README.md": "# Tool"})
+
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{"grit.yaml": "types: {}\n"})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "staged.txt"), []byte("staged"), 0644))
+	git(t, dir, "add", "staged.txt")
+
+	require.NoError(t, importHistory(dir, source, "", "", "packages/tool/my tool", "my tool: import", []byte("package:\n  name: my tool\n")))
+
+	assert.Equal(t, "my tool: import\nadd docs\nadd main\ninitial\n", git(t, dir, "log", "--format=%s", "--topo-order"))
+	assert.Equal(t, "initial\nadd docs\n", git(t, dir, "log", "--format=%s", "HEAD^1", "HEAD^2", "--no-walk"))
+	assert.Equal(t, "add main\n", git(t, dir, "log", "--format=%s", "--", "packages/tool/my tool/main.go"))
+	content, err := os.ReadFile(filepath.Join(dir, "packages/tool/my tool/docs/README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Tool", string(content))
+
+	// The package config is part of the merge commit
+	assert.Equal(t, "package:\n  name: my tool\n", git(t, dir, "show", "HEAD:packages/tool/my tool/grit.yaml"))
+	assert.FileExists(t, filepath.Join(dir, "packages/tool/my tool/grit.yaml"))
+
+	// The index keeps its staged changes and the temporary ref is removed
+	assert.Equal(t, "A  staged.txt\n", git(t, dir, "status", "--short"))
+	assert.Equal(t, "", git(t, dir, "for-each-ref", importRef))
+
+	// Importing into an existing directory fails
+	assert.Error(t, importHistory(dir, source, "", "", "packages/tool/my tool", "again", nil))
+
+	// A source with a grit.yaml of its own is refused, leaving HEAD alone
+	head := git(t, dir, "rev-parse", "HEAD")
+	commitFiles(t, source, "add config", map[string]string{"grit.yaml": "package:\n  name: tool\n"})
+	assert.Error(t, importHistory(dir, source, "", "", "packages/tool/other", "other: import", []byte("package: {}\n")))
+	assert.Equal(t, head, git(t, dir, "rev-parse", "HEAD"))
+	assert.NoDirExists(t, filepath.Join(dir, "packages/tool/other"))
+}
+
+func TestImportHistorySubdir(t *testing.T) {
+	source := newGitRepo(t)
+	commitFiles(t, source, "add util", map[string]string{"tools/util/util.go": "package util"})
+	commitFiles(t, source, "add cli", map[string]string{"tools/cli/main.go": "package main"})
+	commitFiles(t, source, "change util", map[string]string{"tools/util/util.go": "package util // v2"})
+	git(t, source, "tag", "v2")
+	commitFiles(t, source, "remove util", map[string]string{"tools/util/util.go": ""})
+
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{"grit.yaml": "types: {}\n"})
+	require.NoError(t, importHistory(dir, source, "v2", "tools/util", "lib/util", "util: import", nil))
+
+	// Only the commits that changed the directory up to the tag are imported
+	assert.Equal(t, "util: import\nchange util\nadd util\ninitial\n", git(t, dir, "log", "--format=%s", "--topo-order"))
+	assert.Equal(t, "lib/util/util.go\n", git(t, dir, "ls-files", "lib"))
+}
+
+func TestSourceKind(t *testing.T) {
+	bare := t.TempDir()
+	git(t, bare, "init", "-q", "--bare")
+	work := newGitRepo(t)
+
+	tests := map[string]struct {
+		source string
+		ref    string
+		want   importSourceKind
+	}{
+		"https":            {"https://github.com/org/repo", "", sourceGit},
+		"file url":         {"file:///srv/repo.git", "", sourceGit},
+		"scp":              {"git@github.com:org/repo.git", "", sourceGit},
+		"bare repository":  {bare, "", sourceGit},
+		"working tree":     {work, "", sourceDir},
+		"working tree ref": {work, "main", sourceGit},
+		"windows path":     {`C:\\src\\repo`, "", sourceDir},
+		"tar.gz":           {"https://example.com/repo-1.0.tar.gz?download=1", "", sourceArchive},
+		"zip":              {"../repo.zip", "", sourceArchive},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tt.want, sourceKind(tt.source, tt.ref))
+		})
+	}
+}
+
+func TestExtractArchive(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "repo.tar.gz")
+	file, err := os.Create(archive)
+	require.NoError(t, err)
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	for _, entry := range []struct {
+		name string
+		mode int64
+		body string
+	}{
+		{"repo-1.0/", 0755, ""},
+		{"repo-1.0/README.md", 0644, "# Repo"},
+		{"repo-1.0/bin/run.sh", 0755, "#!/bin/sh"},
+		{"../../repo-1.0/escape.txt", 0644, "escape"},
+	} {
+		hdr := &tar.Header{Name: entry.name, Mode: entry.mode, Size: int64(len(entry.body)), Typeflag: tar.TypeReg}
+		if entry.body == "" {
+			hdr.Typeflag = tar.TypeDir
+		}
+		require.NoError(t, tw.WriteHeader(hdr))
+		_, err := tw.Write([]byte(entry.body))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "repo-1.0/link", Linkname: "README.md", Typeflag: tar.TypeSymlink}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "repo-1.0/out", Linkname: "/tmp", Typeflag: tar.TypeSymlink}))
+	require.NoError(t, tw.WriteHeader(&tar.Header{Name: "repo-1.0/out/escape", Linkname: "README.md", Typeflag: tar.TypeSymlink}))
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	require.NoError(t, file.Close())
+
+	dst := filepath.Join(t.TempDir(), "pkg")
+	skipped, err := extractArchive(archive, "tgz", "", dst, true)
+	require.NoError(t, err)
+	// A symlink below another one is skipped, it could be created outside
+	assert.Equal(t, []string{"out/escape"}, skipped)
+	target, err := os.Readlink(filepath.Join(dst, "link"))
+	require.NoError(t, err)
+	assert.Equal(t, "README.md", target)
+	content, err := os.ReadFile(filepath.Join(dst, "README.md"))
+	require.NoError(t, err)
+	assert.Equal(t, "# Repo", string(content))
+	info, err := os.Stat(filepath.Join(dst, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	// The entry trying to escape is kept inside the package
+	assert.FileExists(t, filepath.Join(dst, "escape.txt"))
+
+	dst = filepath.Join(t.TempDir(), "bin")
+	_, err = extractArchive(archive, "tgz", "bin", dst, true)
+	require.NoError(t, err)
+	assert.FileExists(t, filepath.Join(dst, "run.sh"))
+
+	_, err = extractArchive(archive, "tgz", "docs", t.TempDir(), true)
+	assert.Error(t, err)
+	_, err = extractArchive(archive, "tgz", "../other", t.TempDir(), true)
+	assert.Error(t, err)
+}
+
+func TestExportGitTree(t *testing.T) {
+	source := newGitRepo(t)
+	commitFiles(t, source, "one", map[string]string{"tools/util/a.txt": "v1", "README.md": "# Tools"})
+	first := git(t, source, "rev-parse", "--short", "HEAD")[:7]
+	commitFiles(t, source, "two", map[string]string{"tools/util/a.txt": "v2"})
+
+	tests := map[string]struct {
+		ref    string
+		subdir string
+		file   string
+		want   string
+	}{
+		"default branch":      {"", "", "tools/util/a.txt", "v2"},
+		"abbreviated commit":  {first, "", "tools/util/a.txt", "v1"},
+		"subdirectory of ref": {first, "tools/util", "a.txt", "v1"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			dst := t.TempDir()
+			_, err := exportGitTree("file://"+source, tt.ref, tt.subdir, dst)
+			require.NoError(t, err)
+			content, err := os.ReadFile(filepath.Join(dst, tt.file))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, string(content))
+		})
+	}
+
+	_, err := exportGitTree(source, "no-such-branch", "", t.TempDir())
+	assert.Error(t, err)
+	_, err = exportGitTree(source, "", "docs", t.TempDir())
+	assert.Error(t, err)
+
+	// Export attributes of the source don't change the files, and modes and
+	// symlinks are kept
+	commitFiles(t, source, "attributes", map[string]string{
+		".gitattributes":    "tools/util/a.txt export-ignore\nversion.txt export-subst\n",
+		"version.txt":       "$Format:%H$",
+		"tools/util/run.sh": "#!/bin/sh",
+	})
+	require.NoError(t, os.Chmod(filepath.Join(source, "tools", "util", "run.sh"), 0755))
+	require.NoError(t, os.Symlink("run.sh", filepath.Join(source, "tools", "util", "run")))
+	git(t, source, "add", "-A")
+	git(t, source, "commit", "-q", "-m", "script")
+
+	dst := t.TempDir()
+	skipped, err := exportGitTree(source, "", "", dst)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+	content, err := os.ReadFile(filepath.Join(dst, "tools", "util", "a.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "v2", string(content))
+	content, err = os.ReadFile(filepath.Join(dst, "version.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "$Format:%H$", string(content))
+	info, err := os.Stat(filepath.Join(dst, "tools", "util", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	link, err := os.Readlink(filepath.Join(dst, "tools", "util", "run"))
+	require.NoError(t, err)
+	assert.Equal(t, "run.sh", link)
+}
+
+func TestCopyDir(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(src, "bin"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(src, ".git"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "bin", "run.sh"), []byte("#!/bin/sh"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(src, "README.md"), []byte("# Tool"), 0644))
+	require.NoError(t, os.Symlink("bin/run.sh", filepath.Join(src, "run")))
+
+	dst := t.TempDir()
+	skipped, err := copyDir(src, dst)
+	require.NoError(t, err)
+	assert.Empty(t, skipped)
+
+	info, err := os.Stat(filepath.Join(dst, "bin", "run.sh"))
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0755), info.Mode().Perm())
+	target, err := os.Readlink(filepath.Join(dst, "run"))
+	require.NoError(t, err)
+	assert.Equal(t, "bin/run.sh", target)
+	assert.NoDirExists(t, filepath.Join(dst, ".git"))
+}
+
+func TestImportPackage(t *testing.T) {
+	src := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(src, "main.go"), []byte("package main"), 0644))
+	formatter := output.New()
+
+	dir := t.TempDir()
+	pkgDir := filepath.Join(dir, "packages", "tool", "cli")
+	require.NoError(t, importPackage(src, sourceDir, pkgDir, "cli", "tool", formatter))
+	assert.FileExists(t, filepath.Join(pkgDir, "main.go"))
+	assert.FileExists(t, filepath.Join(pkgDir, "grit.yaml"))
+	entries, err := os.ReadDir(filepath.Dir(pkgDir))
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "the staging directory is renamed into place")
+
+	// A source with a grit.yaml of its own is refused rather than overwritten
+	require.NoError(t, os.WriteFile(filepath.Join(src, "grit.yaml"), []byte("package:\n  name: cli\n"), 0644))
+	err = importPackage(src, sourceDir, filepath.Join(dir, "packages", "tool", "other"), "other", "tool", formatter)
+	assert.ErrorContains(t, err, "grit.yaml")
+	assert.NoDirExists(t, filepath.Join(dir, "packages", "tool", "other"))
+
+	// A subdirectory can't reach outside of the source
+	importSubdir = "../.."
+	defer func() { importSubdir = "" }()
+	err = importPackage(src, sourceDir, filepath.Join(dir, "packages", "tool", "up"), "up", "tool", formatter)
+	assert.ErrorContains(t, err, "outside of the source")
+	assert.NoDirExists(t, filepath.Join(dir, "packages", "tool", "up"))
+	importSubdir = ""
+
+	// A failed import removes the package and the directories created for it
+	dir = t.TempDir()
+	pkgDir = filepath.Join(dir, "packages", "tool", "cli")
+	assert.Error(t, importPackage(filepath.Join(src, "missing"), sourceDir, pkgDir, "cli", "tool", formatter))
+	entries, err = os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+}
@@ -0,0 +1,227 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var infoCmd = &cobra.Command{
+	Use:   "info [type] [name]",
+	Short: "Show details of a package",
+	Long: `Show everything grit knows about a package: its metadata, the targets it can
+run and the level each is defined at, its direct and transitive dependencies and
+dependents, the state of its build cache, the result of its last build and which
+standard directories exist.
+
+Examples:
+  grit info utils        # Package by bare name
+  grit info lib/utils    # Package by type/name
+  grit info lib utils    # Same as above`,
+	Args: cobra.RangeArgs(1, 2),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+
+		rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error loading root config: %v", err))
+			os.Exit(1)
+		}
+
+		pm := grit.NewPackageManager(cwd)
+		packages, err := pm.LoadPackagesWithConfig(rootConfig)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error loading packages: %v", err))
+			os.Exit(1)
+		}
+		index := grit.NewIndex(packages)
+
+		cfg, err := index.Lookup(packageRefFromArgs(args))
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+			os.Exit(1)
+		}
+		pkg := cfg.Package
+
+		formatter.Header(fmt.Sprintf("Package %s", pkg.ID()))
+		displayPackageDetails(pkg, cwd, formatter)
+
+		formatter.Section("Targets")
+		targets := rootConfig.ResolveTargets(cfg)
+		if len(targets) == 0 {
+			formatter.Info("No targets defined")
+		} else {
+			rows := make([][]string, 0, len(targets))
+			for _, target := range targets {
+				rows = append(rows, []string{target.Name, target.Command, string(target.Source)})
+			}
+			formatter.Table([]string{"TARGET", "COMMAND", "SOURCE"}, rows)
+		}
+
+		dependencies, dependents := dependencyGraph(index)
+		_, depErrs := index.Dependencies(pkg)
+
+		formatter.Section("Dependencies")
+		displayRelations(pkg.ID(), dependencies, formatter)
+		for _, depErr := range depErrs {
+			formatter.Warning(fmt.Sprintf("Unresolved dependency: %v", depErr))
+		}
+
+		formatter.Section("Dependents")
+		displayRelations(pkg.ID(), dependents, formatter)
+
+		formatter.Section("Build")
+		cacheDir := filepath.Join(cwd, ".grit", "cache")
+		formatter.Detail(fmt.Sprintf("Cache: %s", packageCacheStatus(cacheDir, pkg)))
+		record, err := loadBuildRecord(cacheDir, pkg)
+		if err != nil {
+			formatter.Warning(fmt.Sprintf("Could not read the last build result: %v", err))
+		}
+		formatter.Detail(fmt.Sprintf("Last build: %s", describeBuildRecord(record)))
+
+		formatter.Section("Directories")
+		for _, dir := range packageDirectories(cfg, rootConfig, cwd) {
+			rel := filepath.ToSlash(relativePath(cwd, dir))
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				formatter.Detail(fmt.Sprintf("✓ %s", rel))
+			} else {
+				formatter.Detail(fmt.Sprintf("✗ %s (missing)", rel))
+			}
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(infoCmd)
+}
+
+func displayPackageDetails(pkg grit.Package, cwd string, formatter *output.Formatter) {
+	formatter.PackageInfo(pkg.Name, pkg.Version, pkg.Type, nil)
+	if pkg.Description != "" {
+		formatter.Detail(pkg.Description)
+	}
+	formatter.Detail(fmt.Sprintf("Path: %s", filepath.ToSlash(relativePath(cwd, filepath.Dir(pkg.Path)))))
+	if len(pkg.Owners) > 0 {
+		formatter.Detail(fmt.Sprintf("Owners: %s", strings.Join(pkg.Owners, ", ")))
+	}
+	if len(pkg.Tags) > 0 {
+		formatter.Detail(fmt.Sprintf("Tags: %s", strings.Join(pkg.Tags, ", ")))
+	}
+	if pkg.Private {
+		formatter.Detail("Private: not published")
+	}
+
+	keys := make([]string, 0, len(pkg.Metadata))
+	for key := range pkg.Metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		formatter.Detail(fmt.Sprintf("%s: %v", key, pkg.Metadata[key]))
+	}
+}
+
+// Dependency edges between package IDs, in both directions
+func dependencyGraph(index *grit.Index) (map[string][]string, map[string][]string) {
+	dependencies := make(map[string][]string)
+	dependents := make(map[string][]string)
+	for _, cfg := range index.Packages() {
+		depIDs, _ := index.Dependencies(cfg.Package)
+		dependencies[cfg.Package.ID()] = depIDs
+		for _, depID := range depIDs {
+			dependents[depID] = append(dependents[depID], cfg.Package.ID())
+		}
+	}
+	return dependencies, dependents
+}
+
+// Everything reachable from start over the edges, excluding start itself
+func transitiveClosure(start string, edges map[string][]string) []string {
+	visited := map[string]bool{start: true}
+	var result []string
+	queue := append([]string{}, edges[start]...)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if visited[id] {
+			continue
+		}
+		visited[id] = true
+		result = append(result, id)
+		queue = append(queue, edges[id]...)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Print the direct relations of a package and those only reachable through them
+func displayRelations(id string, edges map[string][]string, formatter *output.Formatter) {
+	direct := append([]string{}, edges[id]...)
+	sort.Strings(direct)
+
+	var indirect []string
+	for _, other := range transitiveClosure(id, edges) {
+		if !containsString(direct, other) {
+			indirect = append(indirect, other)
+		}
+	}
+
+	if len(direct) == 0 {
+		formatter.Detail("None")
+		return
+	}
+	formatter.Detail(fmt.Sprintf("Direct: %s", strings.Join(direct, ", ")))
+	if len(indirect) > 0 {
+		formatter.Detail(fmt.Sprintf("Transitive: %s", strings.Join(indirect, ", ")))
+	}
+}
+
+// Compare the package files with the hash cached by the last successful build
+func packageCacheStatus(cacheDir string, pkg grit.Package) string {
+	cachedHash, err := os.ReadFile(packageCacheFile(cacheDir, pkg))
+	if err != nil {
+		return "not cached"
+	}
+	hash, err := calculatePackageHash(filepath.Dir(pkg.Path))
+	if err != nil {
+		return fmt.Sprintf("unknown (%v)", err)
+	}
+	if string(cachedHash) != hash {
+		return "changed since the last build"
+	}
+	return "up to date"
+}
+
+func describeBuildRecord(record *buildRecord) string {
+	if record == nil {
+		return "never built"
+	}
+	when := record.Time.Format(time.RFC3339)
+	if !record.Success {
+		return fmt.Sprintf("failed at %s after %v: %s", when, record.Duration.Round(time.Millisecond), record.Error)
+	}
+	return fmt.Sprintf("succeeded at %s in %v", when, record.Duration.Round(time.Millisecond))
+}
+
+// The standard package subdirectories and the build and coverage output of the package
+func packageDirectories(cfg grit.Config, rootConfig *grit.RootConfig, cwd string) []string {
+	pkgDir := filepath.Dir(cfg.Package.Path)
+	dirs := make([]string, 0, len(packageSubdirs)+2)
+	for _, subdir := range packageSubdirs {
+		dirs = append(dirs, filepath.Join(pkgDir, subdir))
+	}
+	return append(dirs, packageOutputDirs(cwd, rootConfig, cfg.Package)...)
+}
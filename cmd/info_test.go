@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestDependencyGraph(t *testing.T) {
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app", Dependencies: []string{"api-client"}}},
+		{Package: grit.Package{Name: "api-client", Type: "lib", Dependencies: []string{"utils"}}},
+		{Package: grit.Package{Name: "utils", Type: "lib"}},
+	})
+
+	dependencies, dependents := dependencyGraph(index)
+	assert.Equal(t, []string{"lib/api-client"}, dependencies["app/web"])
+	assert.Equal(t, []string{"lib/api-client", "lib/utils"}, transitiveClosure("app/web", dependencies))
+	assert.Equal(t, []string{"app/web", "lib/api-client"}, transitiveClosure("lib/utils", dependents))
+	assert.Empty(t, transitiveClosure("app/web", dependents))
+}
+
+func TestBuildRecord(t *testing.T) {
+	cacheDir := t.TempDir()
+	pkg := grit.Package{Name: "utils", Type: "lib", Path: filepath.Join(t.TempDir(), "grit.yaml")}
+
+	record, err := loadBuildRecord(cacheDir, pkg)
+	require.NoError(t, err)
+	assert.Nil(t, record)
+	assert.Equal(t, "never built", describeBuildRecord(record))
+	assert.Equal(t, "not cached", packageCacheStatus(cacheDir, pkg))
+
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, saveBuildRecord(cacheDir, pkg, buildRecord{
+		Time:     started,
+		Duration: 1500 * time.Millisecond,
+		Error:    errors.New("build command failed").Error(),
+	}))
+	record, err = loadBuildRecord(cacheDir, pkg)
+	require.NoError(t, err)
+	require.NotNil(t, record)
+	assert.Equal(t, "failed at 2024-05-01T12:00:00Z after 1.5s: build command failed", describeBuildRecord(record))
+
+	hash, err := calculatePackageHash(filepath.Dir(pkg.Path))
+	require.NoError(t, err)
+	require.NoError(t, os.MkdirAll(filepath.Join(cacheDir, "lib"), 0755))
+	require.NoError(t, os.WriteFile(packageCacheFile(cacheDir, pkg), []byte(hash), 0644))
+	assert.Equal(t, "up to date", packageCacheStatus(cacheDir, pkg))
+}
@@ -1,80 +1,457 @@
 package cmd
 
 import (
+	"bufio"
 	_ "embed"
 	"fmt"
+	"io"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
-	"gopkg.in/yaml.v3"
 )
 
-//go:embed tpl/grit.yaml
-var gritYamlTemplate string
+//go:embed tpl/root_grit.yaml
+var rootGritYamlTemplate []byte
+
+var (
+	initName    string
+	initOwner   string
+	initLicense string
+	initTypes   []string
+	initYes     bool
+)
+
+// Types offered by init, in the order they are listed in a new grit.yaml
+var starterTypes = []string{"lib", "app", "service", "tool"}
+
+// Entries init makes sure are present in .gitignore
+var initGitignoreEntries = []string{".grit/cache/", "build/", "coverage/"}
+
+// License files and the SPDX identifiers recognised in them
+var licenseFiles = []string{"LICENSE", "LICENSE.md", "LICENSE.txt", "COPYING"}
+var licenseMarkers = []struct {
+	marker string
+	id     string
+}{
+	{"MIT License", "MIT"},
+	{"Apache License", "Apache-2.0"},
+	{"Mozilla Public License Version 2.0", "MPL-2.0"},
+	{"GNU AFFERO GENERAL PUBLIC LICENSE", "AGPL-3.0"},
+	{"GNU LESSER GENERAL PUBLIC LICENSE", "LGPL-3.0"},
+	{"GNU GENERAL PUBLIC LICENSE", "GPL-3.0"},
+	{"BSD 3-Clause", "BSD-3-Clause"},
+	{"BSD 2-Clause", "BSD-2-Clause"},
+}
+
+// What init sets up in a workspace
+type initOptions struct {
+	repo  grit.RepoConfig
+	types []string
+}
 
 var initCmd = &cobra.Command{
 	Use:   "init",
 	Short: "Initialize a new Grit workspace",
-	Long:  `Create a new Grit workspace with default configuration files`,
+	Long: `Create a new Grit workspace, or complete the setup of an existing one.
+
+The repository name, owner and URL default to the values of the git remote
+'origin' and the license to the one found in the LICENSE file. A new workspace
+gets the starter types lib, app, service and tool. When run in a terminal, init
+asks to confirm each value unless --yes is given.
+
+Running init again is safe: values already in grit.yaml are kept, and only
+missing types, directories and .gitignore entries are added.
+
+Examples:
+  grit init                               # Interactive setup
+  grit init --yes                         # Accept the detected defaults
+  grit init --types lib,service --yes     # Only set up the lib and service types`,
+	Args: cobra.NoArgs,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		rootDir, err := os.Getwd()
 		if err != nil {
 			return fmt.Errorf("failed to get current directory: %w", err)
 		}
 
-		gritDir := filepath.Join(rootDir, ".grit")
+		opts, err := defaultInitOptions(rootDir)
+		if err != nil {
+			return err
+		}
+
+		flags := cmd.Flags()
+		if flags.Changed("name") {
+			opts.repo.Name = initName
+		}
+		if flags.Changed("owner") {
+			opts.repo.Owner = initOwner
+		}
+		if flags.Changed("license") {
+			opts.repo.License = initLicense
+		}
+		if flags.Changed("types") {
+			opts.types = initTypes
+		}
+
+		if !initYes && isTerminal(cmd.InOrStdin()) {
+			reader := bufio.NewReader(cmd.InOrStdin())
+			out := cmd.OutOrStdout()
+			if !flags.Changed("name") {
+				opts.repo.Name = prompt(reader, out, "Repository name", opts.repo.Name)
+			}
+			if !flags.Changed("owner") {
+				opts.repo.Owner = prompt(reader, out, "Owner", opts.repo.Owner)
+			}
+			if !flags.Changed("license") {
+				opts.repo.License = prompt(reader, out, "License", opts.repo.License)
+			}
+			if !flags.Changed("types") {
+				opts.types = splitList(prompt(reader, out, "Package types", strings.Join(opts.types, ",")))
+			}
+		}
+
+		changes, err := initWorkspace(rootDir, opts)
+		if err != nil {
+			return err
+		}
+
+		for _, change := range changes {
+			fmt.Fprintf(cmd.OutOrStdout(), "  %s\n", change)
+		}
+		if len(changes) == 0 {
+			fmt.Fprintf(cmd.OutOrStdout(), "Grit workspace in %s is already initialized\n", rootDir)
+			return nil
+		}
+		fmt.Fprintf(cmd.OutOrStdout(), "Initialized Grit workspace in %s\n", rootDir)
+		return nil
+	},
+}
+
+func init() {
+	initCmd.Flags().StringVar(&initName, "name", "", "Repository name (default from the git remote or directory name)")
+	initCmd.Flags().StringVar(&initOwner, "owner", "", "Repository owner (default from the git remote)")
+	initCmd.Flags().StringVar(&initLicense, "license", "", "SPDX license identifier (default from the LICENSE file)")
+	initCmd.Flags().StringSliceVar(&initTypes, "types", nil, "Package types to set up (default lib,app,service,tool for a new workspace)")
+	initCmd.Flags().BoolVarP(&initYes, "yes", "y", false, "Don't prompt, accept the defaults")
+	rootCmd.AddCommand(initCmd)
+}
+
+// Defaults for init, taken from an existing grit.yaml, the git remote and the
+// license file, in that order of precedence
+func defaultInitOptions(rootDir string) (initOptions, error) {
+	opts := initOptions{repo: detectRepoConfig(rootDir)}
+
+	configPath := filepath.Join(rootDir, "grit.yaml")
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		opts.types = starterTypes
+		return opts, nil
+	}
+
+	existing, err := grit.LoadConfig(configPath)
+	if err != nil {
+		return opts, fmt.Errorf("failed to load existing grit.yaml: %w", err)
+	}
+	if existing.Repo.Name != "" {
+		opts.repo.Name = existing.Repo.Name
+	}
+	if existing.Repo.Owner != "" {
+		opts.repo.Owner = existing.Repo.Owner
+	}
+	if existing.Repo.URL != "" {
+		opts.repo.URL = existing.Repo.URL
+	}
+	if existing.Repo.License != "" {
+		opts.repo.License = existing.Repo.License
+	}
+	for _, typeName := range starterTypes {
+		if _, exists := existing.Types[typeName]; exists {
+			opts.types = append(opts.types, typeName)
+		}
+	}
+	return opts, nil
+}
+
+// Set up the workspace in rootDir and describe every change made. Existing
+// values are never overwritten, so the result of a second run is empty.
+func initWorkspace(rootDir string, opts initOptions) ([]string, error) {
+	var changes []string
 
+	gritDir := filepath.Join(rootDir, ".grit")
+	if _, err := os.Stat(gritDir); os.IsNotExist(err) {
 		if err := os.MkdirAll(gritDir, 0755); err != nil {
-			return fmt.Errorf("failed to create .grit directory: %w", err)
+			return nil, fmt.Errorf("failed to create .grit directory: %w", err)
 		}
+		changes = append(changes, "created .grit/")
+	}
 
-		configFile := filepath.Join("grit.yaml")
-		var existingConfig grit.Config
+	change, err := initRootConfig(filepath.Join(rootDir, "grit.yaml"), opts)
+	if err != nil {
+		return nil, err
+	}
+	if change != "" {
+		changes = append(changes, change)
+	}
 
-		if _, err := os.Stat(configFile); err == nil {
-			data, err := os.ReadFile(configFile)
-			if err != nil {
-				return fmt.Errorf("failed to read existing grit.yaml: %w", err)
+	for _, typeName := range opts.types {
+		for _, dir := range typeDirs(typeName) {
+			path := filepath.Join(rootDir, dir)
+			if _, err := os.Stat(path); !os.IsNotExist(err) {
+				continue
 			}
-			if err := yaml.Unmarshal(data, &existingConfig); err != nil {
-				return fmt.Errorf("failed to parse existing grit.yaml: %w", err)
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory %s: %w", dir, err)
 			}
+			changes = append(changes, fmt.Sprintf("created %s/", filepath.ToSlash(dir)))
 		}
+	}
 
-		templateConfig := grit.TypeConfig{}
-		yaml.Unmarshal([]byte(gritYamlTemplate), &templateConfig)
+	added, err := ensureGitignoreEntries(filepath.Join(rootDir, ".gitignore"), initGitignoreEntries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update .gitignore: %w", err)
+	}
+	if len(added) > 0 {
+		changes = append(changes, fmt.Sprintf("added %s to .gitignore", strings.Join(added, ", ")))
+	}
 
-		mergedConfig := existingConfig
-		if existingConfig.Types == nil {
-			existingConfig.Types = make(map[string]grit.TypeConfig)
-		}
+	return changes, nil
+}
+
+// Create grit.yaml from the template, or add missing repo values and types to
+// an existing one. Returns a description of the change, or "" if there was none.
+func initRootConfig(path string, opts initOptions) (string, error) {
+	template, err := grit.ParseDocument(path, rootGritYamlTemplate)
+	if err != nil {
+		return "", err
+	}
+	var templateConfig grit.RootConfig
+	if err := template.Decode(&templateConfig); err != nil {
+		return "", fmt.Errorf("failed to parse grit.yaml template: %w", err)
+	}
 
-		// Merge type configuration
-		if _, exists := existingConfig.Types["lib"]; !exists {
-			existingConfig.Types["lib"] = grit.TypeConfig{
-				PackageDir:  "packages/lib",
-				BuildDir:    "build/lib",
-				CoverageDir: "coverage/lib",
-				Targets:     templateConfig.Targets,
+	before, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		doc := template
+		for _, typeName := range starterTypes {
+			if !containsString(opts.types, typeName) {
+				doc.Delete("types", typeName)
+			}
+		}
+		for _, typeName := range opts.types {
+			if _, exists := templateConfig.Types[typeName]; !exists {
+				if err := doc.Set([]string{"types", typeName}, defaultTypeConfig(typeName)); err != nil {
+					return "", err
+				}
 			}
 		}
+		if err := doc.Set([]string{"version"}, grit.SchemaVersion); err != nil {
+			return "", err
+		}
+		if opts.repo != (grit.RepoConfig{}) {
+			if err := doc.Set([]string{"repo"}, opts.repo); err != nil {
+				return "", err
+			}
+		}
+		doc.Normalize(grit.RootConfig{})
+		if err := doc.Save(); err != nil {
+			return "", fmt.Errorf("failed to write grit.yaml: %w", err)
+		}
+		return "created grit.yaml", nil
+	} else if err != nil {
+		return "", fmt.Errorf("failed to read existing grit.yaml: %w", err)
+	}
 
-		mergedData, err := yaml.Marshal(mergedConfig)
-		if err != nil {
-			return fmt.Errorf("failed to marshal merged config: %w", err)
+	// Edit the existing file in place so comments and unknown sections survive
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read existing grit.yaml: %w", err)
+	}
+	var raw grit.RootConfig
+	if err := doc.Decode(&raw); err != nil {
+		return "", fmt.Errorf("failed to parse existing grit.yaml: %w", err)
+	}
+	existing, err := grit.LoadConfig(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to load existing grit.yaml: %w", err)
+	}
+
+	// Only fill in repo values that aren't set yet
+	missing := grit.RepoConfig{}
+	if raw.Repo.Name == "" {
+		missing.Name = opts.repo.Name
+	}
+	if raw.Repo.Owner == "" {
+		missing.Owner = opts.repo.Owner
+	}
+	if raw.Repo.URL == "" {
+		missing.URL = opts.repo.URL
+	}
+	if raw.Repo.License == "" {
+		missing.License = opts.repo.License
+	}
+	if missing != (grit.RepoConfig{}) {
+		if err := doc.Set([]string{"repo"}, missing); err != nil {
+			return "", err
 		}
+	}
 
-		if err := os.WriteFile(configFile, mergedData, 0644); err != nil {
-			return fmt.Errorf("failed to update grit.yaml: %w", err)
+	// Types may also come from included files, so check the effective config
+	for _, typeName := range opts.types {
+		if _, exists := existing.Types[typeName]; exists {
+			continue
+		}
+		typeConfig, ok := templateConfig.Types[typeName]
+		if !ok {
+			typeConfig = defaultTypeConfig(typeName)
 		}
+		if err := doc.Set([]string{"types", typeName}, typeConfig); err != nil {
+			return "", err
+		}
+	}
 
-		fmt.Printf("Initialized Grit workspace in %s\n", rootDir)
-		return nil
-	},
+	after, err := doc.Bytes()
+	if err != nil {
+		return "", err
+	}
+	if string(before) == string(after) {
+		return "", nil
+	}
+	if err := doc.Save(); err != nil {
+		return "", fmt.Errorf("failed to update grit.yaml: %w", err)
+	}
+	return "updated grit.yaml", nil
 }
 
-func init() {
-	rootCmd.AddCommand(initCmd)
+// Append the entries missing from a .gitignore file, creating it if needed.
+// Returns the entries that were added.
+func ensureGitignoreEntries(path string, entries []string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	present := make(map[string]bool)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		present[line] = true
+		present[strings.TrimSuffix(line, "/")+"/"] = true
+	}
+
+	var added []string
+	content := string(data)
+	for _, entry := range entries {
+		if present[entry] || present["/"+entry] {
+			continue
+		}
+		if content != "" && !strings.HasSuffix(content, "\n") {
+			content += "\n"
+		}
+		content += entry + "\n"
+		added = append(added, entry)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+	return added, os.WriteFile(path, []byte(content), 0644)
+}
+
+// Repo config detected from the git remote 'origin' and the license file
+func detectRepoConfig(rootDir string) grit.RepoConfig {
+	repo := grit.RepoConfig{Name: filepath.Base(rootDir)}
+
+	cmd := exec.Command("git", "remote", "get-url", "origin")
+	cmd.Dir = rootDir
+	if out, err := cmd.Output(); err == nil {
+		repo.URL = strings.TrimSpace(string(out))
+		owner, name := parseRemoteURL(repo.URL)
+		if name != "" {
+			repo.Name = name
+		}
+		repo.Owner = owner
+	}
+
+	repo.License = detectLicense(rootDir)
+	return repo
+}
+
+// Split a git remote URL into the owner and repository name. Both scp-like
+// (git@host:owner/name.git) and URL forms are supported.
+func parseRemoteURL(remote string) (string, string) {
+	remote = strings.TrimSuffix(strings.TrimSuffix(strings.TrimSpace(remote), "/"), ".git")
+	if i := strings.Index(remote, "://"); i >= 0 {
+		remote = remote[i+3:]
+		if j := strings.Index(remote, "/"); j >= 0 {
+			remote = remote[j+1:]
+		} else {
+			remote = ""
+		}
+	} else if i := strings.Index(remote, ":"); i >= 0 {
+		remote = remote[i+1:]
+	}
+
+	parts := strings.Split(strings.Trim(remote, "/"), "/")
+	name := parts[len(parts)-1]
+	if len(parts) < 2 {
+		return "", name
+	}
+	return parts[len(parts)-2], name
+}
+
+// SPDX identifier of the license in the workspace root, or "" if unknown
+func detectLicense(rootDir string) string {
+	for _, name := range licenseFiles {
+		data, err := os.ReadFile(filepath.Join(rootDir, name))
+		if err != nil {
+			continue
+		}
+		for _, license := range licenseMarkers {
+			if strings.Contains(string(data), license.marker) {
+				return license.id
+			}
+		}
+	}
+	return ""
+}
+
+// Ask for a value, returning the default when the answer is empty
+func prompt(reader *bufio.Reader, out io.Writer, label string, def string) string {
+	if def != "" {
+		fmt.Fprintf(out, "%s [%s]: ", label, def)
+	} else {
+		fmt.Fprintf(out, "%s: ", label)
+	}
+	answer, _ := reader.ReadString('\n')
+	answer = strings.TrimSpace(answer)
+	if answer == "" {
+		return def
+	}
+	return answer
+}
+
+// Whether the reader is an interactive terminal
+func isTerminal(r io.Reader) bool {
+	file, ok := r.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Split a comma or space separated list, dropping empty entries
+func splitList(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,106 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestParseRemoteURL(t *testing.T) {
+	tests := []struct {
+		remote string
+		owner  string
+		name   string
+	}{
+		{"git@github.com:acme/widgets.git", "acme", "widgets"},
+		{"https://github.com/acme/widgets.git", "acme", "widgets"},
+		{"https://gitlab.com/group/sub/widgets/", "sub", "widgets"},
+		{"ssh://git@host:2222/acme/widgets", "acme", "widgets"},
+		{"/srv/git/widgets.git", "git", "widgets"},
+		{"widgets", "", "widgets"},
+	}
+	for _, tt := range tests {
+		owner, name := parseRemoteURL(tt.remote)
+		assert.Equal(t, tt.owner, owner, tt.remote)
+		assert.Equal(t, tt.name, name, tt.remote)
+	}
+}
+
+func TestEnsureGitignoreEntries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".gitignore")
+	require.NoError(t, os.WriteFile(path, []byte("node_modules\n/build"), 0644))
+
+	added, err := ensureGitignoreEntries(path, []string{".grit/cache/", "build/", "coverage/"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{".grit/cache/", "coverage/"}, added)
+
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "node_modules\n/build\n.grit/cache/\ncoverage/\n", string(data))
+
+	added, err = ensureGitignoreEntries(path, []string{".grit/cache/", "build/", "coverage/"})
+	require.NoError(t, err)
+	assert.Empty(t, added)
+}
+
+func TestInitWorkspace(t *testing.T) {
+	root := t.TempDir()
+	opts := initOptions{
+		repo:  grit.RepoConfig{Name: "widgets", Owner: "acme", License: "MIT"},
+		types: []string{"lib", "service", "docs"},
+	}
+
+	changes, err := initWorkspace(root, opts)
+	require.NoError(t, err)
+	assert.Contains(t, changes, "created grit.yaml")
+	assert.DirExists(t, filepath.Join(root, "packages", "service"))
+	assert.DirExists(t, filepath.Join(root, ".prompt", "docs"))
+
+	cfg, err := grit.LoadConfig(filepath.Join(root, "grit.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, opts.repo, cfg.Repo)
+	assert.ElementsMatch(t, []string{"lib", "service", "docs"}, keys(cfg.Types))
+	assert.Equal(t, "packages/service", cfg.Types["service"].PackageDir)
+	assert.NotEmpty(t, cfg.Types["service"].Targets["run"])
+
+	// A second run with the same options changes nothing
+	changes, err = initWorkspace(root, opts)
+	require.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// Values that are already set are kept, and new types are added in place
+	path := filepath.Join(root, "grit.yaml")
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, append([]byte("# keep me\n"), data...), 0644))
+
+	changes, err = initWorkspace(root, initOptions{
+		repo:  grit.RepoConfig{Name: "other", URL: "git@github.com:acme/widgets.git"},
+		types: []string{"tool"},
+	})
+	require.NoError(t, err)
+	assert.Contains(t, changes, "updated grit.yaml")
+
+	data, err = os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Contains(t, string(data), "# keep me")
+
+	cfg, err = grit.LoadConfig(path)
+	require.NoError(t, err)
+	assert.Equal(t, "widgets", cfg.Repo.Name)
+	assert.Equal(t, "git@github.com:acme/widgets.git", cfg.Repo.URL)
+	assert.Contains(t, cfg.Types, "tool")
+	assert.Contains(t, cfg.Types, "lib")
+}
+
+func keys(m map[string]grit.TypeConfig) []string {
+	var result []string
+	for k := range m {
+		result = append(result, k)
+	}
+	return result
+}
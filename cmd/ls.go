@@ -0,0 +1,222 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	lsFilters []string
+	lsJSON    bool
+	lsFormat  string
+)
+
+// A package as listed by ls
+type packageListing struct {
+	ID           string                 `json:"id"`
+	Name         string                 `json:"name"`
+	Type         string                 `json:"type"`
+	Version      string                 `json:"version"`
+	Description  string                 `json:"description,omitempty"`
+	Path         string                 `json:"path"`
+	Dependencies []string               `json:"dependencies"`
+	Tags         []string               `json:"tags"`
+	Owners       []string               `json:"owners"`
+	Private      bool                   `json:"private"`
+	Metadata     map[string]interface{} `json:"metadata,omitempty"`
+}
+
+var lsCmd = &cobra.Command{
+	Use:   "ls",
+	Short: "List the packages in the workspace",
+	Long: `Print the package inventory with types, versions, paths, dependency counts,
+owners and tags.
+
+With --format each package is printed with a Go template instead of a table.
+The template can use the fields .ID, .Name, .Type, .Version, .Description,
+.Path, .Dependencies, .Tags, .Owners, .Private and .Metadata and the functions
+join and json, for example '{{.ID}} {{len .Dependencies}}'.
+
+Packages can be selected with one or more --filter expressions. A package is
+listed if it matches any of them. An expression is a comma separated list of
+terms that must all match:
+  tag:frontend     has the tag frontend (a bare value is a tag as well)
+  type:lib         is of type lib
+  owner:@org/team  is owned by @org/team
+  name:api-*       name or type/name matches the glob
+  private:true     is private (or false for public)
+Prefix a term with ! to negate it.
+
+Examples:
+  grit ls                                # List all packages
+  grit ls --json                         # Print the inventory as JSON
+  grit ls --format '{{.Name}}@{{.Version}}'
+  grit ls --filter frontend,!private     # Public packages tagged frontend
+  grit ls --filter type:lib --filter owner:@org/platform`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+
+		pm := grit.NewPackageManager(cwd)
+		packages, err := pm.LoadPackages()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error loading packages: %v", err))
+			os.Exit(1)
+		}
+
+		index := grit.NewIndex(packages)
+		selected, err := filterPackages(index, lsFilters)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting packages: %v", err))
+			os.Exit(1)
+		}
+		listings := listPackages(selected, index, cwd)
+
+		if lsJSON && lsFormat != "" {
+			formatter.Error("--json and --format can't be used together")
+			os.Exit(1)
+		}
+
+		if lsFormat != "" {
+			if err := writePackageListingsTemplate(cmd.OutOrStdout(), lsFormat, listings); err != nil {
+				formatter.Error(fmt.Sprintf("Error formatting packages: %v", err))
+				os.Exit(1)
+			}
+			return
+		}
+
+		if lsJSON {
+			if err := writePackageListingsJSON(cmd.OutOrStdout(), listings); err != nil {
+				formatter.Error(fmt.Sprintf("Error encoding packages: %v", err))
+				os.Exit(1)
+			}
+			return
+		}
+
+		if len(listings) == 0 {
+			formatter.Info("No packages found")
+			return
+		}
+		formatter.Table(
+			[]string{"NAME", "TYPE", "VERSION", "PATH", "DEPS", "OWNERS", "TAGS"},
+			packageListingRows(listings),
+		)
+	},
+}
+
+func init() {
+	lsCmd.Flags().StringArrayVar(&lsFilters, "filter", nil, "Only list packages matching the filter expression (repeatable)")
+	lsCmd.Flags().BoolVar(&lsJSON, "json", false, "Output the packages in JSON format")
+	lsCmd.Flags().StringVar(&lsFormat, "format", "", "Print each package with a Go template")
+	rootCmd.AddCommand(lsCmd)
+}
+
+// Listings for the packages, sorted by ID. Paths are relative to the workspace
+// root and dependencies are resolved to IDs where possible.
+func listPackages(packages []grit.Config, index *grit.Index, cwd string) []packageListing {
+	listings := make([]packageListing, 0, len(packages))
+	for _, cfg := range packages {
+		pkg := cfg.Package
+		dependencies := make([]string, 0, len(pkg.Dependencies))
+		for _, ref := range pkg.Dependencies {
+			if dep, err := index.Lookup(ref); err == nil {
+				dependencies = append(dependencies, dep.Package.ID())
+			} else {
+				dependencies = append(dependencies, ref)
+			}
+		}
+
+		listing := packageListing{
+			ID:           pkg.ID(),
+			Name:         pkg.Name,
+			Type:         pkg.Type,
+			Version:      pkg.Version,
+			Description:  pkg.Description,
+			Path:         filepath.ToSlash(relativePath(cwd, filepath.Dir(pkg.Path))),
+			Dependencies: dependencies,
+			Tags:         pkg.Tags,
+			Owners:       pkg.Owners,
+			Private:      pkg.Private,
+			Metadata:     pkg.Metadata,
+		}
+		if listing.Tags == nil {
+			listing.Tags = []string{}
+		}
+		if listing.Owners == nil {
+			listing.Owners = []string{}
+		}
+		listings = append(listings, listing)
+	}
+
+	sort.Slice(listings, func(i, j int) bool {
+		return listings[i].ID < listings[j].ID
+	})
+	return listings
+}
+
+func packageListingRows(listings []packageListing) [][]string {
+	rows := make([][]string, 0, len(listings))
+	for _, listing := range listings {
+		version := listing.Version
+		if listing.Private {
+			version += " (private)"
+		}
+		rows = append(rows, []string{
+			listing.Name,
+			listing.Type,
+			version,
+			listing.Path,
+			strconv.Itoa(len(listing.Dependencies)),
+			strings.Join(listing.Owners, ", "),
+			strings.Join(listing.Tags, ", "),
+		})
+	}
+	return rows
+}
+
+// Execute the template for each package, one package per line
+func writePackageListingsTemplate(w io.Writer, format string, listings []packageListing) error {
+	tmpl, err := template.New("format").Funcs(template.FuncMap{
+		"join": strings.Join,
+		"json": func(v interface{}) (string, error) {
+			data, err := json.Marshal(v)
+			return string(data), err
+		},
+	}).Parse(format)
+	if err != nil {
+		return err
+	}
+
+	for _, listing := range listings {
+		if err := tmpl.Execute(w, listing); err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintln(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePackageListingsJSON(w io.Writer, listings []packageListing) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(listings)
+}
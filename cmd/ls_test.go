@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestListPackages(t *testing.T) {
+	root := t.TempDir()
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{
+			Name:         "web",
+			Type:         "app",
+			Version:      "1.2.0",
+			Dependencies: []string{"utils", "ghost"},
+			Tags:         []string{"frontend"},
+			Owners:       []string{"@org/web"},
+			Private:      true,
+			Path:         filepath.Join(root, "packages", "app", "web", "grit.yaml"),
+		}},
+		{Package: grit.Package{
+			Name:    "utils",
+			Type:    "lib",
+			Version: "0.1.0",
+			Path:    filepath.Join(root, "packages", "lib", "utils", "grit.yaml"),
+		}},
+	})
+
+	listings := listPackages(index.Packages(), index, root)
+	require.Len(t, listings, 2)
+	assert.Equal(t, "app/web", listings[0].ID)
+	assert.Equal(t, "packages/app/web", listings[0].Path)
+	assert.Equal(t, []string{"lib/utils", "ghost"}, listings[0].Dependencies)
+	assert.Equal(t, []string{}, listings[1].Owners)
+
+	rows := packageListingRows(listings)
+	assert.Equal(t, []string{"web", "app", "1.2.0 (private)", "packages/app/web", "2", "@org/web", "frontend"}, rows[0])
+
+	var formatted bytes.Buffer
+	require.NoError(t, writePackageListingsTemplate(&formatted, `{{.ID}} {{len .Dependencies}} {{join .Tags ","}}`, listings))
+	assert.Equal(t, "app/web 2 frontend\nlib/utils 0 \n", formatted.String())
+	assert.Error(t, writePackageListingsTemplate(&formatted, "{{.Missing}}", listings))
+
+	var buf bytes.Buffer
+	require.NoError(t, writePackageListingsJSON(&buf, listings))
+	var decoded []map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &decoded))
+	assert.Equal(t, true, decoded[0]["private"])
+	assert.Equal(t, []interface{}{}, decoded[1]["tags"])
+}
@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var migrateDryRun bool
+
+// Suffix of the copy migrate keeps of each file it changes
+const migrateBackupSuffix = ".bak"
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Upgrade grit.yaml files to the current schema version",
+	Long: fmt.Sprintf(`Upgrade the root and package grit.yaml files to config schema version %d.
+
+Every registered migration step newer than the version of a file is applied in
+order and the file's version field is updated. The original content of each
+changed file is kept next to it with a %s suffix.
+
+Examples:
+  grit migrate             # Migrate all config files
+  grit migrate --dry-run   # Print the changes without writing them`, grit.SchemaVersion, migrateBackupSuffix),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, err := os.Getwd()
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Migrate")
+		formatter.Section("Checking Config Files")
+
+		files, err := planMigration(cwd, formatter)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error migrating workspace: %v", err))
+			os.Exit(1)
+		}
+
+		if len(files) == 0 {
+			formatter.Success(fmt.Sprintf("All config files are at schema version %d", grit.SchemaVersion))
+			return
+		}
+
+		formatter.Section("Config Changes")
+		for _, fix := range files {
+			relPath := relativePath(cwd, fix.path)
+			formatter.Info(fmt.Sprintf("%s: %s", relPath, strings.Join(fix.notes, ", ")))
+			fmt.Print(unifiedDiff(relPath, string(fix.before), string(fix.after)))
+		}
+
+		if migrateDryRun {
+			formatter.NewLine()
+			formatter.Info(fmt.Sprintf("Dry run, %d files would be migrated", len(files)))
+			return
+		}
+
+		formatter.Section("Applying Migrations")
+		for _, fix := range files {
+			backup := fix.path + migrateBackupSuffix
+			if err := os.WriteFile(backup, fix.before, 0644); err != nil {
+				formatter.Error(fmt.Sprintf("Error writing backup %s: %v", backup, err))
+				os.Exit(1)
+			}
+			if err := os.WriteFile(fix.path, fix.after, 0644); err != nil {
+				formatter.Error(fmt.Sprintf("Error writing %s: %v", fix.path, err))
+				os.Exit(1)
+			}
+			formatter.Detail(fmt.Sprintf("%s (backup in %s)", relativePath(cwd, fix.path), relativePath(cwd, backup)))
+		}
+		formatter.Success(fmt.Sprintf("Migrated %d files to schema version %d", len(files), grit.SchemaVersion))
+	},
+}
+
+func init() {
+	migrateCmd.Flags().BoolVar(&migrateDryRun, "dry-run", false, "Print the changes without writing them")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// Migrate the root config and every package config in memory and return the
+// files that change
+func planMigration(cwd string, formatter *output.Formatter) ([]fileFix, error) {
+	var files []fileFix
+
+	rootConfigPath := filepath.Join(cwd, "grit.yaml")
+	// A root config with a package section may be loaded as a package too, as
+	// in a repository created with grit split, and is migrated as both at once
+	kind := grit.RootConfigKind
+	if isPackage, err := hasPackageSection(rootConfigPath); err != nil {
+		return nil, err
+	} else if isPackage {
+		kind = grit.RootPackageConfigKind
+	}
+	rootDoc, fix, err := migrateFile(rootConfigPath, kind)
+	if err != nil {
+		return nil, err
+	}
+	if fix != nil {
+		files = append(files, *fix)
+	}
+
+	// Discover packages with the migrated root config, which may not load as written
+	rootConfig, err := grit.ConfigFromDocument(rootDoc)
+	if err != nil {
+		return nil, err
+	}
+	packages, err := grit.NewPackageManager(cwd).LoadPackagesWithConfig(rootConfig)
+	if err != nil {
+		return nil, err
+	}
+	formatter.Success(fmt.Sprintf("Loaded %d packages", len(packages)))
+
+	for _, cfg := range packages {
+		if isRootPackage(cwd, cfg.Package) {
+			continue
+		}
+		_, fix, err := migrateFile(cfg.Package.Path, grit.PackageConfigKind)
+		if err != nil {
+			return nil, err
+		}
+		if fix != nil {
+			files = append(files, *fix)
+		}
+	}
+	return files, nil
+}
+
+// Whether a config file has a package section
+func hasPackageSection(path string) (bool, error) {
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return doc.Lookup("package") != nil, nil
+}
+
+// Migrate a single config file, returning the migrated document and the change
+// to write, or nil if the file is up to date
+func migrateFile(path string, kind grit.ConfigKind) (*grit.Document, *fileFix, error) {
+	before, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	from, err := doc.SchemaVersion()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	steps, err := grit.Migrate(doc, kind)
+	if err != nil {
+		return nil, nil, err
+	}
+	after, err := doc.Bytes()
+	if err != nil {
+		return nil, nil, err
+	}
+	if string(before) == string(after) {
+		return doc, nil, nil
+	}
+
+	notes := []string{fmt.Sprintf("version %d → %d", from, grit.SchemaVersion)}
+	notes = append(notes, steps...)
+	return doc, &fileFix{path: path, before: before, after: after, notes: notes}, nil
+}
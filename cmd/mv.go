@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+// A planned rename or move of a package
+type movePlan struct {
+	from    grit.Package
+	to      grit.Package
+	updates map[string][]string // Dependency lists to rewrite, keyed by grit.yaml path after the move
+}
+
+var mvCmd = &cobra.Command{
+	Use:   "mv <package> <new-name|type/name>",
+	Short: "Rename a package or move it to another type",
+	Long: `Rename a package, or move it to another type by giving the destination as
+type/name. The package directory is moved to the package_dir of the destination
+type and every package that depends on it is updated to the new name.
+
+The cached build state and the build and coverage output of the package are
+removed, as they were produced under the old name. Moving a package to another
+type is refused if the can_depend_on rules don't allow its dependencies or its
+dependents.
+
+Examples:
+  grit mv utils helpers        # Rename lib/utils to lib/helpers
+  grit mv lib/cli tool/cli     # Move the cli package to the tool type`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		cfg, err := index.Lookup(args[0])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Move")
+
+		plan, err := planMove(cwd, index, rootConfig, cfg, args[1])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Can't move %s: %v", cfg.Package.ID(), err))
+			os.Exit(1)
+		}
+
+		removed, err := applyMove(cwd, rootConfig, plan)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error moving %s: %v", cfg.Package.ID(), err))
+			os.Exit(1)
+		}
+
+		formatter.Detail(fmt.Sprintf("Moved %s to %s", relativePath(cwd, filepath.Dir(plan.from.Path)), relativePath(cwd, filepath.Dir(plan.to.Path))))
+		displayPackageUpdates(cwd, plan.updates, removed, formatter)
+		formatter.Success(fmt.Sprintf("Moved %s to %s", plan.from.ID(), plan.to.ID()))
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(mvCmd)
+}
+
+// Validate the destination of a move and work out the changes it requires
+func planMove(cwd string, index *grit.Index, rootConfig *grit.RootConfig, cfg grit.Config, dest string) (*movePlan, error) {
+	from := cfg.Package
+	if isRootPackage(cwd, from) {
+		return nil, fmt.Errorf("%s is the workspace root, rename it in grit.yaml instead", from.ID())
+	}
+	to := from
+	if typeName, name, found := strings.Cut(dest, "/"); found {
+		to.Type, to.Name = typeName, name
+	} else {
+		to.Name = dest
+	}
+
+	if to.Name == "" || to.Name == "." || to.Name == ".." || strings.ContainsAny(to.Name, `/\`) {
+		return nil, fmt.Errorf("invalid package name %q", to.Name)
+	}
+	if to.ID() == from.ID() {
+		return nil, fmt.Errorf("%s is already named %s", from.ID(), dest)
+	}
+	typeConfig, exists := rootConfig.Types[to.Type]
+	if !exists || typeConfig.PackageDir == "" {
+		return nil, fmt.Errorf("type '%s' does not exist or has no package_dir", to.Type)
+	}
+	if existing, err := index.Lookup(to.ID()); err == nil {
+		return nil, fmt.Errorf("package %s already exists at %s", to.ID(), relativePath(cwd, filepath.Dir(existing.Package.Path)))
+	}
+
+	newDir := filepath.Join(cwd, typeConfig.PackageDir, to.Name)
+	if _, err := os.Stat(newDir); !os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s already exists", relativePath(cwd, newDir))
+	}
+	to.Path = filepath.Join(newDir, filepath.Base(from.Path))
+
+	// The type rules apply to the dependencies and the dependents of the package
+	if to.Type != from.Type {
+		dependencies, dependents := dependencyGraph(index)
+		for _, depID := range dependencies[from.ID()] {
+			dep, _ := index.Lookup(depID)
+			if !rootConfig.CanDependOn(to.Type, dep.Package.Type) {
+				return nil, fmt.Errorf("%w: type %s can't depend on %s", grit.ErrDependencyNotAllowed, to.Type, depID)
+			}
+		}
+		for _, dependentID := range dependents[from.ID()] {
+			dependent, _ := index.Lookup(dependentID)
+			if !rootConfig.CanDependOn(dependent.Package.Type, to.Type) {
+				return nil, fmt.Errorf("%w: %s can't depend on packages of type %s", grit.ErrDependencyNotAllowed, dependentID, to.Type)
+			}
+		}
+	}
+
+	packages := make([]grit.Config, 0, len(index.Packages()))
+	for _, other := range index.Packages() {
+		if other.Package.ID() == from.ID() {
+			other.Package = to
+		}
+		packages = append(packages, other)
+	}
+	newIndex := grit.NewIndex(packages)
+
+	return &movePlan{
+		from:    from,
+		to:      to,
+		updates: updatedDependencies(index, newIndex, map[string]string{from.ID(): to.ID()}),
+	}, nil
+}
+
+// Move the package directory, rename the package and rewrite the dependencies
+// referring to it. Returns the build artifacts that were removed.
+func applyMove(cwd string, rootConfig *grit.RootConfig, plan *movePlan) ([]string, error) {
+	newDir := filepath.Dir(plan.to.Path)
+	if err := os.MkdirAll(filepath.Dir(newDir), 0755); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(filepath.Dir(plan.from.Path), newDir); err != nil {
+		return nil, err
+	}
+
+	if plan.to.Name != plan.from.Name {
+		doc, err := grit.LoadDocument(plan.to.Path)
+		if err != nil {
+			return nil, err
+		}
+		if err := doc.Set([]string{"package", "name"}, plan.to.Name); err != nil {
+			return nil, err
+		}
+		if err := doc.Save(); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writeDependencyUpdates(plan.updates); err != nil {
+		return nil, err
+	}
+	return cleanPackageArtifacts(cwd, rootConfig, plan.from)
+}
+
+// Write the updated dependency lists in a stable order
+func writeDependencyUpdates(updates map[string][]string) error {
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		if err := writeDependencies(path, updates[path]); err != nil {
+			return fmt.Errorf("failed to update %s: %w", path, err)
+		}
+	}
+	return nil
+}
+
+func displayPackageUpdates(cwd string, updates map[string][]string, removed []string, formatter *output.Formatter) {
+	paths := make([]string, 0, len(updates))
+	for path := range updates {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		formatter.Detail(fmt.Sprintf("Updated dependencies in %s", relativePath(cwd, path)))
+	}
+	for _, path := range removed {
+		formatter.Detail(fmt.Sprintf("Removed %s", relativePath(cwd, path)))
+	}
+}
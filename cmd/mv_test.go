@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestUpdatedDependencies(t *testing.T) {
+	oldIndex := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app", Path: "/app/web/grit.yaml", Dependencies: []string{"utils", "core", "ghost"}}},
+		{Package: grit.Package{Name: "utils", Type: "lib", Path: "/lib/utils/grit.yaml"}},
+		{Package: grit.Package{Name: "core", Type: "lib", Path: "/lib/core/grit.yaml", Dependencies: []string{"lib/utils"}}},
+		{Package: grit.Package{Name: "api", Type: "service", Path: "/service/api/grit.yaml", Dependencies: []string{"core"}}},
+	})
+
+	// Renaming utils to helpers rewrites both references to it
+	newIndex := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app", Path: "/app/web/grit.yaml", Dependencies: []string{"utils", "core", "ghost"}}},
+		{Package: grit.Package{Name: "helpers", Type: "lib", Path: "/lib/helpers/grit.yaml"}},
+		{Package: grit.Package{Name: "core", Type: "lib", Path: "/lib/core/grit.yaml", Dependencies: []string{"lib/utils"}}},
+		{Package: grit.Package{Name: "api", Type: "service", Path: "/service/api/grit.yaml", Dependencies: []string{"core"}}},
+	})
+	updates := updatedDependencies(oldIndex, newIndex, map[string]string{"lib/utils": "lib/helpers"})
+	assert.Equal(t, map[string][]string{
+		"/app/web/grit.yaml":  {"helpers", "core", "ghost"},
+		"/lib/core/grit.yaml": {"helpers"},
+	}, updates)
+
+	// Removing core drops the references to it
+	newIndex = grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app", Path: "/app/web/grit.yaml", Dependencies: []string{"utils", "core", "ghost"}}},
+		{Package: grit.Package{Name: "utils", Type: "lib", Path: "/lib/utils/grit.yaml"}},
+		{Package: grit.Package{Name: "api", Type: "service", Path: "/service/api/grit.yaml", Dependencies: []string{"core"}}},
+	})
+	updates = updatedDependencies(oldIndex, newIndex, map[string]string{"lib/core": ""})
+	assert.Equal(t, map[string][]string{
+		"/app/web/grit.yaml":     {"utils", "ghost"},
+		"/service/api/grit.yaml": {},
+	}, updates)
+}
+
+func writeMoveWorkspace(t *testing.T) (string, *grit.Index, *grit.RootConfig) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"packages/lib/utils/grit.yaml": "package:\n  name: utils # shared helpers\n  type: lib\n",
+		"packages/app/web/grit.yaml":   "package:\n  name: web\n  type: app\n  dependencies: [utils]\n",
+		"build/lib/utils/out.txt":      "output",
+	}
+	for path, content := range files {
+		path = filepath.Join(dir, path)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"lib":  {PackageDir: "packages/lib", BuildDir: "build/lib"},
+		"app":  {PackageDir: "packages/app", CanDependOn: []string{"lib"}},
+		"tool": {PackageDir: "packages/tool"},
+	}}
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "utils", Type: "lib", Path: filepath.Join(dir, "packages/lib/utils/grit.yaml")}},
+		{Package: grit.Package{Name: "web", Type: "app", Path: filepath.Join(dir, "packages/app/web/grit.yaml"), Dependencies: []string{"utils"}}},
+	})
+	return dir, index, rootConfig
+}
+
+func TestMovePackage(t *testing.T) {
+	dir, index, rootConfig := writeMoveWorkspace(t)
+	utils, err := index.Lookup("utils")
+	require.NoError(t, err)
+
+	_, err = planMove(dir, index, rootConfig, utils, "web")
+	assert.NoError(t, err, "names only need to be unique within a type")
+	_, err = planMove(dir, index, rootConfig, utils, "app/web")
+	assert.Error(t, err)
+	_, err = planMove(dir, index, rootConfig, utils, "tool/utils")
+	assert.ErrorIs(t, err, grit.ErrDependencyNotAllowed)
+	_, err = planMove(dir, index, rootConfig, utils, "missing/utils")
+	assert.Error(t, err)
+	_, err = planMove(dir, index, rootConfig, utils, "lib/utils")
+	assert.Error(t, err)
+
+	plan, err := planMove(dir, index, rootConfig, utils, "helpers")
+	require.NoError(t, err)
+	removed, err := applyMove(dir, rootConfig, plan)
+	require.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "build/lib/utils")}, removed)
+
+	data, err := os.ReadFile(filepath.Join(dir, "packages/lib/helpers/grit.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "package:\n  name: helpers # shared helpers\n  type: lib\n", string(data))
+	data, err = os.ReadFile(filepath.Join(dir, "packages/app/web/grit.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "package:\n  name: web\n  type: app\n  dependencies: [helpers]\n", string(data))
+	assert.NoDirExists(t, filepath.Join(dir, "packages/lib/utils"))
+}
+
+func TestRemovePackage(t *testing.T) {
+	dir, index, rootConfig := writeMoveWorkspace(t)
+	utils, err := index.Lookup("utils")
+	require.NoError(t, err)
+
+	updates, removed, err := removePackage(dir, index, rootConfig, utils)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{filepath.Join(dir, "packages/app/web/grit.yaml"): {}}, updates)
+	assert.Equal(t, []string{filepath.Join(dir, "build/lib/utils")}, removed)
+	assert.NoDirExists(t, filepath.Join(dir, "packages/lib/utils"))
+
+	data, err := os.ReadFile(filepath.Join(dir, "packages/app/web/grit.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, "package:\n  name: web\n  type: app\n  dependencies: []\n", string(data))
+}
+
+func TestRootPackageIsNotMovedOrRemoved(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "grit.yaml"), []byte("package:\n  name: utils\ntypes:\n  lib:\n    package_dir: .\n"), 0644))
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{"lib": {PackageDir: "."}}}
+	utils := grit.Config{Package: grit.Package{Name: "utils", Type: "lib", Path: filepath.Join(dir, "grit.yaml")}}
+	index := grit.NewIndex([]grit.Config{utils})
+
+	_, err := planMove(dir, index, rootConfig, utils, "helpers")
+	assert.ErrorContains(t, err, "workspace root")
+	_, _, err = removePackage(dir, index, rootConfig, utils)
+	assert.ErrorContains(t, err, "workspace root")
+	assert.FileExists(t, filepath.Join(dir, "grit.yaml"))
+}
@@ -8,7 +8,6 @@ import (
 
 	"github.com/spf13/cobra"
 	"github.com/weslien/grit/pkg/grit"
-	"gopkg.in/yaml.v3"
 )
 
 var newCmd = &cobra.Command{
@@ -20,8 +19,8 @@ var newCmd = &cobra.Command{
 		typeName := args[0]
 		pkgName := args[1]
 
-		// Load root config
-		config, err := loadRootConfig()
+		// Load root config with included types and inheritance applied
+		config, err := grit.LoadConfig("grit.yaml")
 		if err != nil {
 			return fmt.Errorf("failed to load config: %w", err)
 		}
@@ -39,22 +38,17 @@ var newCmd = &cobra.Command{
 		}
 
 		// Create standard package subdirectories
-		subdirs := []string{
-			filepath.Join(pkgDir, "src"),
-			filepath.Join(pkgDir, ".prompt"),
-			filepath.Join(pkgDir, ".mod"),
-			filepath.Join(pkgDir, ".dev"),
-			filepath.Join(pkgDir, ".ops"),
-		}
-
-		for _, dir := range subdirs {
+		for _, subdir := range packageSubdirs {
+			dir := filepath.Join(pkgDir, subdir)
 			if err := os.MkdirAll(dir, 0755); err != nil {
 				return fmt.Errorf("failed to create directory %s: %w", dir, err)
 			}
 		}
 
-		// Create package config file
+		// Create package config file. Targets are inherited from the type and
+		// root config at run time, so only package specific overrides go here.
 		pkgConfig := &grit.Config{
+			Version: grit.SchemaVersion,
 			Package: grit.Package{
 				Name:    pkgName,
 				Version: "0.1.0",
@@ -62,15 +56,8 @@ var newCmd = &cobra.Command{
 			Targets: make(map[string]string),
 		}
 
-		// Copy targets from type config
-		if typeConfig.Targets != nil {
-			for k, v := range typeConfig.Targets {
-				pkgConfig.Targets[k] = v
-			}
-		}
-
 		// Save package config
-		pkgConfigData, err := yaml.Marshal(pkgConfig)
+		pkgConfigData, err := grit.EncodeConfig(pkgConfig)
 		if err != nil {
 			return fmt.Errorf("failed to marshal package config: %w", err)
 		}
@@ -99,15 +86,7 @@ var newTypeCmd = &cobra.Command{
 		}
 
 		// Add new type configuration
-		config.Types[typeName] = grit.TypeConfig{
-			PackageDir:  filepath.Join("packages", typeName),
-			BuildDir:    filepath.Join("build", typeName),
-			CoverageDir: filepath.Join("coverage", typeName),
-			Targets: map[string]string{
-				"build": "echo 'Implement build logic'",
-				"test":  "echo 'Implement test logic'",
-			},
-		}
+		config.Types[typeName] = defaultTypeConfig(typeName)
 
 		// Write updated config
 		if err := saveRootConfig(config); err != nil {
@@ -115,15 +94,7 @@ var newTypeCmd = &cobra.Command{
 		}
 
 		// Create package directories
-		dirs := []string{
-			filepath.Join("packages", typeName),
-			filepath.Join(".prompt", typeName),
-			filepath.Join(".mod", typeName),
-			filepath.Join(".dev", typeName),
-			filepath.Join(".ops", typeName),
-		}
-
-		for _, dir := range dirs {
+		for _, dir := range typeDirs(typeName) {
 			if err := os.MkdirAll(dir, 0755); err != nil {
 				log.Fatal(err)
 			}
@@ -138,25 +109,39 @@ func init() {
 	rootCmd.AddCommand(newCmd)
 }
 
-func loadRootConfig() (*grit.RootConfig, error) {
-	data, err := os.ReadFile("grit.yaml")
-	if err != nil {
-		return &grit.RootConfig{Types: make(map[string]grit.TypeConfig)}, nil
-	}
-	var config grit.RootConfig
-	if err := yaml.Unmarshal(data, &config); err != nil {
-		return nil, err
+// Standard subdirectories of every package
+var packageSubdirs = []string{"src", ".prompt", ".mod", ".dev", ".ops"}
+
+// Configuration for a newly created type
+func defaultTypeConfig(typeName string) grit.TypeConfig {
+	return grit.TypeConfig{
+		PackageDir:  filepath.Join("packages", typeName),
+		BuildDir:    filepath.Join("build", typeName),
+		CoverageDir: filepath.Join("coverage", typeName),
+		Targets: map[string]string{
+			"build": "echo 'Implement build logic'",
+			"test":  "echo 'Implement test logic'",
+		},
 	}
-	if config.Types == nil {
-		config.Types = make(map[string]grit.TypeConfig)
+}
+
+// Workspace level directories of a type
+func typeDirs(typeName string) []string {
+	return []string{
+		filepath.Join("packages", typeName),
+		filepath.Join(".prompt", typeName),
+		filepath.Join(".mod", typeName),
+		filepath.Join(".dev", typeName),
+		filepath.Join(".ops", typeName),
 	}
-	return &config, nil
 }
 
+// Load the root config as written so it can be saved back
+func loadRootConfig() (*grit.RootConfig, error) {
+	return grit.LoadRawConfig("grit.yaml")
+}
+
+// Merge the root config into grit.yaml, preserving comments and formatting
 func saveRootConfig(config *grit.RootConfig) error {
-	data, err := yaml.Marshal(config)
-	if err != nil {
-		return err
-	}
-	return os.WriteFile("grit.yaml", data, 0644)
+	return grit.SaveConfig(config, "grit.yaml")
 }
@@ -0,0 +1,289 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+// Join [type] [name] arguments into a package reference. A single argument is
+// used as is, so both "utils" and "lib/utils" are accepted.
+func packageRefFromArgs(args []string) string {
+	return strings.Join(args, "/")
+}
+
+// Path of the cached build hash for a package, keyed by type/name so packages
+// with the same name in different types don't share a cache entry
+func packageCacheFile(cacheDir string, pkg grit.Package) string {
+	return filepath.Join(cacheDir, filepath.FromSlash(pkg.ID())+".hash")
+}
+
+// Outcome of the last build of a package, stored next to its cached hash
+type buildRecord struct {
+	Success  bool          `json:"success"`
+	Time     time.Time     `json:"time"`
+	Duration time.Duration `json:"duration"`
+	Hash     string        `json:"hash,omitempty"`
+	Error    string        `json:"error,omitempty"`
+}
+
+func packageBuildRecordFile(cacheDir string, pkg grit.Package) string {
+	return filepath.Join(cacheDir, filepath.FromSlash(pkg.ID())+".build.json")
+}
+
+func saveBuildRecord(cacheDir string, pkg grit.Package, record buildRecord) error {
+	path := packageBuildRecordFile(cacheDir, pkg)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(record, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// Load the last build record of a package, nil if it was never built
+func loadBuildRecord(cacheDir string, pkg grit.Package) (*buildRecord, error) {
+	data, err := os.ReadFile(packageBuildRecordFile(cacheDir, pkg))
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var record buildRecord
+	if err := json.Unmarshal(data, &record); err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+// Collect the referenced package and everything it depends on
+func selectPackageWithDependencies(index *grit.Index, ref string) ([]grit.Config, error) {
+	root, err := index.Lookup(ref)
+	if err != nil {
+		return nil, err
+	}
+	return withDependencies(index, []grit.Config{root}), nil
+}
+
+// Collect the given packages and everything they depend on
+func withDependencies(index *grit.Index, roots []grit.Config) []grit.Config {
+	var selected []grit.Config
+	visited := make(map[string]bool)
+	var visit func(cfg grit.Config)
+	visit = func(cfg grit.Config) {
+		if visited[cfg.Package.ID()] {
+			return
+		}
+		visited[cfg.Package.ID()] = true
+		selected = append(selected, cfg)
+
+		depIDs, _ := index.Dependencies(cfg.Package)
+		for _, depID := range depIDs {
+			dep, err := index.Lookup(depID)
+			if err == nil {
+				visit(dep)
+			}
+		}
+	}
+	for _, root := range roots {
+		visit(root)
+	}
+
+	return selected
+}
+
+// Select the packages matching any of the filter expressions
+func filterPackages(index *grit.Index, exprs []string) ([]grit.Config, error) {
+	filters, err := grit.ParseFilters(exprs)
+	if err != nil {
+		return nil, err
+	}
+	return grit.FilterPackages(index.Packages(), filters), nil
+}
+
+// Load the root config and index the packages of the workspace in the current
+// directory, exiting on failure
+func loadWorkspaceIndex(formatter *output.Formatter) (string, *grit.Index, *grit.RootConfig) {
+	cwd, err := os.Getwd()
+	if err != nil {
+		formatter.Error(fmt.Sprintf("Error getting current directory: %v", err))
+		os.Exit(1)
+	}
+
+	rootConfig, err := grit.LoadConfig(filepath.Join(cwd, "grit.yaml"))
+	if err != nil {
+		formatter.Error(fmt.Sprintf("Error loading root config: %v", err))
+		os.Exit(1)
+	}
+
+	packages, err := grit.NewPackageManager(cwd).LoadPackagesWithConfig(rootConfig)
+	if err != nil {
+		formatter.Error(fmt.Sprintf("Error loading packages: %v", err))
+		os.Exit(1)
+	}
+	return cwd, grit.NewIndex(packages), rootConfig
+}
+
+// The build and coverage output directories of a package
+func packageOutputDirs(cwd string, rootConfig *grit.RootConfig, pkg grit.Package) []string {
+	typeConfig := rootConfig.Types[pkg.Type]
+	var dirs []string
+	if typeConfig.BuildDir != "" {
+		dirs = append(dirs, filepath.Join(cwd, typeConfig.BuildDir, pkg.Name))
+	}
+	if typeConfig.CoverageDir != "" {
+		dirs = append(dirs, filepath.Join(cwd, typeConfig.CoverageDir, pkg.Name))
+	}
+	return dirs
+}
+
+// Remove the cached hash, the build record and the build and coverage output of
+// a package. Returns the paths that were removed.
+func cleanPackageArtifacts(cwd string, rootConfig *grit.RootConfig, pkg grit.Package) ([]string, error) {
+	cacheDir := filepath.Join(cwd, ".grit", "cache")
+	paths := append([]string{packageCacheFile(cacheDir, pkg), packageBuildRecordFile(cacheDir, pkg)},
+		packageOutputDirs(cwd, rootConfig, pkg)...)
+
+	var removed []string
+	for _, path := range paths {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			continue
+		}
+		if err := os.RemoveAll(path); err != nil {
+			return removed, err
+		}
+		removed = append(removed, path)
+	}
+	return removed, nil
+}
+
+// Work out the dependency lists that change when the workspace goes from the
+// packages of oldIndex to those of newIndex. renamed maps old package IDs to new
+// ones, with "" for packages that were removed; references to removed packages
+// are dropped. References that would no longer resolve to the same package are
+// rewritten to the shortest unambiguous name. The result is keyed by the path of
+// the package's grit.yaml in the new workspace.
+func updatedDependencies(oldIndex *grit.Index, newIndex *grit.Index, renamed map[string]string) map[string][]string {
+	mapID := func(id string) string {
+		if newID, ok := renamed[id]; ok {
+			return newID
+		}
+		return id
+	}
+
+	updates := make(map[string][]string)
+	for _, oldCfg := range oldIndex.Packages() {
+		newID := mapID(oldCfg.Package.ID())
+		if newID == "" {
+			continue
+		}
+		newCfg, err := newIndex.Lookup(newID)
+		if err != nil {
+			continue
+		}
+
+		changed := false
+		deps := make([]string, 0, len(oldCfg.Package.Dependencies))
+		for _, ref := range oldCfg.Package.Dependencies {
+			dep, err := oldIndex.Lookup(ref)
+			if err != nil {
+				deps = append(deps, ref) // Keep references that were already broken
+				continue
+			}
+			target := mapID(dep.Package.ID())
+			if target == "" {
+				changed = true
+				continue
+			}
+			if resolved, err := newIndex.Lookup(ref); err == nil && resolved.Package.ID() == target {
+				deps = append(deps, ref)
+				continue
+			}
+			targetCfg, err := newIndex.Lookup(target)
+			if err != nil {
+				deps = append(deps, ref)
+				continue
+			}
+			deps = append(deps, newIndex.Name(targetCfg.Package))
+			changed = true
+		}
+		if changed {
+			updates[newCfg.Package.Path] = deps
+		}
+	}
+	return updates
+}
+
+// Whether a package is the workspace root itself, whose grit.yaml is the root
+// config as well, as in a repository created with grit split
+func isRootPackage(cwd string, pkg grit.Package) bool {
+	return filepath.Dir(pkg.Path) == filepath.Clean(cwd)
+}
+
+// Find the package a file belongs to. Packages can be nested, so the package
+// with the deepest directory containing the file wins.
+func packageForPath(index *grit.Index, path string) (grit.Config, bool) {
+	var owner grit.Config
+	found := false
+	for _, cfg := range index.Packages() {
+		dir := filepath.Dir(cfg.Package.Path)
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			continue
+		}
+		if !found || len(dir) > len(filepath.Dir(owner.Package.Path)) {
+			owner, found = cfg, true
+		}
+	}
+	return owner, found
+}
+
+// The packages with files that changed since HEAD branched off the base revision
+func affectedPackages(cwd string, index *grit.Index, base string) ([]grit.Config, error) {
+	files, err := changedFilesSince(cwd, base)
+	if err != nil {
+		return nil, err
+	}
+
+	var affected []grit.Config
+	seen := make(map[string]bool)
+	for _, file := range files {
+		cfg, ok := packageForPath(index, filepath.Join(cwd, filepath.FromSlash(file)))
+		if !ok || seen[cfg.Package.ID()] {
+			continue
+		}
+		seen[cfg.Package.ID()] = true
+		affected = append(affected, cfg)
+	}
+	return affected, nil
+}
+
+// Select the referenced packages, or all packages when there are no references,
+// that match any of the filter expressions
+func selectPackages(index *grit.Index, refs []string, exprs []string) ([]grit.Config, error) {
+	filters, err := grit.ParseFilters(exprs)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) == 0 {
+		return grit.FilterPackages(index.Packages(), filters), nil
+	}
+
+	selected := make([]grit.Config, 0, len(refs))
+	for _, ref := range refs {
+		cfg, err := index.Lookup(ref)
+		if err != nil {
+			return nil, err
+		}
+		selected = append(selected, cfg)
+	}
+	return grit.FilterPackages(selected, filters), nil
+}
@@ -0,0 +1,275 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	releaseFilters   []string
+	releaseAffected  bool
+	releaseBase      string
+	releaseDryRun    bool
+	releaseNoPublish bool
+	releaseInitial   bool
+)
+
+// A planned release of a package
+type releasePlan struct {
+	cfg     grit.Config
+	name    string // Name the package is referred to by in tags and commits
+	from    string
+	to      string
+	tag     string
+	commits []grit.ConventionalCommit // Conventional commits since the last release
+	changes []grit.ConventionalCommit // Those not in the changelog yet
+}
+
+var releaseCmd = &cobra.Command{
+	Use:   "release [package]...",
+	Short: "Version, tag and publish packages",
+	Long: `Release packages in dependency order. For each package:
+
+  1. The next version is worked out from the conventional commits made since
+     the tag of its current version: major for breaking changes, minor for
+     features and patch for fixes and other changes listed in changelogs. For
+     a package whose current version was never tagged, every commit of the
+     package counts, unless --initial releases that version as is.
+  2. The version in grit.yaml is updated and the commits are added to its
+     CHANGELOG.md, in a section for the new version that the entries of its
+     Unreleased section move into, and both are committed.
+  3. The release is tagged <name>@<version>, or <type>/<name>@<version> when
+     packages of several types share the name.
+  4. The publish target of the package is run, unless the package is private.
+
+Packages without commits that call for a release are skipped, unless they are
+released as is with --initial.
+
+Examples:
+  grit release                          # Release every package with changes
+  grit release utils web --dry-run      # Show what would be released
+  grit release --affected --base main   # Packages changed since main
+  grit release --initial                # First release of untagged packages
+  grit release --filter type:lib --no-publish`,
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+
+		var packages []grit.Config
+		var err error
+		if releaseAffected {
+			packages, err = affectedPackages(cwd, index, releaseBase)
+			if err == nil {
+				var filters []*grit.Filter
+				filters, err = grit.ParseFilters(releaseFilters)
+				packages = grit.FilterPackages(packages, filters)
+			}
+		} else {
+			packages, err = selectPackages(index, args, releaseFilters)
+		}
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting packages: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Release")
+
+		ordered, err := resolveDependencies(packages, index, formatter)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error ordering packages: %v", err))
+			os.Exit(1)
+		}
+
+		var plans []*releasePlan
+		for _, cfg := range ordered {
+			plan, err := planRelease(cwd, index, rootConfig.Commit, cfg, releaseInitial)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Can't release %s: %v", cfg.Package.ID(), err))
+				os.Exit(1)
+			}
+			if plan != nil {
+				plans = append(plans, plan)
+			}
+		}
+
+		if len(plans) == 0 {
+			formatter.Info("Nothing to release")
+			return
+		}
+
+		rows := make([][]string, 0, len(plans))
+		for _, plan := range plans {
+			rows = append(rows, []string{plan.cfg.Package.ID(), plan.from, plan.to, plan.tag, strconv.Itoa(len(plan.commits))})
+		}
+		formatter.Table([]string{"PACKAGE", "FROM", "TO", "TAG", "COMMITS"}, rows)
+
+		if releaseDryRun {
+			formatter.Info(fmt.Sprintf("Dry run, %d packages would be released", len(plans)))
+			return
+		}
+
+		for _, plan := range plans {
+			formatter.Section(fmt.Sprintf("Releasing %s %s", plan.cfg.Package.ID(), plan.to))
+			if err := applyRelease(cwd, plan); err != nil {
+				formatter.Error(fmt.Sprintf("Error releasing %s: %v", plan.cfg.Package.ID(), err))
+				os.Exit(1)
+			}
+			formatter.Detail(fmt.Sprintf("Tagged %s", plan.tag))
+
+			if releaseNoPublish {
+				continue
+			}
+			if plan.cfg.Package.Private {
+				formatter.Detail("Private package, not published")
+				continue
+			}
+			cfg := plan.cfg
+			cfg.Package.Version = plan.to
+			if err := runPackageTarget(cfg, rootConfig, "publish", cwd, formatter); errors.Is(err, errTargetNotDefined) {
+				formatter.Detail("No publish target defined")
+			} else if err != nil {
+				formatter.Error(fmt.Sprintf("Error publishing %s: %v", plan.cfg.Package.ID(), err))
+				formatter.Detail(fmt.Sprintf("The release is tagged %s; run its publish target again once fixed", plan.tag))
+				os.Exit(1)
+			}
+		}
+
+		formatter.Success(fmt.Sprintf("Released %d packages", len(plans)))
+	},
+}
+
+func init() {
+	releaseCmd.Flags().StringArrayVar(&releaseFilters, "filter", nil, "Only release packages matching the filter expression (repeatable)")
+	releaseCmd.Flags().BoolVar(&releaseAffected, "affected", false, "Release the packages changed since the base revision")
+	releaseCmd.Flags().StringVar(&releaseBase, "base", "HEAD", "Revision to compare against with --affected")
+	releaseCmd.Flags().BoolVar(&releaseDryRun, "dry-run", false, "Show what would be released without changing anything")
+	releaseCmd.Flags().BoolVar(&releaseNoPublish, "no-publish", false, "Don't run the publish targets")
+	releaseCmd.Flags().BoolVar(&releaseInitial, "initial", false, "Release packages whose current version was never tagged at that version")
+	rootCmd.AddCommand(releaseCmd)
+}
+
+// The git tag of a package release, where name is the name the index refers to
+// the package by
+func releaseTag(name string, version string) string {
+	return name + "@" + version
+}
+
+func gitTagExists(cwd string, tag string) bool {
+	_, err := runGit(cwd, "rev-parse", "-q", "--verify", "refs/tags/"+tag)
+	return err == nil
+}
+
+// The more recent of two revisions, where one is expected to be an ancestor of
+// the other. Empty revisions are ignored.
+func laterRevision(cwd string, a string, b string) string {
+	if a == "" {
+		return b
+	}
+	if b == "" {
+		return a
+	}
+	if _, err := runGit(cwd, "merge-base", "--is-ancestor", a, b); err == nil {
+		return b
+	}
+	return a
+}
+
+// Work out the next release of a package from the commits since its last
+// release, or from all its commits when its current version was never tagged.
+// With initial, an untagged version is released as is. Returns nil when there
+// is nothing to release.
+func planRelease(cwd string, index *grit.Index, commitConfig grit.CommitConfig, cfg grit.Config, initial bool) (*releasePlan, error) {
+	plan := &releasePlan{cfg: cfg, name: index.Name(cfg.Package), from: cfg.Package.Version}
+	pkgDir := filepath.Dir(cfg.Package.Path)
+
+	since := releaseTag(plan.name, plan.from)
+	if !gitTagExists(cwd, since) {
+		since = ""
+	}
+
+	var err error
+	if plan.commits, err = packageCommits(cwd, commitConfig, cfg.Package, since); err != nil {
+		return nil, err
+	}
+
+	// Commits already added with grit changelog are not added again
+	changelogCommit, err := lastChangelogCommit(cwd, pkgDir)
+	if err != nil {
+		return nil, err
+	}
+	if changesSince := laterRevision(cwd, since, changelogCommit); changesSince == since {
+		plan.changes = plan.commits
+	} else if plan.changes, err = packageCommits(cwd, commitConfig, cfg.Package, changesSince); err != nil {
+		return nil, err
+	}
+
+	plan.to = plan.from
+	if since != "" || !initial {
+		level := grit.ReleaseLevel(plan.commits)
+		if level == "" {
+			return nil, nil
+		}
+		if plan.to, err = grit.NextVersion(plan.from, level); err != nil {
+			return nil, err
+		}
+	} else if _, err := grit.ParseSemver(plan.from); err != nil {
+		return nil, err
+	}
+
+	plan.tag = releaseTag(plan.name, plan.to)
+	if gitTagExists(cwd, plan.tag) {
+		return nil, fmt.Errorf("tag %s already exists", plan.tag)
+	}
+	return plan, nil
+}
+
+// Update the version and changelog of a package, commit them and tag the release
+func applyRelease(cwd string, plan *releasePlan) error {
+	pkgDir := filepath.Dir(plan.cfg.Package.Path)
+	paths := []string{plan.cfg.Package.Path}
+
+	if plan.to != plan.from {
+		change := versionChange{pkg: plan.cfg.Package, from: plan.from, to: plan.to}
+		if err := writeVersions([]versionChange{change}); err != nil {
+			return err
+		}
+	}
+	changes, err := unlistedCommits(pkgDir, plan.changes)
+	if err != nil {
+		return err
+	}
+	// Changes collected in the Unreleased section are released as well
+	updated, err := updateChangelog(pkgDir, plan.to, changes)
+	if err != nil {
+		return err
+	}
+	if updated {
+		paths = append(paths, filepath.Join(pkgDir, grit.ChangelogFileName))
+	}
+
+	if _, err := runGit(cwd, append([]string{"add", "--"}, paths...)...); err != nil {
+		return err
+	}
+	staged, err := runGit(cwd, append([]string{"diff", "--cached", "--name-only", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	if strings.TrimSpace(staged) != "" {
+		message := fmt.Sprintf("%s: chore(release): %s", plan.name, plan.to)
+		if _, err := runGit(cwd, append([]string{"commit", "-q", "-m", message, "--"}, paths...)...); err != nil {
+			return err
+		}
+	}
+
+	_, err = runGit(cwd, "tag", "-a", plan.tag, "-m", fmt.Sprintf("%s %s", plan.name, plan.to))
+	return err
+}
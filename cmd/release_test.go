@@ -0,0 +1,85 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestRelease(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "utils: feat: add utils", map[string]string{
+		"packages/lib/utils/grit.yaml": "package:\n  name: utils\n  version: 0.1.0\n",
+	})
+
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{"lib": {PackageDir: "packages/lib"}}}
+	load := func() (*grit.Index, grit.Config) {
+		packages, err := grit.NewPackageManager(dir).LoadPackagesWithConfig(rootConfig)
+		require.NoError(t, err)
+		index := grit.NewIndex(packages)
+		cfg, err := index.Lookup("lib/utils")
+		require.NoError(t, err)
+		return index, cfg
+	}
+
+	// The commits of an untagged version decide its release
+	commitFiles(t, dir, "chore: add readme", map[string]string{"packages/lib/utils/README.md": "# Utils"})
+	index, cfg := load()
+	plan, err := planRelease(dir, index, rootConfig.Commit, cfg, false)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	assert.Equal(t, "0.2.0", plan.to)
+
+	// With initial, an untagged version is released as is
+	plan, err = planRelease(dir, index, rootConfig.Commit, cfg, true)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	assert.Equal(t, "0.1.0", plan.to)
+	assert.Equal(t, "utils@0.1.0", plan.tag)
+	require.NoError(t, applyRelease(dir, plan))
+	assert.True(t, gitTagExists(dir, "utils@0.1.0"))
+
+	index, cfg = load()
+	plan, err = planRelease(dir, index, rootConfig.Commit, cfg, false)
+	require.NoError(t, err)
+	assert.Nil(t, plan, "nothing changed since the release")
+
+	// Features since the last release call for a minor release
+	commitFiles(t, dir, "chore: tidy", map[string]string{"packages/lib/utils/a.txt": "a"})
+	commitFiles(t, dir, "utils: feat: add retries", map[string]string{"packages/lib/utils/b.txt": "b"})
+	index, cfg = load()
+	plan, err = planRelease(dir, index, rootConfig.Commit, cfg, false)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	assert.Equal(t, "0.2.0", plan.to)
+	assert.Len(t, plan.commits, 2)
+	require.NoError(t, applyRelease(dir, plan))
+
+	index, cfg = load()
+	assert.Equal(t, "0.2.0", cfg.Package.Version)
+	assert.Equal(t, "utils: chore(release): 0.2.0", strings.TrimSpace(git(t, dir, "log", "-1", "--format=%s", "utils@0.2.0")))
+	changelog, err := os.ReadFile(filepath.Join(dir, "packages", "lib", "utils", "CHANGELOG.md"))
+	require.NoError(t, err)
+	assert.Contains(t, string(changelog), "## 0.2.0")
+	assert.Contains(t, string(changelog), "- add retries")
+	assert.Equal(t, 1, strings.Count(string(changelog), "- add utils"), "released commits are not added again")
+
+	plan, err = planRelease(dir, index, rootConfig.Commit, cfg, true)
+	require.NoError(t, err)
+	assert.Nil(t, plan, "a tagged version is not released again with initial")
+
+	// Once another type has a package of the same name, tags use the ID
+	commitFiles(t, dir, "add app utils", map[string]string{"packages/app/utils/grit.yaml": "package:\n  name: utils\n  version: 1.0.0\n"})
+	rootConfig.Types["app"] = grit.TypeConfig{PackageDir: "packages/app"}
+	index, cfg = load()
+	assert.Equal(t, "lib/utils", index.Name(cfg.Package))
+	plan, err = planRelease(dir, index, rootConfig.Commit, cfg, true)
+	require.NoError(t, err)
+	require.NotNil(t, plan)
+	assert.Equal(t, "lib/utils@0.2.0", plan.tag)
+}
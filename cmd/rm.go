@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var rmForce bool
+
+var rmCmd = &cobra.Command{
+	Use:   "rm <package>",
+	Short: "Delete a package",
+	Long: `Delete a package directory together with its cached build state and its
+build and coverage output.
+
+A package that other packages depend on is only deleted with --force, in which
+case the dependency on it is removed from each of them.
+
+Examples:
+  grit rm utils            # Delete lib/utils if nothing depends on it
+  grit rm lib/utils --force`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		cfg, err := index.Lookup(args[0])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+			os.Exit(1)
+		}
+
+		formatter.Header("GRIT Remove")
+
+		_, dependents := dependencyGraph(index)
+		if len(dependents[cfg.Package.ID()]) > 0 && !rmForce {
+			formatter.Error(fmt.Sprintf("Can't remove %s, it is a dependency of %s", cfg.Package.ID(), strings.Join(dependents[cfg.Package.ID()], ", ")))
+			formatter.Detail("Use --force to remove it and the dependencies on it")
+			os.Exit(1)
+		}
+
+		updates, removed, err := removePackage(cwd, index, rootConfig, cfg)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error removing %s: %v", cfg.Package.ID(), err))
+			os.Exit(1)
+		}
+
+		formatter.Detail(fmt.Sprintf("Removed %s", relativePath(cwd, filepath.Dir(cfg.Package.Path))))
+		displayPackageUpdates(cwd, updates, removed, formatter)
+		formatter.Success(fmt.Sprintf("Removed %s", cfg.Package.ID()))
+	},
+}
+
+func init() {
+	rmCmd.Flags().BoolVarP(&rmForce, "force", "f", false, "Remove the package even if other packages depend on it")
+	rootCmd.AddCommand(rmCmd)
+}
+
+// Delete the package directory and its build artifacts and drop the dependencies
+// on it. Returns the dependency lists that were rewritten and the artifacts that
+// were removed.
+func removePackage(cwd string, index *grit.Index, rootConfig *grit.RootConfig, cfg grit.Config) (map[string][]string, []string, error) {
+	if isRootPackage(cwd, cfg.Package) {
+		return nil, nil, fmt.Errorf("%s is the workspace root, removing it would remove the workspace", cfg.Package.ID())
+	}
+
+	var remaining []grit.Config
+	for _, other := range index.Packages() {
+		if other.Package.ID() != cfg.Package.ID() {
+			remaining = append(remaining, other)
+		}
+	}
+	updates := updatedDependencies(index, grit.NewIndex(remaining), map[string]string{cfg.Package.ID(): ""})
+
+	if err := os.RemoveAll(filepath.Dir(cfg.Package.Path)); err != nil {
+		return nil, nil, err
+	}
+	if err := writeDependencyUpdates(updates); err != nil {
+		return nil, nil, err
+	}
+	removed, err := cleanPackageArtifacts(cwd, rootConfig, cfg.Package)
+	return updates, removed, err
+}
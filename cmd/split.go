@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var splitBranch string
+
+// Temporary ref the split history is fetched from
+const splitRef = "refs/grit/split"
+
+var splitCmd = &cobra.Command{
+	Use:   "split <package> <dest>",
+	Short: "Split a package out into a repository of its own",
+	Long: `Create a new git repository at dest holding only a package, with the history
+of its directory.
+
+Each commit of HEAD that changed the package directory is rewritten so the
+package is the root of the repository, keeping its author, date and message.
+Commits that didn't change the package are left out. The package's grit.yaml is
+then turned into the root config of the new repository in a final commit: the
+targets the package inherits from its type and the root config are copied in,
+its type is defined with the repository root as package_dir, so the package is
+loaded from the root grit.yaml, and its dependencies on other packages are
+removed.
+
+Only committed changes are split, and history from before the package was
+moved to its current directory is not included. The monorepo is not changed.
+
+Examples:
+  grit split utils ../utils          # Split lib/utils into ../utils
+  grit split lib/utils ../utils --branch master`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, rootConfig := loadWorkspaceIndex(formatter)
+		formatter.Header("GRIT Split")
+
+		cfg, err := index.Lookup(args[0])
+		if err != nil {
+			formatter.Error(err.Error())
+			os.Exit(1)
+		}
+		if isRootPackage(cwd, cfg.Package) {
+			formatter.Error(fmt.Sprintf("%s is the workspace root, there is nothing to split", cfg.Package.ID()))
+			os.Exit(1)
+		}
+		dest, err := filepath.Abs(args[1])
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Invalid destination: %v", err))
+			os.Exit(1)
+		}
+		if entries, err := os.ReadDir(dest); err == nil && len(entries) > 0 {
+			formatter.Error(fmt.Sprintf("%s already exists and is not empty", dest))
+			os.Exit(1)
+		}
+
+		pkgName := index.Name(cfg.Package)
+		root, dir, err := packageRepoDir(cwd, cfg)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error finding the package in the repository: %v", err))
+			os.Exit(1)
+		}
+		if status, err := runGit(root, "status", "--porcelain", "--", dir); err == nil && status != "" {
+			formatter.Warning(fmt.Sprintf("%s has uncommitted changes, which are not included", pkgName))
+		}
+
+		formatter.Section("Rewriting History")
+		tip, count, err := splitHistory(root, "HEAD", dir)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Error rewriting the history of %s: %v", pkgName, err))
+			os.Exit(1)
+		}
+		formatter.Info(fmt.Sprintf("Rewrote %d commits of %s", count, dir))
+
+		notes, err := createSplitRepo(root, tip, dest, splitBranch, rootConfig, cfg)
+		if err != nil {
+			os.RemoveAll(dest)
+			formatter.Error(fmt.Sprintf("Error creating %s: %v", dest, err))
+			os.Exit(1)
+		}
+		for _, note := range notes {
+			formatter.Warning(fmt.Sprintf("grit.yaml: %s", note))
+		}
+		formatter.Success(fmt.Sprintf("Split %s into %s", pkgName, dest))
+	},
+}
+
+func init() {
+	splitCmd.Flags().StringVar(&splitBranch, "branch", "main", "Branch of the new repository")
+	rootCmd.AddCommand(splitCmd)
+}
+
+// The root of the repository and the directory of a package relative to it,
+// with forward slashes
+func packageRepoDir(cwd string, cfg grit.Config) (string, string, error) {
+	root, err := runGit(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", "", err
+	}
+	prefix, err := runGit(cwd, "rev-parse", "--show-prefix")
+	if err != nil {
+		return "", "", err
+	}
+	rel := relativePath(cwd, filepath.Dir(cfg.Package.Path))
+	return strings.TrimSpace(root), strings.TrimSpace(prefix) + filepath.ToSlash(rel), nil
+}
+
+// Rewrite the commits of rev that changed dir into commits holding the content
+// of dir at their root, with the same authors, dates and messages. The new
+// commits are written to the repository but not referenced. Returns the new tip
+// and the number of commits written.
+func splitHistory(root string, rev string, dir string) (string, int, error) {
+	// With a path, rev-list only lists the commits that changed it and rewrites
+	// their parents to skip the others
+	out, err := runGit(root, "rev-list", "--reverse", "--topo-order", "--parents", rev, "--", dir)
+	if err != nil {
+		return "", 0, err
+	}
+	emptyTree, err := runGit(root, "mktree")
+	if err != nil {
+		return "", 0, err
+	}
+	emptyTree = strings.TrimSpace(emptyTree)
+
+	rewritten := make(map[string]string) // Old commit to new commit
+	trees := make(map[string]string)     // New commit to its tree
+	tip, count := "", 0
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		commit := fields[0]
+
+		tree := emptyTree
+		entry, err := runGit(root, "--literal-pathspecs", "ls-tree", "-z", commit, "--", dir)
+		if err != nil {
+			return "", 0, err
+		}
+		// The entry is "<mode> tree <hash>\t<path>" while the directory exists
+		if fields := strings.Fields(strings.SplitN(entry, "\t", 2)[0]); len(fields) == 3 && fields[1] == "tree" {
+			tree = fields[2]
+		}
+
+		var parents []string
+		for _, parent := range fields[1:] {
+			if mapped, ok := rewritten[parent]; ok && !containsString(parents, mapped) {
+				parents = append(parents, mapped)
+			}
+		}
+		// Leave out commits that don't change the content, like merges of
+		// branches that didn't touch it
+		if len(parents) == 1 && trees[parents[0]] == tree {
+			rewritten[commit] = parents[0]
+			tip = parents[0]
+			continue
+		}
+		if len(parents) == 0 && tree == emptyTree {
+			continue
+		}
+
+		newCommit, err := rewriteCommit(root, commit, tree, parents)
+		if err != nil {
+			return "", 0, err
+		}
+		rewritten[commit] = newCommit
+		trees[newCommit] = tree
+		tip = newCommit
+		count++
+	}
+	if tip == "" {
+		return "", 0, fmt.Errorf("%s has no committed history", dir)
+	}
+	return tip, count, nil
+}
+
+// Write a copy of a commit with another tree and parents
+func rewriteCommit(root string, commit string, tree string, parents []string) (string, error) {
+	raw, err := runGit(root, "cat-file", "commit", commit)
+	if err != nil {
+		return "", err
+	}
+	headers, message, _ := strings.Cut(raw, "\n\n")
+
+	var env []string
+	for _, header := range strings.Split(headers, "\n") {
+		role, ident, _ := strings.Cut(header, " ")
+		if role != "author" && role != "committer" {
+			continue
+		}
+		name, email, date, ok := parseIdent(ident)
+		if !ok {
+			return "", fmt.Errorf("commit %s has a malformed %s", commit, role)
+		}
+		prefix := "GIT_" + strings.ToUpper(role)
+		env = append(env, prefix+"_NAME="+name, prefix+"_EMAIL="+email, prefix+"_DATE="+date)
+	}
+
+	args := []string{"commit-tree", tree}
+	for _, parent := range parents {
+		args = append(args, "-p", parent)
+	}
+	out, err := runGitEnv(root, env, message, args...)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(out), nil
+}
+
+// Split an identity of a commit header like "Jane Doe <jane@example.com>
+// 1700000000 +0100" into name, email and date
+func parseIdent(ident string) (string, string, string, bool) {
+	start := strings.LastIndex(ident, " <")
+	end := strings.LastIndex(ident, "> ")
+	if start < 0 || end < start {
+		return "", "", "", false
+	}
+	return ident[:start], ident[start+2 : end], ident[end+2:], true
+}
+
+// Create the repository at dest with the split history on branch and turn the
+// package's grit.yaml into its root config. Returns the notes of the config
+// conversion.
+func createSplitRepo(root string, tip string, dest string, branch string, rootConfig *grit.RootConfig, cfg grit.Config) ([]string, error) {
+	if err := os.MkdirAll(dest, 0755); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(dest, "init", "-q"); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(dest, "symbolic-ref", "HEAD", "refs/heads/"+branch); err != nil {
+		return nil, err
+	}
+
+	// The new commits aren't referenced, so fetch them through a temporary ref
+	if _, err := runGit(root, "update-ref", splitRef, tip); err != nil {
+		return nil, err
+	}
+	defer runGit(root, "update-ref", "-d", splitRef)
+	if _, err := runGit(dest, "fetch", "-q", "--no-tags", "--update-head-ok", root, splitRef+":refs/heads/"+branch); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(dest, "reset", "-q", "--hard"); err != nil {
+		return nil, err
+	}
+
+	doc, err := grit.LoadDocument(filepath.Join(dest, "grit.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	notes, err := rootConfig.StandaloneConfig(doc, cfg)
+	if err != nil {
+		return nil, err
+	}
+	if err := doc.Save(); err != nil {
+		return nil, err
+	}
+	if _, err := runGit(dest, "add", "grit.yaml"); err != nil {
+		return nil, err
+	}
+	if changed, err := hasStagedChanges(dest, []string{"grit.yaml"}); err != nil || !changed {
+		return notes, err
+	}
+	// Commit as the user of the monorepo, whose identity may be configured there
+	env, err := identityEnv(root)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := runGitEnv(dest, env, "", "commit", "-q", "-m", "chore: turn grit.yaml into a standalone root config"); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// The environment variables for git to commit as the author and committer
+// configured in a repository
+func identityEnv(root string) ([]string, error) {
+	var env []string
+	for _, role := range []string{"AUTHOR", "COMMITTER"} {
+		ident, err := runGit(root, "var", "GIT_"+role+"_IDENT")
+		if err != nil {
+			return nil, err
+		}
+		name, email, _, ok := parseIdent(strings.TrimSpace(ident))
+		if !ok {
+			return nil, fmt.Errorf("malformed %s identity %q", strings.ToLower(role), ident)
+		}
+		env = append(env, "GIT_"+role+"_NAME="+name, "GIT_"+role+"_EMAIL="+email)
+	}
+	return env, nil
+}
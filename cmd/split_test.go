@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+func TestParseIdent(t *testing.T) {
+	name, email, date, ok := parseIdent("Jane Q. Doe <jane@example.com> 1700000000 +0100")
+	require.True(t, ok)
+	assert.Equal(t, "Jane Q. Doe", name)
+	assert.Equal(t, "jane@example.com", email)
+	assert.Equal(t, "1700000000 +0100", date)
+
+	_, _, _, ok = parseIdent("no email 1700000000 +0100")
+	assert.False(t, ok)
+}
+
+func TestSplitPackage(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{
+		"grit.yaml":                 "repo:\n  name: mono\n  license: MIT\ntargets:\n  build: mkdir -p ${type.build_dir} && echo ${package.id} > ${type.build_dir}/${package.name}\ntypes:\n  lib:\n    package_dir: packages/lib\n    build_dir: build/lib\n    targets:\n      test: go test ./...\n",
+		"packages/lib/a/grit.yaml":  "package:\n  name: a\n  version: 1.0.0\n  dependencies: [b]\n",
+		"packages/lib/b/grit.yaml":  "package:\n  name: b\n  version: 1.0.0\n",
+		"packages/lib/a/src/a.go":   "package a",
+		"packages/lib/b/src/b.go":   "package b",
+		"packages/lib/ab/src/ab.go": "package ab",
+	})
+	commitFiles(t, dir, "b: change", map[string]string{"packages/lib/b/src/b.go": "package b // changed"})
+
+	// A branch changing a, merged back
+	git(t, dir, "checkout", "-q", "-b", "topic")
+	commitFiles(t, dir, "a: on topic", map[string]string{"packages/lib/a/src/topic.go": "package a"})
+	git(t, dir, "checkout", "-q", "-")
+	commitFiles(t, dir, "a: on main", map[string]string{"packages/lib/a/src/main.go": "package a"})
+	git(t, dir, "merge", "-q", "--no-edit", "topic")
+	git(t, dir, "-c", "user.name=Other", "commit", "-q", "--allow-empty", "-m", "empty")
+
+	rootConfig, err := grit.LoadConfig(filepath.Join(dir, "grit.yaml"))
+	require.NoError(t, err)
+	packages, err := grit.NewPackageManager(dir).LoadPackagesWithConfig(rootConfig)
+	require.NoError(t, err)
+	cfg, err := grit.NewIndex(packages).Lookup("a")
+	require.NoError(t, err)
+
+	root, pkgDir, err := packageRepoDir(dir, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, "packages/lib/a", pkgDir)
+
+	tip, count, err := splitHistory(root, "HEAD", pkgDir)
+	require.NoError(t, err)
+	assert.Equal(t, 4, count, "initial, both branches and the merge")
+
+	dest := filepath.Join(t.TempDir(), "a")
+	notes, err := createSplitRepo(root, tip, dest, "main", rootConfig, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"removed the dependencies on b"}, notes)
+
+	assert.Equal(t, "main\n", git(t, dest, "branch", "--show-current"))
+	log := git(t, dest, "log", "--format=%s|%an")
+	assert.ElementsMatch(t, []string{
+		"chore: turn grit.yaml into a standalone root config|Dev",
+		"Merge branch 'topic'|Dev",
+		"a: on main|Dev",
+		"a: on topic|Dev",
+		"initial|Dev",
+	}, strings.Split(strings.TrimSpace(log), "\n"))
+	assert.Equal(t, "initial\n", git(t, dest, "log", "-1", "--format=%s", "main~3"))
+	assert.Equal(t, "1\n", git(t, dest, "rev-list", "--merges", "--count", "main"))
+	assert.Equal(t, "grit.yaml\nsrc/a.go\nsrc/main.go\nsrc/topic.go\n", git(t, dest, "ls-files"))
+	assert.Equal(t, "", git(t, dest, "status", "--porcelain"))
+	// Files keep their paths, so their history is found without --follow
+	assert.Equal(t, "initial\n", git(t, dest, "log", "--diff-filter=A", "--format=%s", "--", "src/a.go"))
+
+	// The monorepo is left as it was
+	assert.Equal(t, "", git(t, dir, "for-each-ref", splitRef))
+
+	standalone, err := grit.LoadConfig(filepath.Join(dest, "grit.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, grit.RepoConfig{Name: "a", License: "MIT"}, standalone.Repo)
+	assert.Equal(t, "go test ./...", standalone.Targets["test"])
+	data, err := os.ReadFile(filepath.Join(dest, "grit.yaml"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "dependencies")
+
+	// The new repository is a workspace holding the package, which builds
+	packages, err = grit.NewPackageManager(dest).LoadPackagesWithConfig(standalone)
+	require.NoError(t, err)
+	index := grit.NewIndex(packages)
+	listings := listPackages(index.Packages(), index, dest)
+	require.Len(t, listings, 1)
+	assert.Equal(t, "lib/a", listings[0].ID)
+	assert.Equal(t, ".", listings[0].Path)
+	require.NoError(t, executeBuild(packages[0], standalone, filepath.Join(dest, ".grit", "cache"), true, output.New(), dest))
+	built, err := os.ReadFile(filepath.Join(dest, "build", "lib", "a"))
+	require.NoError(t, err)
+	assert.Equal(t, "lib/a\n", string(built))
+}
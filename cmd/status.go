@@ -0,0 +1,285 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+// A changed file reported by git status --porcelain=v2
+type statusEntry struct {
+	XY       string // Staged and unstaged state, e.g. "M." or ".D", "??" when untracked
+	Path     string // Relative to the repository root
+	OrigPath string // Path before a rename or copy
+}
+
+// Whether the index has changes for the file
+func (e statusEntry) staged() bool {
+	return e.XY[0] != '.' && e.XY[0] != '?'
+}
+
+// Whether the working tree has changes for the file that are not staged
+func (e statusEntry) unstaged() bool {
+	return e.XY[1] != '.'
+}
+
+func (e statusEntry) untracked() bool {
+	return e.XY == "??"
+}
+
+// Whether the file has merge conflicts
+func (e statusEntry) unmerged() bool {
+	return e.XY == "DD" || e.XY == "AA" || strings.ContainsRune(e.XY, 'U')
+}
+
+// The paths to stage or commit for the change. A rename includes its source, so
+// the removal is committed together with the new file.
+func (e statusEntry) paths() []string {
+	if e.OrigPath != "" {
+		return []string{e.Path, e.OrigPath}
+	}
+	return []string{e.Path}
+}
+
+// Short status line like git status -s prints
+func (e statusEntry) String() string {
+	xy := strings.ReplaceAll(e.XY, ".", " ")
+	if e.OrigPath != "" {
+		return fmt.Sprintf("%s %s -> %s", xy, e.OrigPath, e.Path)
+	}
+	return fmt.Sprintf("%s %s", xy, e.Path)
+}
+
+// Parse the output of git status --porcelain=v2 -z. Headers and ignored files
+// are skipped.
+func parseStatusV2(out string) ([]statusEntry, error) {
+	var entries []statusEntry
+	records := strings.Split(out, "\x00")
+	for i := 0; i < len(records); i++ {
+		record := records[i]
+		if record == "" {
+			continue
+		}
+
+		// The number of space separated fields before the path depends on the kind
+		var fields int
+		switch record[0] {
+		case '1':
+			fields = 8 // 1 XY sub mH mI mW hH hI path
+		case '2':
+			fields = 9 // 2 XY sub mH mI mW hH hI Xscore path, then origPath
+		case 'u':
+			fields = 10 // u XY sub m1 m2 m3 mW h1 h2 h3 path
+		case '?':
+			entries = append(entries, statusEntry{XY: "??", Path: record[2:]})
+			continue
+		case '#', '!':
+			continue
+		default:
+			return nil, fmt.Errorf("unexpected git status entry %q", record)
+		}
+
+		parts := strings.SplitN(record, " ", fields+1)
+		if len(parts) != fields+1 || len(parts[1]) != 2 {
+			return nil, fmt.Errorf("malformed git status entry %q", record)
+		}
+		entry := statusEntry{XY: parts[1], Path: parts[fields]}
+		if record[0] == '2' {
+			if i+1 >= len(records) {
+				return nil, fmt.Errorf("git status entry %q is missing its original path", record)
+			}
+			i++
+			entry.OrigPath = records[i]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// The changes in a workspace, attributed to the package each file belongs to
+type workspaceChanges struct {
+	root     string                   // Repository root the paths are relative to
+	packages []grit.Config            // Packages with changes, in index order
+	byID     map[string][]statusEntry // Changes by package ID
+	repo     []statusEntry            // Changes outside packages
+}
+
+// Read the changes below the workspace directory from git status and attribute
+// each file to exactly one package, the one with the deepest directory holding
+// it, or to the repository
+func workspaceStatus(cwd string, index *grit.Index) (*workspaceChanges, error) {
+	root, err := runGit(cwd, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := runGit(cwd, "rev-parse", "--show-prefix")
+	if err != nil {
+		return nil, err
+	}
+	out, err := runGit(cwd, "status", "--porcelain=v2", "-z", "--untracked-files=all", "--", ".")
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseStatusV2(out)
+	if err != nil {
+		return nil, err
+	}
+	return attributeChanges(index, cwd, strings.TrimSpace(prefix), strings.TrimSpace(root), entries), nil
+}
+
+// Group status entries by package. prefix is the path of the workspace directory
+// cwd within the repository, as printed by git rev-parse --show-prefix. A rename
+// across packages, or between a package and the repository, is split into the
+// addition of the new path and the deletion of the original one, so each side is
+// committed with the changes it belongs to.
+func attributeChanges(index *grit.Index, cwd string, prefix string, root string, entries []statusEntry) *workspaceChanges {
+	changes := &workspaceChanges{root: root, byID: make(map[string][]statusEntry)}
+	owner := func(path string) (grit.Config, bool) {
+		rel := strings.TrimPrefix(path, prefix)
+		return packageForPath(index, filepath.Join(cwd, filepath.FromSlash(rel)))
+	}
+	add := func(entry statusEntry) {
+		cfg, ok := owner(entry.Path)
+		if !ok {
+			changes.repo = append(changes.repo, entry)
+			return
+		}
+		changes.byID[cfg.Package.ID()] = append(changes.byID[cfg.Package.ID()], entry)
+	}
+
+	for _, entry := range entries {
+		if entry.OrigPath != "" {
+			cfg, ok := owner(entry.Path)
+			origCfg, origOk := owner(entry.OrigPath)
+			if ok != origOk || cfg.Package.ID() != origCfg.Package.ID() {
+				// The original of a copy is unchanged, only a rename removes it
+				if entry.XY[0] == 'R' {
+					add(statusEntry{XY: "D.", Path: entry.OrigPath})
+				}
+				entry = statusEntry{XY: "A" + entry.XY[1:], Path: entry.Path}
+			}
+		}
+		add(entry)
+	}
+
+	for _, cfg := range index.Packages() {
+		if len(changes.byID[cfg.Package.ID()]) > 0 {
+			changes.packages = append(changes.packages, cfg)
+		}
+	}
+	return changes
+}
+
+// The changes for which keep returns true. Packages left without changes are
+// dropped.
+func (c *workspaceChanges) filter(keep func(statusEntry) bool) *workspaceChanges {
+	filtered := &workspaceChanges{root: c.root, byID: make(map[string][]statusEntry)}
+	for _, cfg := range c.packages {
+		for _, entry := range c.byID[cfg.Package.ID()] {
+			if keep(entry) {
+				filtered.byID[cfg.Package.ID()] = append(filtered.byID[cfg.Package.ID()], entry)
+			}
+		}
+		if len(filtered.byID[cfg.Package.ID()]) > 0 {
+			filtered.packages = append(filtered.packages, cfg)
+		}
+	}
+	for _, entry := range c.repo {
+		if keep(entry) {
+			filtered.repo = append(filtered.repo, entry)
+		}
+	}
+	return filtered
+}
+
+// All paths touched by the changes
+func changedPaths(entries []statusEntry) []string {
+	var paths []string
+	for _, entry := range entries {
+		paths = append(paths, entry.paths()...)
+	}
+	return paths
+}
+
+// Stage the given paths, relative to the repository root, including deletions.
+// Paths are taken literally, not as patterns.
+func stagePaths(root string, paths []string) error {
+	_, err := runGit(root, append([]string{"--literal-pathspecs", "add", "-A", "--"}, paths...)...)
+	return err
+}
+
+// Commit the given paths only, leaving anything else in the index staged
+func commitPaths(root string, message string, paths []string) error {
+	_, err := runGit(root, append([]string{"--literal-pathspecs", "commit", "-q", "-m", message, "--"}, paths...)...)
+	return err
+}
+
+// Whether the index has changes for any of the paths compared to HEAD
+func hasStagedChanges(root string, paths []string) (bool, error) {
+	out, err := runGit(root, append([]string{"--literal-pathspecs", "diff", "--cached", "--name-only", "-z", "--"}, paths...)...)
+	if err != nil {
+		return false, err
+	}
+	return out != "", nil
+}
+
+// Commit what the index holds for the given paths, leaving the working tree and
+// anything else in the index as it is. The commit is made from a temporary index
+// with the content of HEAD and the index entries of the paths.
+func commitIndexPaths(root string, message string, paths []string) error {
+	env, cleanup, err := tempIndex(root)
+	if err != nil {
+		return err
+	}
+	defer cleanup()
+
+	// Replace the entries of the paths with those of the real index. Paths the
+	// index doesn't have, like the source of a rename, end up deleted.
+	staged, err := runGit(root, append([]string{"--literal-pathspecs", "ls-files", "-s", "-z", "--"}, paths...)...)
+	if err != nil {
+		return err
+	}
+	if _, err := runGitEnv(root, env, "", append([]string{"--literal-pathspecs", "rm", "--cached", "-q", "--ignore-unmatch", "--"}, paths...)...); err != nil {
+		return err
+	}
+	if staged != "" {
+		if _, err := runGitEnv(root, env, staged, "update-index", "-z", "--index-info"); err != nil {
+			return err
+		}
+	}
+
+	_, err = runGitEnv(root, env, "", "commit", "-q", "-m", message)
+	return err
+}
+
+// Create a temporary index holding the tree of HEAD. Returns the environment
+// for git to use it instead of the real index and a function that removes it.
+func tempIndex(root string) ([]string, func(), error) {
+	gitDir, err := runGit(root, "rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return nil, nil, err
+	}
+	tmp, err := os.CreateTemp(strings.TrimSpace(gitDir), "grit-index-")
+	if err != nil {
+		return nil, nil, err
+	}
+	indexFile := tmp.Name()
+	tmp.Close()
+	// git refuses to read an empty index file, so let read-tree write it
+	os.Remove(indexFile)
+	cleanup := func() { os.Remove(indexFile) }
+	env := []string{"GIT_INDEX_FILE=" + indexFile}
+
+	base := []string{"read-tree", "HEAD"}
+	if _, err := runGit(root, "rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		base = []string{"read-tree", "--empty"} // No commits yet
+	}
+	if _, err := runGitEnv(root, env, "", base...); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return env, cleanup, nil
+}
@@ -0,0 +1,165 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestParseStatusV2(t *testing.T) {
+	out := strings.Join([]string{
+		"# branch.oid 1234",
+		"1 .M N... 100644 100644 100644 aaaa aaaa packages/lib/foo/main.go",
+		"1 A. N... 000000 100644 100644 0000 bbbb packages/lib/foo/with space.go",
+		"2 R. N... 100644 100644 100644 cccc cccc R100 packages/lib/bar/new name.go",
+		"packages/lib/foo/old.go",
+		"u UU N... 100644 100644 100644 100644 dddd eeee ffff README.md",
+		"? packages/lib/foobar/\"quoted\".txt",
+		"! build/out.o",
+		"",
+	}, "\x00")
+
+	entries, err := parseStatusV2(out)
+	require.NoError(t, err)
+	assert.Equal(t, []statusEntry{
+		{XY: ".M", Path: "packages/lib/foo/main.go"},
+		{XY: "A.", Path: "packages/lib/foo/with space.go"},
+		{XY: "R.", Path: "packages/lib/bar/new name.go", OrigPath: "packages/lib/foo/old.go"},
+		{XY: "UU", Path: "README.md"},
+		{XY: "??", Path: `packages/lib/foobar/"quoted".txt`},
+	}, entries)
+
+	assert.False(t, entries[0].staged())
+	assert.True(t, entries[0].unstaged())
+	assert.True(t, entries[1].staged())
+	assert.False(t, entries[1].unstaged())
+	assert.True(t, entries[4].untracked())
+	assert.Equal(t, "R  packages/lib/foo/old.go -> packages/lib/bar/new name.go", entries[2].String())
+
+	_, err = parseStatusV2("2 R. N... 100644 100644 100644 cccc cccc R100 new.go")
+	assert.Error(t, err)
+	_, err = parseStatusV2("1 .M short")
+	assert.Error(t, err)
+}
+
+func TestAttributeChanges(t *testing.T) {
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "foo", Type: "lib", Path: "/repo/ws/packages/lib/foo/grit.yaml"}},
+		{Package: grit.Package{Name: "foobar", Type: "lib", Path: "/repo/ws/packages/lib/foobar/grit.yaml"}},
+		{Package: grit.Package{Name: "widgets", Type: "lib", Path: "/repo/ws/packages/lib/foo/widgets/grit.yaml"}},
+	})
+	entries := []statusEntry{
+		{XY: ".M", Path: "ws/packages/lib/foo/main.go"},
+		{XY: "??", Path: "ws/packages/lib/foobar/main.go"},
+		{XY: ".M", Path: "ws/packages/lib/foo/widgets/button.go"},
+		{XY: "R.", Path: "ws/README.md", OrigPath: "ws/packages/lib/foo/README.md"},
+		{XY: "??", Path: "ws/packages/lib/foo.txt"},
+		{XY: "RM", Path: "ws/packages/lib/foo/b.go", OrigPath: "ws/packages/lib/foo/a.go"},
+	}
+
+	changes := attributeChanges(index, "/repo/ws", "ws/", "/repo", entries)
+	assert.Equal(t, "/repo", changes.root)
+	assert.Equal(t, []statusEntry{entries[0], {XY: "D.", Path: "ws/packages/lib/foo/README.md"}, entries[5]}, changes.byID["lib/foo"], "renames within a package are kept")
+	assert.Equal(t, []statusEntry{entries[1]}, changes.byID["lib/foobar"])
+	assert.Equal(t, []statusEntry{entries[2]}, changes.byID["lib/widgets"])
+	assert.Equal(t, []statusEntry{{XY: "A.", Path: "ws/README.md"}, entries[4]}, changes.repo)
+	require.Len(t, changes.packages, 3)
+	assert.Equal(t, "lib/foo", changes.packages[0].Package.ID())
+}
+
+func TestCommitChanges(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{
+		"grit.yaml":                     "types:\n  lib:\n    package_dir: packages/lib\n",
+		"packages/lib/foo/grit.yaml":    "package:\n  name: foo\n",
+		"packages/lib/foo/old.go":       "old",
+		"packages/lib/foobar/grit.yaml": "package:\n  name: foobar\n",
+	})
+
+	git(t, dir, "mv", "packages/lib/foo/old.go", "packages/lib/foo/new.go")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "packages/lib/foo/a*b.go"), []byte("new"), 0644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "packages/lib/foobar/staged.go"), []byte("staged"), 0644))
+	git(t, dir, "add", "packages/lib/foobar/staged.go")
+
+	rootConfig, err := grit.LoadConfig(filepath.Join(dir, "grit.yaml"))
+	require.NoError(t, err)
+	packages, err := grit.NewPackageManager(dir).LoadPackagesWithConfig(rootConfig)
+	require.NoError(t, err)
+	changes, err := workspaceStatus(dir, grit.NewIndex(packages))
+	require.NoError(t, err)
+	require.Len(t, changes.packages, 2)
+	assert.Empty(t, changes.repo)
+
+	require.NoError(t, commitChanges(changes.root, "foo: move", changes.byID["lib/foo"]))
+	committed := git(t, dir, "show", "--name-status", "--format=", "HEAD")
+	assert.Equal(t, "A\tpackages/lib/foo/a*b.go\nR100\tpackages/lib/foo/old.go\tpackages/lib/foo/new.go\n", committed)
+
+	// Changes of other packages stay staged
+	assert.Equal(t, "A  packages/lib/foobar/staged.go\n", git(t, dir, "status", "--short"))
+
+	// Each side of a rename across packages is committed with its package
+	require.NoError(t, commitChanges(changes.root, "foobar: add", changes.byID["lib/foobar"]))
+	git(t, dir, "mv", "packages/lib/foo/new.go", "packages/lib/foobar/new.go")
+	changes, err = workspaceStatus(dir, grit.NewIndex(packages))
+	require.NoError(t, err)
+	require.NoError(t, commitChanges(changes.root, "foobar: take new.go", changes.byID["lib/foobar"]))
+	assert.Equal(t, "A\tpackages/lib/foobar/new.go\n", git(t, dir, "show", "--name-status", "--format=", "HEAD"))
+	assert.Equal(t, "D  packages/lib/foo/new.go\n", git(t, dir, "status", "--short"))
+}
+
+func TestWorkspaceChangesFilter(t *testing.T) {
+	foo := grit.Config{Package: grit.Package{Name: "foo", Type: "lib"}}
+	bar := grit.Config{Package: grit.Package{Name: "bar", Type: "lib"}}
+	changes := &workspaceChanges{
+		root:     "/repo",
+		packages: []grit.Config{foo, bar},
+		byID: map[string][]statusEntry{
+			"lib/foo": {{XY: "M.", Path: "foo/a.go"}, {XY: ".M", Path: "foo/b.go"}},
+			"lib/bar": {{XY: "??", Path: "bar/c.go"}},
+		},
+		repo: []statusEntry{{XY: "UU", Path: "README.md"}, {XY: "A.", Path: "go.mod"}},
+	}
+
+	staged := changes.filter(func(entry statusEntry) bool { return entry.staged() && !entry.unmerged() })
+	assert.Equal(t, []grit.Config{foo}, staged.packages)
+	assert.Equal(t, []statusEntry{{XY: "M.", Path: "foo/a.go"}}, staged.byID["lib/foo"])
+	assert.Empty(t, staged.byID["lib/bar"])
+	assert.Equal(t, []statusEntry{{XY: "A.", Path: "go.mod"}}, staged.repo)
+}
+
+func TestCommitIndexPaths(t *testing.T) {
+	dir := newGitRepo(t)
+	commitFiles(t, dir, "initial", map[string]string{
+		"foo/a.go":   "a",
+		"foo/old.go": "old",
+		"bar/b.go":   "b",
+	})
+
+	// Staged and unstaged edits of the same file
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo/a.go"), []byte("staged"), 0644))
+	git(t, dir, "add", "foo/a.go")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "foo/a.go"), []byte("unstaged"), 0644))
+	git(t, dir, "mv", "foo/old.go", "foo/new.go")
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "bar/b.go"), []byte("bar"), 0644))
+	git(t, dir, "add", "bar/b.go")
+
+	staged, err := hasStagedChanges(dir, []string{"foo/a.go"})
+	require.NoError(t, err)
+	assert.True(t, staged)
+
+	require.NoError(t, commitIndexPaths(dir, "foo: edit", []string{"foo/a.go", "foo/new.go", "foo/old.go"}))
+	assert.Equal(t, "M\tfoo/a.go\nR100\tfoo/old.go\tfoo/new.go\n", git(t, dir, "show", "--name-status", "--format=", "HEAD"))
+	assert.Equal(t, "staged", git(t, dir, "show", "HEAD:foo/a.go"))
+
+	// The working tree and the staged changes of other paths are kept
+	assert.Equal(t, "M  bar/b.go\n M foo/a.go\n", git(t, dir, "status", "--short"))
+
+	staged, err = hasStagedChanges(dir, []string{"foo/a.go", "foo/new.go"})
+	require.NoError(t, err)
+	assert.False(t, staged)
+}
@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+// Maximum time a single target command may run
+const targetTimeout = 2 * time.Minute
+
+var errTargetNotDefined = errors.New("target not defined")
+
+// Run a target in the package directory. The command is resolved through the
+// package, type and root config and its ${...} variables are interpolated.
+func runPackageTarget(cfg grit.Config, rootConfig *grit.RootConfig, target string, cwd string, formatter *output.Formatter) error {
+	resolved, ok := rootConfig.ResolveTarget(cfg, target)
+	if !ok {
+		return fmt.Errorf("%w: no %s command defined for package %s", errTargetNotDefined, target, cfg.Package.ID())
+	}
+
+	command, err := grit.Interpolate(resolved.Command, rootConfig.TargetVars(cfg, cwd))
+	if err != nil {
+		return fmt.Errorf("invalid %s command: %w", target, err)
+	}
+
+	formatter.Detail(fmt.Sprintf("Executing %s command from %s config: %s", target, resolved.Source, command))
+
+	// Execute the command with a timeout
+	ctx, cancel := context.WithTimeout(context.Background(), targetTimeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	cmd.Dir = filepath.Dir(cfg.Package.Path)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return fmt.Errorf("%s command timed out after %v", target, targetTimeout)
+		}
+		return fmt.Errorf("%s command failed: %w", target, err)
+	}
+
+	return nil
+}
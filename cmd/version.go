@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/spf13/cobra"
+	"github.com/weslien/grit/pkg/grit"
+	"github.com/weslien/grit/pkg/output"
+)
+
+var (
+	versionDependents bool
+	versionAffected   bool
+	versionBase       string
+	versionDryRun     bool
+)
+
+// A version bump requested for a package
+type versionRequest struct {
+	id   string
+	spec string // major, minor, patch or an explicit version
+}
+
+// A planned change of a package version
+type versionChange struct {
+	pkg    grit.Package
+	from   string
+	to     string
+	reason string
+}
+
+var versionCmd = &cobra.Command{
+	Use:   "version [package] <major|minor|patch|x.y.z>",
+	Short: "Bump package versions",
+	Long: `Bump the semantic version of a package by a level, or set an explicit version
+higher than the current one, and update its grit.yaml.
+
+With --dependents every package that depends on a bumped package, directly or
+transitively, gets a patch bump as well. With --affected each package with
+changes since HEAD branched off the --base revision is bumped, by patch unless a
+level is given.
+
+Examples:
+  grit version utils minor                  # 0.1.0 → 0.2.0
+  grit version lib/utils 1.0.0-rc.1
+  grit version utils patch --dependents     # Also bump the packages using utils
+  grit version --affected --base main       # Patch every package changed since main`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if versionAffected {
+			return cobra.MaximumNArgs(1)(cmd, args)
+		}
+		return cobra.ExactArgs(2)(cmd, args)
+	},
+	Run: func(cmd *cobra.Command, args []string) {
+		formatter := output.New()
+
+		cwd, index, _ := loadWorkspaceIndex(formatter)
+		formatter.Header("GRIT Version")
+
+		var requests []versionRequest
+		if versionAffected {
+			spec := grit.BumpPatch
+			if len(args) == 1 {
+				spec = args[0]
+			}
+			affected, err := affectedPackages(cwd, index, versionBase)
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error finding packages changed since %s: %v", versionBase, err))
+				os.Exit(1)
+			}
+			for _, cfg := range affected {
+				requests = append(requests, versionRequest{id: cfg.Package.ID(), spec: spec})
+			}
+		} else {
+			cfg, err := index.Lookup(args[0])
+			if err != nil {
+				formatter.Error(fmt.Sprintf("Error selecting package: %v", err))
+				os.Exit(1)
+			}
+			requests = append(requests, versionRequest{id: cfg.Package.ID(), spec: args[1]})
+		}
+
+		if len(requests) == 0 {
+			formatter.Info(fmt.Sprintf("No packages changed since %s", versionBase))
+			return
+		}
+
+		changes, err := planVersionBumps(index, requests, versionDependents)
+		if err != nil {
+			formatter.Error(fmt.Sprintf("Can't bump versions: %v", err))
+			os.Exit(1)
+		}
+
+		rows := make([][]string, 0, len(changes))
+		for _, change := range changes {
+			rows = append(rows, []string{change.pkg.ID(), change.from, change.to, change.reason})
+		}
+		formatter.Table([]string{"PACKAGE", "FROM", "TO", "REASON"}, rows)
+
+		if versionDryRun {
+			formatter.Info(fmt.Sprintf("Dry run, %d versions would change", len(changes)))
+			return
+		}
+		if err := writeVersions(changes); err != nil {
+			formatter.Error(fmt.Sprintf("Error updating versions: %v", err))
+			os.Exit(1)
+		}
+		formatter.Success(fmt.Sprintf("Updated %d versions", len(changes)))
+	},
+}
+
+func init() {
+	versionCmd.Flags().BoolVar(&versionDependents, "dependents", false, "Patch bump the packages depending on bumped packages")
+	versionCmd.Flags().BoolVar(&versionAffected, "affected", false, "Bump every package changed since the base revision")
+	versionCmd.Flags().StringVar(&versionBase, "base", "HEAD", "Revision to compare against with --affected")
+	versionCmd.Flags().BoolVar(&versionDryRun, "dry-run", false, "Show the new versions without writing them")
+	rootCmd.AddCommand(versionCmd)
+}
+
+// Work out the new version of each requested package and, with dependents, a
+// patch bump for every package depending on one of them. Changes are sorted by
+// package ID.
+func planVersionBumps(index *grit.Index, requests []versionRequest, dependents bool) ([]versionChange, error) {
+	changes := make(map[string]versionChange)
+	for _, req := range requests {
+		cfg, err := index.Lookup(req.id)
+		if err != nil {
+			return nil, err
+		}
+		next, err := grit.NextVersion(cfg.Package.Version, req.spec)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", cfg.Package.ID(), err)
+		}
+		reason := req.spec
+		if next == req.spec {
+			reason = "set"
+		}
+		changes[cfg.Package.ID()] = versionChange{pkg: cfg.Package, from: cfg.Package.Version, to: next, reason: reason}
+	}
+
+	if dependents {
+		_, reverse := dependencyGraph(index)
+		queue := make([]string, 0, len(changes))
+		for id := range changes {
+			queue = append(queue, id)
+		}
+		sort.Strings(queue)
+
+		for len(queue) > 0 {
+			id := queue[0]
+			queue = queue[1:]
+			for _, dependentID := range reverse[id] {
+				if _, done := changes[dependentID]; done {
+					continue
+				}
+				cfg, _ := index.Lookup(dependentID)
+				next, err := grit.NextVersion(cfg.Package.Version, grit.BumpPatch)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", dependentID, err)
+				}
+				changes[dependentID] = versionChange{pkg: cfg.Package, from: cfg.Package.Version, to: next, reason: fmt.Sprintf("depends on %s", id)}
+				queue = append(queue, dependentID)
+			}
+		}
+	}
+
+	result := make([]versionChange, 0, len(changes))
+	for _, change := range changes {
+		result = append(result, change)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].pkg.ID() < result[j].pkg.ID() })
+	return result, nil
+}
+
+// Write the new versions to the grit.yaml of each package, keeping its formatting
+func writeVersions(changes []versionChange) error {
+	for _, change := range changes {
+		doc, err := grit.LoadDocument(change.pkg.Path)
+		if err != nil {
+			return err
+		}
+		if err := doc.Set([]string{"package", "version"}, change.to); err != nil {
+			return err
+		}
+		if err := doc.Save(); err != nil {
+			return fmt.Errorf("failed to update %s: %w", change.pkg.Path, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestPlanVersionBumps(t *testing.T) {
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "utils", Type: "lib", Version: "0.1.0"}},
+		{Package: grit.Package{Name: "core", Type: "lib", Version: "1.2.3", Dependencies: []string{"utils"}}},
+		{Package: grit.Package{Name: "web", Type: "app", Version: "2.0.0", Dependencies: []string{"core", "utils"}}},
+		{Package: grit.Package{Name: "docs", Type: "app", Version: "0.0.1"}},
+	})
+
+	summarize := func(changes []versionChange) [][]string {
+		var rows [][]string
+		for _, c := range changes {
+			rows = append(rows, []string{c.pkg.ID(), c.from, c.to, c.reason})
+		}
+		return rows
+	}
+
+	changes, err := planVersionBumps(index, []versionRequest{{"lib/utils", "minor"}}, false)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"lib/utils", "0.1.0", "0.2.0", "minor"}}, summarize(changes))
+
+	// Dependents are bumped transitively, but an explicit request wins
+	changes, err = planVersionBumps(index, []versionRequest{{"lib/utils", "minor"}, {"app/web", "3.0.0"}}, true)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"app/web", "2.0.0", "3.0.0", "set"},
+		{"lib/core", "1.2.3", "1.2.4", "depends on lib/utils"},
+		{"lib/utils", "0.1.0", "0.2.0", "minor"},
+	}, summarize(changes))
+
+	_, err = planVersionBumps(index, []versionRequest{{"lib/core", "1.0.0"}}, false)
+	assert.Error(t, err)
+}
+
+func TestPackageForPath(t *testing.T) {
+	index := grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app", Path: "/ws/packages/app/web/grit.yaml"}},
+		{Package: grit.Package{Name: "widgets", Type: "lib", Path: "/ws/packages/app/web/widgets/grit.yaml"}},
+	})
+
+	cfg, ok := packageForPath(index, "/ws/packages/app/web/src/main.go")
+	require.True(t, ok)
+	assert.Equal(t, "app/web", cfg.Package.ID())
+
+	cfg, ok = packageForPath(index, "/ws/packages/app/web/widgets/button.go")
+	require.True(t, ok)
+	assert.Equal(t, "lib/widgets", cfg.Package.ID())
+
+	_, ok = packageForPath(index, "/ws/packages/app/webapp/main.go")
+	assert.False(t, ok)
+}
+
+func TestWriteVersions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grit.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("package:\n  name: utils\n  version: 0.1.0 # released\n"), 0644))
+
+	changes := []versionChange{{pkg: grit.Package{Name: "utils", Path: path}, from: "0.1.0", to: "0.2.0"}}
+	require.NoError(t, writeVersions(changes))
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "package:\n  name: utils\n  version: 0.2.0 # released\n", string(data))
+}
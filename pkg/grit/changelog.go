@@ -0,0 +1,176 @@
+package grit
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ChangelogFileName is the changelog kept in each package directory
+const ChangelogFileName = "CHANGELOG.md"
+
+const changelogTitle = "# Changelog"
+
+// UnreleasedVersion names the changelog section grit changelog collects changes
+// in, until grit release turns it into the section of the new version
+const UnreleasedVersion = "Unreleased"
+
+// The changelog groups in the order they are rendered
+var changelogGroups = []string{groupBreaking, groupFeatures, groupFixes, groupOther}
+
+// RenderChangelogSection renders the entries of a release as markdown, grouped
+// into breaking changes, features, fixes and other changes. Conventional types
+// like chore or docs are left out. The Unreleased section has no date. Returns
+// an empty string when none of the commits belongs in the changelog.
+func RenderChangelogSection(version string, date time.Time, commits []ConventionalCommit) string {
+	return renderChangelogSection(version, date, changelogEntries(commits))
+}
+
+// The changelog entries of commits by group
+func changelogEntries(commits []ConventionalCommit) map[string][]string {
+	groups := make(map[string][]string)
+	for _, commit := range commits {
+		group := commit.group()
+		if group == "" {
+			continue
+		}
+		entry := "- "
+		if commit.Scope != "" {
+			entry += fmt.Sprintf("**%s:** ", commit.Scope)
+		}
+		entry += commit.Subject
+		if commit.Hash != "" {
+			entry += fmt.Sprintf(" (%s)", shortHash(commit.Hash))
+		}
+		groups[group] = append(groups[group], entry)
+	}
+	return groups
+}
+
+func renderChangelogSection(version string, date time.Time, groups map[string][]string) string {
+	if len(groups) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	if version == UnreleasedVersion {
+		fmt.Fprintf(&sb, "## %s\n", version)
+	} else {
+		fmt.Fprintf(&sb, "## %s (%s)\n", version, date.Format("2006-01-02"))
+	}
+	// Groups written by hand follow the ones grit writes
+	titles := append([]string(nil), changelogGroups...)
+	var extra []string
+	for title := range groups {
+		if !containsValue(changelogGroups, title) {
+			extra = append(extra, title)
+		}
+	}
+	sort.Strings(extra)
+	for _, title := range append(titles, extra...) {
+		if len(groups[title]) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n### %s\n\n%s\n", title, strings.Join(groups[title], "\n"))
+	}
+	return sb.String()
+}
+
+// UpdateChangelog adds the entries of commits to a changelog, in the section of
+// version. The entries of an Unreleased section are kept, and the section is
+// turned into the one of version, so collecting changes with version
+// Unreleased adds to the section, and releasing a version names it. Without an
+// Unreleased section, the new section is added to the top. Returns the
+// changelog unchanged when there is nothing to add.
+func UpdateChangelog(existing string, version string, date time.Time, commits []ConventionalCommit) string {
+	groups := changelogEntries(commits)
+	start, end, found := unreleasedSection(existing)
+	if found {
+		// New entries come first, like the newer sections do
+		for title, entries := range parseChangelogSection(existing[start:end]) {
+			groups[title] = append(groups[title], entries...)
+		}
+	}
+	section := renderChangelogSection(version, date, groups)
+	if section == "" {
+		return existing
+	}
+	if !found {
+		return PrependChangelog(existing, section)
+	}
+	if end == len(existing) {
+		return existing[:start] + section
+	}
+	return existing[:start] + section + "\n" + existing[end:]
+}
+
+// The start and end of the Unreleased section of a changelog, up to the next
+// section or the end of the changelog
+func unreleasedSection(changelog string) (int, int, bool) {
+	heading := "## " + UnreleasedVersion
+	offset := 0
+	for _, line := range strings.SplitAfter(changelog, "\n") {
+		if strings.TrimSpace(line) == heading {
+			start := offset
+			if next := strings.Index(changelog[start+len(line):], "\n## "); next >= 0 {
+				return start, start + len(line) + next + 1, true
+			}
+			return start, len(changelog), true
+		}
+		offset += len(line)
+	}
+	return 0, 0, false
+}
+
+// The entries of a changelog section by group
+func parseChangelogSection(section string) map[string][]string {
+	groups := make(map[string][]string)
+	title := groupOther
+	for _, line := range strings.Split(section, "\n") {
+		switch {
+		case strings.HasPrefix(line, "### "):
+			title = strings.TrimSpace(strings.TrimPrefix(line, "### "))
+		case strings.HasPrefix(line, "- "):
+			groups[title] = append(groups[title], strings.TrimRight(line, " "))
+		}
+	}
+	return groups
+}
+
+// PrependChangelog adds a section to the top of a changelog, below its title. An
+// empty changelog gets a title first.
+func PrependChangelog(existing string, section string) string {
+	if strings.TrimSpace(existing) == "" {
+		return changelogTitle + "\n\n" + section
+	}
+
+	// Keep a leading title and the introduction below it, up to the first section
+	if strings.HasPrefix(existing, "# ") {
+		if i := strings.Index(existing, "\n## "); i >= 0 {
+			return existing[:i+1] + section + "\n" + existing[i+1:]
+		}
+		return strings.TrimRight(existing, "\n") + "\n\n" + section
+	}
+	return section + "\n" + existing
+}
+
+// UnlistedCommits returns the commits whose short hash isn't in the changelog
+// yet, so adding commits to a changelog that wasn't committed since it was last
+// updated doesn't list them twice
+func UnlistedCommits(changelog string, commits []ConventionalCommit) []ConventionalCommit {
+	var unlisted []ConventionalCommit
+	for _, commit := range commits {
+		if commit.Hash == "" || !strings.Contains(changelog, "("+shortHash(commit.Hash)+")") {
+			unlisted = append(unlisted, commit)
+		}
+	}
+	return unlisted
+}
+
+func shortHash(hash string) string {
+	if len(hash) > 7 {
+		return hash[:7]
+	}
+	return hash
+}
@@ -0,0 +1,167 @@
+package grit_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestParseCommitMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    grit.ConventionalCommit
+	}{
+		{"feat: add retries", grit.ConventionalCommit{Type: "feat", Subject: "add retries"}},
+		{"Fix(parser): handle empty input", grit.ConventionalCommit{Type: "fix", Scope: "parser", Subject: "handle empty input"}},
+		{"refactor!: drop the v1 API", grit.ConventionalCommit{Type: "refactor", Subject: "drop the v1 API", Breaking: true}},
+		{"utils: feat: add retries", grit.ConventionalCommit{Package: "utils", Type: "feat", Subject: "add retries"}},
+		{"lib/utils: fix(io)!: close files", grit.ConventionalCommit{Package: "lib/utils", Type: "fix", Scope: "io", Subject: "close files", Breaking: true}},
+		{"feat: new config\n\nBREAKING CHANGE: the old keys are gone", grit.ConventionalCommit{
+			Type: "feat", Subject: "new config", Body: "BREAKING CHANGE: the old keys are gone", Breaking: true}},
+		{"utils: update readme", grit.ConventionalCommit{Package: "utils", Subject: "update readme"}},
+		{"utils: security: pin versions", grit.ConventionalCommit{Package: "utils", Type: "security", Subject: "pin versions"}},
+	}
+	commitConfig := grit.CommitConfig{Types: []string{"feat", "fix", "refactor", "security"}}
+	for _, tt := range tests {
+		got, ok := commitConfig.ParseCommitMessage(tt.message)
+		if !ok {
+			t.Errorf("%q: not recognized", tt.message)
+		} else if got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.message, got, tt.want)
+		}
+	}
+
+	for _, message := range []string{"", "update readme", "feat:no space"} {
+		if _, ok := commitConfig.ParseCommitMessage(message); ok {
+			t.Errorf("%q: should not be recognized", message)
+		}
+	}
+}
+
+func TestRenderChangelogSection(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	commits := []grit.ConventionalCommit{
+		{Hash: "1111111aaaa", Type: "feat", Subject: "add retries"},
+		{Hash: "2222222bbbb", Type: "fix", Scope: "io", Subject: "close files"},
+		{Hash: "3333333cccc", Type: "chore", Subject: "tidy up"},
+		{Hash: "4444444dddd", Type: "feat", Subject: "new config", Breaking: true},
+		{Hash: "5555555eeee", Subject: "update readme"},
+		{Hash: "6666666ffff", Type: "security", Subject: "pin versions"},
+	}
+
+	want := `## 1.0.0 (2024-03-01)
+
+### Breaking Changes
+
+- new config (4444444)
+
+### Features
+
+- add retries (1111111)
+
+### Fixes
+
+- **io:** close files (2222222)
+
+### Other Changes
+
+- update readme (5555555)
+- pin versions (6666666)
+`
+	if got := grit.RenderChangelogSection("1.0.0", date, commits); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+	if got := grit.RenderChangelogSection("1.0.0", date, commits[2:3]); got != "" {
+		t.Errorf("expected no section for chores, got:\n%s", got)
+	}
+}
+
+func TestPrependChangelog(t *testing.T) {
+	section := "## 1.1.0 (2024-03-01)\n\n### Fixes\n\n- a fix\n"
+
+	tests := []struct {
+		existing string
+		want     string
+	}{
+		{"", "# Changelog\n\n" + section},
+		{"# Changelog\n\nAll notable changes.\n\n## 1.0.0\n\n- first\n",
+			"# Changelog\n\nAll notable changes.\n\n" + section + "\n## 1.0.0\n\n- first\n"},
+		{"# Changelog\n", "# Changelog\n\n" + section},
+		{"## 1.0.0\n\n- first\n", section + "\n## 1.0.0\n\n- first\n"},
+	}
+	for _, tt := range tests {
+		if got := grit.PrependChangelog(tt.existing, section); got != tt.want {
+			t.Errorf("%q: got:\n%s\nwant:\n%s", tt.existing, got, tt.want)
+		}
+	}
+}
+
+func TestUpdateChangelog(t *testing.T) {
+	date := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	feat := grit.ConventionalCommit{Hash: "1111111aaaa", Type: "feat", Subject: "add retries"}
+	fix := grit.ConventionalCommit{Hash: "2222222bbbb", Type: "fix", Subject: "close files"}
+	released := "## 1.0.0 (2024-01-01)\n\n### Fixes\n\n- first\n"
+
+	changelog := grit.UpdateChangelog("# Changelog\n\n"+released, grit.UnreleasedVersion, date, []grit.ConventionalCommit{feat})
+	want := "# Changelog\n\n## Unreleased\n\n### Features\n\n- add retries (1111111)\n\n" + released
+	if changelog != want {
+		t.Errorf("got:\n%s\nwant:\n%s", changelog, want)
+	}
+
+	// New entries are added to the Unreleased section
+	changelog = grit.UpdateChangelog(changelog, grit.UnreleasedVersion, date, []grit.ConventionalCommit{fix})
+	want = "# Changelog\n\n## Unreleased\n\n### Features\n\n- add retries (1111111)\n\n### Fixes\n\n- close files (2222222)\n\n" + released
+	if changelog != want {
+		t.Errorf("got:\n%s\nwant:\n%s", changelog, want)
+	}
+	if got := grit.UpdateChangelog(changelog, grit.UnreleasedVersion, date, nil); got != changelog {
+		t.Errorf("expected no change without commits, got:\n%s", got)
+	}
+
+	// A release takes over the Unreleased section
+	changelog = grit.UpdateChangelog(changelog, "1.1.0", date, nil)
+	want = "# Changelog\n\n## 1.1.0 (2024-03-01)\n\n### Features\n\n- add retries (1111111)\n\n### Fixes\n\n- close files (2222222)\n\n" + released
+	if changelog != want {
+		t.Errorf("got:\n%s\nwant:\n%s", changelog, want)
+	}
+}
+
+func TestUnlistedCommits(t *testing.T) {
+	changelog := "# Changelog\n\n## 1.0.0 (2024-03-01)\n\n### Features\n\n- add retries (1111111)\n"
+	commits := []grit.ConventionalCommit{
+		{Type: "fix", Subject: "handle timeouts", Hash: "2222222aaaa"},
+		{Type: "feat", Subject: "add retries", Hash: "1111111bbbb"},
+		{Type: "fix", Subject: "no hash"},
+	}
+
+	got := grit.UnlistedCommits(changelog, commits)
+	if len(got) != 2 || got[0].Subject != "handle timeouts" || got[1].Subject != "no hash" {
+		t.Errorf("UnlistedCommits() = %+v", got)
+	}
+}
+
+func TestReleaseLevel(t *testing.T) {
+	feat := grit.ConventionalCommit{Type: "feat"}
+	fix := grit.ConventionalCommit{Type: "fix"}
+	chore := grit.ConventionalCommit{Type: "chore"}
+	plain := grit.ConventionalCommit{Subject: "update readme"}
+	breaking := grit.ConventionalCommit{Type: "chore", Breaking: true}
+
+	tests := []struct {
+		commits []grit.ConventionalCommit
+		want    string
+	}{
+		{nil, ""},
+		{[]grit.ConventionalCommit{chore}, ""},
+		{[]grit.ConventionalCommit{chore, fix}, "patch"},
+		{[]grit.ConventionalCommit{chore, plain}, "patch"},
+		{[]grit.ConventionalCommit{fix, feat, fix}, "minor"},
+		{[]grit.ConventionalCommit{feat, breaking}, "major"},
+	}
+	for _, tt := range tests {
+		if got := grit.ReleaseLevel(tt.commits); got != tt.want {
+			t.Errorf("%+v: got %q, want %q", tt.commits, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package grit
+
+import (
+	"sort"
+	"strings"
+)
+
+// CodeownersPaths are the locations GitHub reads a CODEOWNERS file from, relative
+// to the repository root, in the order it looks for them
+var CodeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+const (
+	codeownersBegin = "# BEGIN grit codeowners: generated from the package owners, run grit codeowners to update"
+	codeownersEnd   = "# END grit codeowners"
+)
+
+// CodeownersRule assigns owners to a directory of the repository
+type CodeownersRule struct {
+	Dir    string // Relative to the repository root, with forward slashes
+	Owners []string
+}
+
+// PackageOwners returns the owners of a package, or the default owners of its
+// type when the package doesn't list any
+func (c *RootConfig) PackageOwners(cfg Config) []string {
+	if len(cfg.Package.Owners) > 0 {
+		return cfg.Package.Owners
+	}
+	return c.Types[cfg.Package.Type].Owners
+}
+
+// RenderCodeowners renders rules as the block of a CODEOWNERS file grit
+// maintains. Rules are sorted by directory, so the rule of a nested directory
+// follows the rule of its parent and takes precedence.
+func RenderCodeowners(rules []CodeownersRule) string {
+	sorted := append([]CodeownersRule(nil), rules...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Dir < sorted[j].Dir })
+
+	var sb strings.Builder
+	sb.WriteString(codeownersBegin + "\n")
+	for _, rule := range sorted {
+		path := "/" + strings.Trim(rule.Dir, "/") + "/"
+		// The root of the repository owns everything
+		if dir := strings.Trim(rule.Dir, "/"); dir == "" || dir == "." {
+			path = "*"
+		}
+		sb.WriteString(strings.ReplaceAll(path, " ", `\ `) + " " + strings.Join(rule.Owners, " ") + "\n")
+	}
+	sb.WriteString(codeownersEnd + "\n")
+	return sb.String()
+}
+
+// UpdateCodeowners replaces the block grit maintains in a CODEOWNERS file,
+// keeping the lines around it. A file without the block gets it appended, so it
+// takes precedence over the rules written by hand.
+func UpdateCodeowners(existing string, block string) string {
+	begin := strings.Index(existing, codeownersBegin)
+	if begin >= 0 {
+		if end := strings.Index(existing[begin:], codeownersEnd); end >= 0 {
+			end += begin + len(codeownersEnd)
+			if end < len(existing) && existing[end] == '\n' {
+				end++
+			}
+			return existing[:begin] + block + existing[end:]
+		}
+	}
+
+	if existing == "" {
+		return block
+	}
+	if !strings.HasSuffix(existing, "\n") {
+		existing += "\n"
+	}
+	return existing + "\n" + block
+}
@@ -0,0 +1,71 @@
+package grit_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestPackageOwners(t *testing.T) {
+	root := &grit.RootConfig{Types: map[string]grit.TypeConfig{"lib": {Owners: []string{"@org/libs"}}}}
+	own := grit.Config{Package: grit.Package{Name: "a", Type: "lib", Owners: []string{"@alice"}}}
+	inherited := grit.Config{Package: grit.Package{Name: "b", Type: "lib"}}
+	none := grit.Config{Package: grit.Package{Name: "c", Type: "app"}}
+
+	if got := root.PackageOwners(own); !reflect.DeepEqual(got, []string{"@alice"}) {
+		t.Errorf("package owners = %v", got)
+	}
+	if got := root.PackageOwners(inherited); !reflect.DeepEqual(got, []string{"@org/libs"}) {
+		t.Errorf("type owners = %v", got)
+	}
+	if got := root.PackageOwners(none); len(got) != 0 {
+		t.Errorf("expected no owners, got %v", got)
+	}
+}
+
+func TestRenderCodeowners(t *testing.T) {
+	block := grit.RenderCodeowners([]grit.CodeownersRule{
+		{Dir: "packages/lib/foo/widgets", Owners: []string{"@bob"}},
+		{Dir: "packages/lib/foo", Owners: []string{"@alice", "@org/libs"}},
+		{Dir: "packages/app/my app/", Owners: []string{"@carol"}},
+		{Dir: ".", Owners: []string{"@org/core"}},
+	})
+	lines := []string{
+		"* @org/core",
+		"/packages/app/my\\ app/ @carol",
+		"/packages/lib/foo/ @alice @org/libs",
+		"/packages/lib/foo/widgets/ @bob",
+	}
+	want := "# BEGIN grit codeowners: generated from the package owners, run grit codeowners to update\n"
+	for _, line := range lines {
+		want += line + "\n"
+	}
+	want += "# END grit codeowners\n"
+	if block != want {
+		t.Errorf("RenderCodeowners() =\n%s\nwant\n%s", block, want)
+	}
+}
+
+func TestUpdateCodeowners(t *testing.T) {
+	block := grit.RenderCodeowners([]grit.CodeownersRule{{Dir: "packages/lib/foo", Owners: []string{"@alice"}}})
+
+	if got := grit.UpdateCodeowners("", block); got != block {
+		t.Errorf("new file = %q", got)
+	}
+
+	appended := grit.UpdateCodeowners("* @org/admins", block)
+	if want := "* @org/admins\n\n" + block; appended != want {
+		t.Errorf("appended = %q, want %q", appended, want)
+	}
+
+	// The block is replaced in place, keeping the rules around it
+	stale := grit.RenderCodeowners([]grit.CodeownersRule{{Dir: "packages/lib/old", Owners: []string{"@bob"}}})
+	existing := "* @org/admins\n" + stale + "/docs/ @org/docs\n"
+	if got, want := grit.UpdateCodeowners(existing, block), "* @org/admins\n"+block+"/docs/ @org/docs\n"; got != want {
+		t.Errorf("replaced = %q, want %q", got, want)
+	}
+	if got := grit.UpdateCodeowners(appended, block); got != appended {
+		t.Errorf("an up to date file should not change, got %q", got)
+	}
+}
@@ -1,14 +1,53 @@
 package grit
 
 import (
+	"bytes"
 	"fmt"
 	"os"
+	"path/filepath"
+	"sort"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
 // Config operations
+
+// LoadConfig loads the effective root config: fragments listed under include
+// are merged in and types that extend another type inherit its settings.
 func LoadConfig(path string) (*RootConfig, error) {
+	config, err := LoadRawConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := config.resolve(filepath.Dir(path)); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// ConfigFromDocument returns the effective root config of a document that may
+// not have been saved yet, resolving includes relative to its path
+func ConfigFromDocument(doc *Document) (*RootConfig, error) {
+	var config RootConfig
+	if err := doc.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse config: %w", err)
+	}
+	if err := checkSchemaVersion(doc.Path(), config.Version); err != nil {
+		return nil, err
+	}
+	if config.Types == nil {
+		config.Types = make(map[string]TypeConfig)
+	}
+	if err := config.resolve(filepath.Dir(doc.Path())); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// LoadRawConfig loads the root config as written, without includes or type
+// inheritance applied. Use it when the config is going to be written back.
+func LoadRawConfig(path string) (*RootConfig, error) {
 	data, err := os.ReadFile(path)
 	if err != nil {
 		return &RootConfig{Types: make(map[string]TypeConfig)}, nil
@@ -18,6 +57,9 @@ func LoadConfig(path string) (*RootConfig, error) {
 	if err := yaml.Unmarshal(data, &config); err != nil {
 		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
+	if err := checkSchemaVersion(path, config.Version); err != nil {
+		return nil, err
+	}
 
 	if config.Types == nil {
 		config.Types = make(map[string]TypeConfig)
@@ -25,12 +67,32 @@ func LoadConfig(path string) (*RootConfig, error) {
 	return &config, nil
 }
 
+// SaveConfig writes the root config. When the file exists the config is merged
+// into it, keeping comments, key order and sections grit doesn't know about.
 func SaveConfig(config *RootConfig, path string) error {
-	data, err := yaml.Marshal(config)
+	doc, err := LoadDocument(path)
 	if err != nil {
+		return err
+	}
+	if err := doc.Merge(config); err != nil {
 		return fmt.Errorf("failed to marshal config: %w", err)
 	}
-	return os.WriteFile(path, data, 0644)
+	return doc.Save()
+}
+
+// EncodeConfig marshals a config the way grit writes its files: two space
+// indentation and keys in struct order, with map keys sorted
+func EncodeConfig(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
 }
 
 func (c *RootConfig) MergeDefaults(defaults TypeConfig) {
@@ -43,3 +105,137 @@ func (c *RootConfig) MergeDefaults(defaults TypeConfig) {
 		}
 	}
 }
+
+// Apply includes and type inheritance
+func (c *RootConfig) resolve(dir string) error {
+	if err := c.applyIncludes(dir); err != nil {
+		return err
+	}
+	return c.resolveExtends()
+}
+
+// A config file listed under include. It may define types and targets.
+type configFragment struct {
+	Targets map[string]string     `yaml:"targets"`
+	Types   map[string]TypeConfig `yaml:"types"`
+}
+
+// Merge the fragments matched by the include patterns, which are relative to the
+// directory of the root config. A type or target may only be defined once.
+func (c *RootConfig) applyIncludes(dir string) error {
+	seen := make(map[string]bool)
+	for _, pattern := range c.Include {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return fmt.Errorf("invalid include pattern %s: %w", pattern, err)
+		}
+
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			seen[match] = true
+
+			data, err := os.ReadFile(match)
+			if err != nil {
+				return fmt.Errorf("failed to read included config: %w", err)
+			}
+			var fragment configFragment
+			if err := yaml.Unmarshal(data, &fragment); err != nil {
+				return fmt.Errorf("failed to parse included config %s: %w", match, err)
+			}
+
+			for name, typeConfig := range fragment.Types {
+				if _, exists := c.Types[name]; exists {
+					return fmt.Errorf("type %s in %s is already defined", name, match)
+				}
+				c.Types[name] = typeConfig
+			}
+			for name, command := range fragment.Targets {
+				if _, exists := c.Targets[name]; exists {
+					return fmt.Errorf("target %s in %s is already defined", name, match)
+				}
+				if c.Targets == nil {
+					c.Targets = make(map[string]string)
+				}
+				c.Targets[name] = command
+			}
+		}
+	}
+
+	c.Include = nil
+	return nil
+}
+
+// Apply type inheritance. A type that extends another inherits its targets, can
+// depend on rules and build and coverage directories unless it sets them itself.
+// The package directory is never inherited, so a base type without one only
+// serves as a template.
+func (c *RootConfig) resolveExtends() error {
+	names := make([]string, 0, len(c.Types))
+	for name := range c.Types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	resolved := make(map[string]bool)
+	var resolve func(name string, chain []string) error
+	resolve = func(name string, chain []string) error {
+		if resolved[name] {
+			return nil
+		}
+		for _, ancestor := range chain {
+			if ancestor == name {
+				return fmt.Errorf("type inheritance cycle: %s", strings.Join(append(chain, name), " → "))
+			}
+		}
+
+		typeConfig := c.Types[name]
+		if typeConfig.Extends != "" {
+			if _, exists := c.Types[typeConfig.Extends]; !exists {
+				return fmt.Errorf("type %s extends unknown type %s", name, typeConfig.Extends)
+			}
+			if err := resolve(typeConfig.Extends, append(chain, name)); err != nil {
+				return err
+			}
+			c.Types[name] = typeConfig.inherit(c.Types[typeConfig.Extends])
+		}
+
+		resolved[name] = true
+		return nil
+	}
+
+	for _, name := range names {
+		if err := resolve(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t TypeConfig) inherit(parent TypeConfig) TypeConfig {
+	merged := t
+	if merged.BuildDir == "" {
+		merged.BuildDir = parent.BuildDir
+	}
+	if merged.CoverageDir == "" {
+		merged.CoverageDir = parent.CoverageDir
+	}
+	if len(merged.CanDependOn) == 0 {
+		merged.CanDependOn = parent.CanDependOn
+	}
+	if len(merged.Owners) == 0 {
+		merged.Owners = parent.Owners
+	}
+
+	merged.Targets = make(map[string]string)
+	for name, command := range parent.Targets {
+		merged.Targets[name] = command
+	}
+	for name, command := range t.Targets {
+		if command != "" {
+			merged.Targets[name] = command
+		}
+	}
+	return merged
+}
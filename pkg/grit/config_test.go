@@ -4,6 +4,7 @@ import (
 	_ "embed"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/weslien/grit/pkg/grit"
@@ -169,3 +170,88 @@ func TestLoadMissingFile(t *testing.T) {
 	}
 
 }
+
+func TestLoadConfigIncludesAndExtends(t *testing.T) {
+	tmp := t.TempDir()
+	files := map[string]string{
+		"grit.yaml": `
+include:
+  - .grit/types/*.yaml
+targets:
+  lint: "echo lint"
+types:
+  base:
+    build_dir: build/base
+    can_depend_on: [lib]
+    owners: ["@org/platform"]
+    targets:
+      build: "echo base-build"
+      test: "echo base-test"
+`,
+		".grit/types/lib.yaml": `
+types:
+  lib:
+    extends: base
+    package_dir: packages/lib
+    targets:
+      test: "echo lib-test"
+      build: ""
+`,
+	}
+	for name, content := range files {
+		if err := os.MkdirAll(filepath.Dir(filepath.Join(tmp, name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(tmp, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg, err := grit.LoadConfig(filepath.Join(tmp, "grit.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	lib, ok := cfg.Types["lib"]
+	if !ok {
+		t.Fatal("LoadConfig() did not include the lib type")
+	}
+	if lib.PackageDir != "packages/lib" || lib.BuildDir != "build/base" || len(lib.CanDependOn) != 1 || len(lib.Owners) != 1 {
+		t.Errorf("lib type = %+v, want inherited build_dir, can_depend_on and owners", lib)
+	}
+	if lib.Targets["build"] != "echo base-build" || lib.Targets["test"] != "echo lib-test" {
+		t.Errorf("lib targets = %v", lib.Targets)
+	}
+
+	raw, err := grit.LoadRawConfig(filepath.Join(tmp, "grit.yaml"))
+	if err != nil {
+		t.Fatalf("LoadRawConfig() error = %v", err)
+	}
+	if _, ok := raw.Types["lib"]; ok || len(raw.Include) != 1 {
+		t.Errorf("LoadRawConfig() should not apply includes, got %+v", raw)
+	}
+}
+
+func TestLoadConfigExtendsErrors(t *testing.T) {
+	tests := map[string]string{
+		"cycle":        "types:\n  a:\n    extends: b\n  b:\n    extends: a\n",
+		"unknown":      "types:\n  a:\n    extends: nope\n",
+		"self":         "types:\n  a:\n    extends: a\n",
+		"include dupe": "include: [other.yaml]\ntypes:\n  a: {}\n",
+	}
+
+	for name, content := range tests {
+		t.Run(name, func(t *testing.T) {
+			tmp := t.TempDir()
+			if err := os.WriteFile(filepath.Join(tmp, "grit.yaml"), []byte(content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(filepath.Join(tmp, "other.yaml"), []byte("types:\n  a: {}\n"), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := grit.LoadConfig(filepath.Join(tmp, "grit.yaml")); err == nil {
+				t.Error("LoadConfig() expected an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package grit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var ErrInvalidCommitMessage = errors.New("invalid commit message")
+
+// The commit types of https://www.conventionalcommits.org that grit recognizes
+var defaultCommitTypes = []string{"feat", "fix", "perf", "refactor", "docs", "style", "test", "build", "ci", "chore", "revert"}
+
+var (
+	conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+	packagePrefix      = regexp.MustCompile(`^([^\s:]+): +(.+)$`)
+	breakingFooter     = regexp.MustCompile(`(?m)^BREAKING[ -]CHANGE: *(.+)$`)
+)
+
+// ConventionalCommit is a commit message of the form type(scope)!: subject. The
+// message may be prefixed with the package it belongs to, as grit commit writes
+// them, e.g. "utils: fix: handle empty input".
+type ConventionalCommit struct {
+	Hash     string
+	Package  string // Package prefix of the message, if any
+	Type     string // Lower case commit type, e.g. feat or fix, empty for plain package messages
+	Scope    string
+	Subject  string
+	Body     string
+	Breaking bool
+}
+
+// ParseCommitMessage parses a conventional commit message with one of the
+// allowed commit types. A message with only a package prefix, like the ones grit
+// commit writes outside conventional mode, e.g. "utils: add retries", is parsed
+// with an empty type. It returns false for other messages.
+func (c CommitConfig) ParseCommitMessage(message string) (ConventionalCommit, bool) {
+	header, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	header = strings.TrimSpace(header)
+
+	commit, ok := c.ParseCommitHeader(header)
+	if !ok {
+		// Try again without a package prefix
+		p := packagePrefix.FindStringSubmatch(header)
+		if p == nil {
+			return ConventionalCommit{}, false
+		}
+		if commit, ok = c.ParseCommitHeader(p[2]); !ok {
+			commit = ConventionalCommit{Subject: strings.TrimSpace(p[2])}
+		}
+		commit.Package = p[1]
+	}
+
+	commit.Body = strings.TrimSpace(body)
+	if breakingFooter.MatchString(commit.Body) {
+		commit.Breaking = true
+	}
+	return commit, true
+}
+
+// Changelog groups of commits
+const (
+	groupBreaking = "Breaking Changes"
+	groupFeatures = "Features"
+	groupFixes    = "Fixes"
+	groupOther    = "Other Changes"
+)
+
+// The changelog group of a commit. Plain package messages and commit types
+// configured on top of the conventional ones are other changes, the remaining
+// conventional types, like chore or docs, belong in no group.
+func (c ConventionalCommit) group() string {
+	switch {
+	case c.Breaking:
+		return groupBreaking
+	case c.Type == "feat":
+		return groupFeatures
+	case c.Type == "fix" || c.Type == "perf":
+		return groupFixes
+	case !containsValue(defaultCommitTypes, c.Type):
+		return groupOther
+	}
+	return ""
+}
+
+// ReleaseLevel returns the version bump the commits call for: major for breaking
+// changes, minor for features and patch for fixes and other changes that are
+// listed in changelogs. It returns an empty string when none of the commits
+// needs a release.
+func ReleaseLevel(commits []ConventionalCommit) string {
+	level := ""
+	for _, commit := range commits {
+		switch commit.group() {
+		case groupBreaking:
+			return BumpMajor
+		case groupFeatures:
+			level = BumpMinor
+		case groupFixes, groupOther:
+			if level == "" {
+				level = BumpPatch
+			}
+		}
+	}
+	return level
+}
+
+// CommitTypes returns the commit types allowed in messages
+func (c CommitConfig) CommitTypes() []string {
+	if len(c.Types) > 0 {
+		return c.Types
+	}
+	return defaultCommitTypes
+}
+
+// String formats the commit as a message, without a package prefix
+func (c ConventionalCommit) String() string {
+	header := c.Type
+	if c.Scope != "" {
+		header += "(" + c.Scope + ")"
+	}
+	if c.Breaking {
+		header += "!"
+	}
+	message := header + ": " + c.Subject
+	if c.Body != "" {
+		message += "\n\n" + c.Body
+	}
+	return message
+}
+
+// ValidateCommitMessage checks that the first line of a message is a
+// conventional commit header with an allowed type, and that it matches the
+// configured pattern
+func (c CommitConfig) ValidateCommitMessage(message string) error {
+	header, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	header = strings.TrimSpace(header)
+
+	m := conventionalHeader.FindStringSubmatch(header)
+	if m == nil {
+		return fmt.Errorf("%w: %q is not of the form type(scope): subject", ErrInvalidCommitMessage, header)
+	}
+	if !containsValue(c.CommitTypes(), strings.ToLower(m[1])) {
+		return fmt.Errorf("%w: unknown type %s, expected one of %s", ErrInvalidCommitMessage, m[1], strings.Join(c.CommitTypes(), ", "))
+	}
+	if c.Pattern != "" {
+		pattern, err := regexp.Compile(c.Pattern)
+		if err != nil {
+			return fmt.Errorf("invalid commit pattern %q: %w", c.Pattern, err)
+		}
+		if !pattern.MatchString(header) {
+			return fmt.Errorf("%w: %q does not match %s", ErrInvalidCommitMessage, header, c.Pattern)
+		}
+	}
+	return nil
+}
+
+// ParseCommitHeader parses the first line of a message typed as a conventional
+// commit with one of the allowed types. It returns false for other messages.
+func (c CommitConfig) ParseCommitHeader(message string) (ConventionalCommit, bool) {
+	header, body, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	m := conventionalHeader.FindStringSubmatch(strings.TrimSpace(header))
+	if m == nil || !containsValue(c.CommitTypes(), strings.ToLower(m[1])) {
+		return ConventionalCommit{}, false
+	}
+	return ConventionalCommit{
+		Type:     strings.ToLower(m[1]),
+		Scope:    strings.TrimSpace(m[2]),
+		Breaking: m[3] == "!",
+		Subject:  strings.TrimSpace(m[4]),
+		Body:     strings.TrimSpace(body),
+	}, true
+}
+
+// References returns the packages named in the first line of a message: the
+// package prefix grit commit writes, as in "utils: add retries", and the scope
+// of a conventional commit, which may list several packages separated by commas
+func (c CommitConfig) References(message string) []string {
+	header, _, _ := strings.Cut(strings.TrimSpace(message), "\n")
+	header = strings.TrimSpace(header)
+
+	var refs []string
+	commit, ok := c.ParseCommitHeader(header)
+	if p := packagePrefix.FindStringSubmatch(header); !ok && p != nil {
+		refs = append(refs, p[1])
+		commit, ok = c.ParseCommitHeader(p[2])
+	}
+	if ok && commit.Scope != "" {
+		for _, scope := range strings.Split(commit.Scope, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				refs = append(refs, scope)
+			}
+		}
+	}
+	return refs
+}
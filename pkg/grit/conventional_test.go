@@ -0,0 +1,71 @@
+package grit_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestValidateCommitMessage(t *testing.T) {
+	config := grit.CommitConfig{}
+	for _, message := range []string{"feat(utils): add retries", "fix!: drop v1", "docs: readme\n\nlonger body"} {
+		if err := config.ValidateCommitMessage(message); err != nil {
+			t.Errorf("%q: %v", message, err)
+		}
+	}
+	for _, message := range []string{"add retries", "wip(utils): stuff", "feat(utils):"} {
+		if err := config.ValidateCommitMessage(message); !errors.Is(err, grit.ErrInvalidCommitMessage) {
+			t.Errorf("%q: expected ErrInvalidCommitMessage, got %v", message, err)
+		}
+	}
+
+	config = grit.CommitConfig{Types: []string{"feat", "wip"}, Pattern: `^\w+(\([\w/-]+\))?!?: [a-z].{0,20}$`}
+	if err := config.ValidateCommitMessage("wip(lib/utils): stuff"); err != nil {
+		t.Errorf("custom type: %v", err)
+	}
+	for _, message := range []string{"fix: not allowed", "feat: Capitalized", "feat: a subject that is far too long"} {
+		if err := config.ValidateCommitMessage(message); !errors.Is(err, grit.ErrInvalidCommitMessage) {
+			t.Errorf("%q: expected ErrInvalidCommitMessage, got %v", message, err)
+		}
+	}
+
+	config = grit.CommitConfig{Pattern: "("}
+	if err := config.ValidateCommitMessage("feat: x"); err == nil || errors.Is(err, grit.ErrInvalidCommitMessage) {
+		t.Errorf("expected an invalid pattern error, got %v", err)
+	}
+}
+
+func TestCommitReferences(t *testing.T) {
+	config := grit.CommitConfig{}
+	for message, want := range map[string][]string{
+		"utils: add retries":            {"utils"},
+		"lib/utils: fix: handle errors": {"lib/utils"},
+		"feat(utils, web)!: new config": {"utils", "web"},
+		"web: feat(forms): validation":  {"web", "forms"},
+		"fix(utils): handle errors":     {"utils"},
+		"fix: handle errors":            nil,
+		"update readme\n\nutils: body":  nil,
+	} {
+		if got := config.References(message); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: expected %v, got %v", message, want, got)
+		}
+	}
+}
+
+func TestConventionalCommitString(t *testing.T) {
+	commit := grit.ConventionalCommit{Type: "feat", Scope: "utils", Subject: "new config", Breaking: true, Body: "BREAKING CHANGE: old keys are gone"}
+	want := "feat(utils)!: new config\n\nBREAKING CHANGE: old keys are gone"
+	if got := commit.String(); got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	parsed, ok := grit.CommitConfig{}.ParseCommitHeader(want)
+	if !ok || parsed != commit {
+		t.Errorf("round trip gave %+v", parsed)
+	}
+	if _, ok := (grit.CommitConfig{Types: []string{"fix"}}).ParseCommitHeader(want); ok {
+		t.Errorf("feat should not be recognized when only fix is allowed")
+	}
+}
@@ -0,0 +1,73 @@
+package grit
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrDependencyNotAllowed = errors.New("dependency not allowed")
+	ErrDependencyCycle      = errors.New("dependency cycle")
+)
+
+// CanDependOn reports whether packages of one type may depend on packages of
+// another. A type without can_depend_on rules may depend on any type.
+func (c *RootConfig) CanDependOn(fromType string, toType string) bool {
+	rules := c.Types[fromType].CanDependOn
+	if len(rules) == 0 {
+		return true
+	}
+	for _, allowed := range rules {
+		if allowed == toType {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckDependency validates a new dependency of pkg on dep: the type rules of the
+// root config must allow it and it must not introduce a cycle
+func (idx *Index) CheckDependency(rootConfig *RootConfig, pkg Package, dep Package) error {
+	if pkg.ID() == dep.ID() {
+		return fmt.Errorf("%w: %s can't depend on itself", ErrDependencyCycle, pkg.ID())
+	}
+	if !rootConfig.CanDependOn(pkg.Type, dep.Type) {
+		return fmt.Errorf("%w: packages of type %s can only depend on %s, not on %s (%s)",
+			ErrDependencyNotAllowed, pkg.Type, strings.Join(rootConfig.Types[pkg.Type].CanDependOn, ", "), dep.Type, dep.ID())
+	}
+	if path := idx.dependencyPath(dep.ID(), pkg.ID()); path != nil {
+		cycle := append([]string{pkg.ID()}, path...)
+		return fmt.Errorf("%w: %s", ErrDependencyCycle, strings.Join(cycle, " → "))
+	}
+	return nil
+}
+
+// Find a chain of dependencies leading from one package to another, nil if
+// there is none
+func (idx *Index) dependencyPath(from string, to string) []string {
+	visited := make(map[string]bool)
+	var visit func(id string) []string
+	visit = func(id string) []string {
+		if id == to {
+			return []string{id}
+		}
+		if visited[id] {
+			return nil
+		}
+		visited[id] = true
+
+		i, ok := idx.byID[id]
+		if !ok {
+			return nil
+		}
+		depIDs, _ := idx.Dependencies(idx.packages[i].Package)
+		for _, depID := range depIDs {
+			if path := visit(depID); path != nil {
+				return append([]string{id}, path...)
+			}
+		}
+		return nil
+	}
+	return visit(from)
+}
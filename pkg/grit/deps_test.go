@@ -0,0 +1,50 @@
+package grit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestCheckDependency(t *testing.T) {
+	rootConfig := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"app":  {CanDependOn: []string{"lib"}},
+		"lib":  {CanDependOn: []string{"lib"}},
+		"tool": {},
+	}}
+	web := grit.Package{Name: "web", Type: "app", Dependencies: []string{"client"}}
+	client := grit.Package{Name: "client", Type: "lib", Dependencies: []string{"utils"}}
+	utils := grit.Package{Name: "utils", Type: "lib"}
+	cli := grit.Package{Name: "cli", Type: "tool"}
+	index := grit.NewIndex([]grit.Config{{Package: web}, {Package: client}, {Package: utils}, {Package: cli}})
+
+	tests := []struct {
+		name    string
+		pkg     grit.Package
+		dep     grit.Package
+		wantErr error
+	}{
+		{"allowed by rules", web, utils, nil},
+		{"type without rules", cli, web, nil},
+		{"not allowed by rules", utils, web, grit.ErrDependencyNotAllowed},
+		{"direct cycle", client, client, grit.ErrDependencyCycle},
+		{"transitive cycle", utils, client, grit.ErrDependencyCycle},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := index.CheckDependency(rootConfig, tt.pkg, tt.dep)
+			if tt.wantErr == nil && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+
+	err := index.CheckDependency(rootConfig, utils, client)
+	if err == nil || err.Error() != "dependency cycle: lib/utils → lib/client → lib/utils" {
+		t.Errorf("unexpected cycle error: %v", err)
+	}
+}
@@ -0,0 +1,306 @@
+package grit
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Document is a YAML file edited through its node tree, so comments, key order
+// and sections grit doesn't know about survive a round trip
+type Document struct {
+	path string
+	root *yaml.Node
+}
+
+// LoadDocument reads a YAML file for editing. A missing or empty file results in
+// an empty document that is created on Save.
+func LoadDocument(path string) (*Document, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	return ParseDocument(path, data)
+}
+
+// ParseDocument parses YAML content into a document that is saved to path
+func ParseDocument(path string, data []byte) (*Document, error) {
+	doc := &Document{path: path}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		root = yaml.Node{Kind: yaml.DocumentNode, HeadComment: root.HeadComment, FootComment: root.FootComment}
+		root.Content = []*yaml.Node{{Kind: yaml.MappingNode, Tag: "!!map"}}
+	}
+	if root.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("failed to parse %s: top level must be a mapping", path)
+	}
+
+	doc.root = &root
+	return doc, nil
+}
+
+// Path of the file the document is saved to
+func (d *Document) Path() string {
+	return d.path
+}
+
+// Decode the document into a config struct
+func (d *Document) Decode(v interface{}) error {
+	return d.root.Decode(v)
+}
+
+// Lookup returns the node at the key path, or nil if it doesn't exist
+func (d *Document) Lookup(path ...string) *yaml.Node {
+	node := d.root.Content[0]
+	for _, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return nil
+		}
+		_, value := mappingEntry(node, key)
+		if value == nil {
+			return nil
+		}
+		node = value
+	}
+	return node
+}
+
+// Set the value at the key path, creating intermediate mappings as needed. An
+// existing value is updated in place, keeping its comments and style.
+func (d *Document) Set(path []string, value interface{}) error {
+	if len(path) == 0 {
+		return d.Merge(value)
+	}
+
+	src := &yaml.Node{}
+	if err := src.Encode(value); err != nil {
+		return err
+	}
+
+	node := d.root.Content[0]
+	for i, key := range path {
+		if node.Kind != yaml.MappingNode {
+			return fmt.Errorf("cannot set %s: %s is not a mapping", strings.Join(path, "."), strings.Join(path[:i], "."))
+		}
+		_, child := mappingEntry(node, key)
+		last := i == len(path)-1
+		if child == nil {
+			child = &yaml.Node{Kind: yaml.MappingNode, Tag: "!!map"}
+			if last {
+				child = src
+			}
+			node.Content = append(node.Content, &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: key}, child)
+		} else if last {
+			mergeNode(child, src)
+		}
+		node = child
+	}
+	return nil
+}
+
+// Delete the value at the key path. Reports whether anything was removed.
+func (d *Document) Delete(path ...string) bool {
+	if len(path) == 0 {
+		return false
+	}
+	parent := d.Lookup(path[:len(path)-1]...)
+	if parent == nil || parent.Kind != yaml.MappingNode {
+		return false
+	}
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == path[len(path)-1] {
+			parent.Content = append(parent.Content[:i], parent.Content[i+2:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Merge a config struct into the document. Keys the struct doesn't produce are
+// left untouched, so use Delete to remove entries.
+func (d *Document) Merge(value interface{}) error {
+	src := &yaml.Node{}
+	if err := src.Encode(value); err != nil {
+		return err
+	}
+	mergeNode(d.root.Content[0], src)
+	return nil
+}
+
+// Normalize orders mapping keys to follow the fields of the schema struct, with
+// unknown keys kept after the known ones in their original order, and turns
+// flow style collections into block style
+func (d *Document) Normalize(schema interface{}) {
+	normalizeNode(d.root.Content[0], reflect.TypeOf(schema))
+}
+
+// Bytes encodes the document the same way EncodeConfig does
+func (d *Document) Bytes() ([]byte, error) {
+	if len(d.root.Content[0].Content) == 0 && d.root.HeadComment == "" {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	enc := yaml.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(d.root); err != nil {
+		return nil, err
+	}
+	if err := enc.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Save writes the document back to its file
+func (d *Document) Save() error {
+	data, err := d.Bytes()
+	if err != nil {
+		return fmt.Errorf("failed to encode %s: %w", d.path, err)
+	}
+	return os.WriteFile(d.path, data, 0644)
+}
+
+func mappingEntry(node *yaml.Node, key string) (*yaml.Node, *yaml.Node) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i], node.Content[i+1]
+		}
+	}
+	return nil, nil
+}
+
+// Merge src into dst in place. Mappings are merged key by key, sequences are
+// replaced while reusing existing items with the same value, and scalars keep
+// their comments and quoting.
+func mergeNode(dst *yaml.Node, src *yaml.Node) {
+	if dst.Kind != src.Kind {
+		head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+		*dst = *src
+		dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+		return
+	}
+
+	switch dst.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(src.Content); i += 2 {
+			_, value := mappingEntry(dst, src.Content[i].Value)
+			if value == nil {
+				dst.Content = append(dst.Content, src.Content[i], src.Content[i+1])
+				continue
+			}
+			mergeNode(value, src.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		existing := make(map[string]*yaml.Node)
+		for _, item := range dst.Content {
+			if item.Kind == yaml.ScalarNode {
+				existing[item.Value] = item
+			}
+		}
+		content := make([]*yaml.Node, 0, len(src.Content))
+		for _, item := range src.Content {
+			if match, ok := existing[item.Value]; ok && item.Kind == yaml.ScalarNode {
+				content = append(content, match)
+				delete(existing, item.Value)
+				continue
+			}
+			content = append(content, item)
+		}
+		dst.Content = content
+	case yaml.ScalarNode:
+		if dst.Tag != src.Tag && dst.Tag != "" {
+			dst.Style = src.Style
+		}
+		dst.Value = src.Value
+		dst.Tag = src.Tag
+	default:
+		dst.Content = src.Content
+	}
+}
+
+func normalizeNode(node *yaml.Node, t reflect.Type) {
+	if node == nil {
+		return
+	}
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	node.Style &^= yaml.FlowStyle
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		var elem reflect.Type
+		if t != nil && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) {
+			elem = t.Elem()
+		}
+		for _, item := range node.Content {
+			normalizeNode(item, elem)
+		}
+	case yaml.MappingNode:
+		if t != nil && t.Kind() == reflect.Map {
+			for i := 1; i < len(node.Content); i += 2 {
+				normalizeNode(node.Content[i], t.Elem())
+			}
+			return
+		}
+
+		fields := make(map[string]reflect.Type)
+		var order []string
+		if t != nil && t.Kind() == reflect.Struct {
+			for i := 0; i < t.NumField(); i++ {
+				name := yamlFieldName(t.Field(i))
+				if name != "" {
+					fields[name] = t.Field(i).Type
+					order = append(order, name)
+				}
+			}
+		}
+
+		var sorted, unknown []*yaml.Node
+		for _, name := range order {
+			if key, value := mappingEntry(node, name); key != nil {
+				sorted = append(sorted, key, value)
+			}
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if _, known := fields[node.Content[i].Value]; !known {
+				unknown = append(unknown, node.Content[i], node.Content[i+1])
+			}
+		}
+		node.Content = append(sorted, unknown...)
+
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// The encoder misplaces line comments of block collections, so keep
+			// them on the key instead
+			if value.Kind != yaml.ScalarNode && value.LineComment != "" && key.LineComment == "" {
+				key.LineComment, value.LineComment = value.LineComment, ""
+			}
+			normalizeNode(value, fields[key.Value])
+		}
+	}
+}
+
+// The key a struct field is marshalled under, or "" if it isn't marshalled
+func yamlFieldName(field reflect.StructField) string {
+	if field.PkgPath != "" {
+		return ""
+	}
+	tag := field.Tag.Get("yaml")
+	if tag == "-" {
+		return ""
+	}
+	name := strings.Split(tag, ",")[0]
+	if name == "" {
+		name = strings.ToLower(field.Name)
+	}
+	return name
+}
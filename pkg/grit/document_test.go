@@ -0,0 +1,167 @@
+package grit_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func loadDocument(t *testing.T, content string) *grit.Document {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "grit.yaml")
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return doc
+}
+
+func documentString(t *testing.T, doc *grit.Document) string {
+	t.Helper()
+	data, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestDocumentRoundTrip(t *testing.T) {
+	content := `# Workspace config
+repo:
+  name: demo # the repo
+types:
+  lib:
+    package_dir: packages/lib
+# Local additions
+custom:
+  keep: me
+`
+	doc := loadDocument(t, content)
+	if got := documentString(t, doc); got != content {
+		t.Errorf("round trip changed the document:\n%s", got)
+	}
+}
+
+func TestDocumentSet(t *testing.T) {
+	doc := loadDocument(t, `types:
+  lib:
+    package_dir: packages/lib # libraries
+custom: true
+`)
+
+	if err := doc.Set([]string{"types", "app"}, grit.TypeConfig{PackageDir: "packages/app"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Set([]string{"types", "lib", "package_dir"}, "libs"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Set([]string{"repo", "name"}, "demo"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `types:
+  lib:
+    package_dir: libs # libraries
+  app:
+    package_dir: packages/app
+custom: true
+repo:
+  name: demo
+`
+	if got := documentString(t, doc); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	if err := doc.Set([]string{"custom", "nested"}, "x"); err == nil {
+		t.Error("expected an error setting a key below a scalar")
+	}
+}
+
+func TestDocumentDeleteAndMerge(t *testing.T) {
+	doc := loadDocument(t, `package:
+  name: web
+  dependencies: [core, "ghost"]
+targets:
+  build: make
+`)
+
+	if !doc.Delete("targets", "build") {
+		t.Error("expected targets.build to be removed")
+	}
+	if doc.Delete("targets", "missing") {
+		t.Error("removing a missing key should report false")
+	}
+
+	var cfg grit.Config
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Package.Version = "1.0.0"
+	cfg.Package.Dependencies = []string{"ghost"}
+	if err := doc.Merge(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	got := documentString(t, doc)
+	if !strings.Contains(got, `dependencies: ["ghost"]`) {
+		t.Errorf("expected the existing quoted item to be kept:\n%s", got)
+	}
+	if !strings.Contains(got, "version: 1.0.0") {
+		t.Errorf("expected the version to be merged in:\n%s", got)
+	}
+	if doc.Lookup("targets", "build") != nil {
+		t.Error("merge should not bring back deleted keys")
+	}
+}
+
+func TestDocumentNormalize(t *testing.T) {
+	doc := loadDocument(t, `targets: {build: "npm run build"} # custom
+custom_section:
+  keep: me
+package:
+  dependencies: [core] # deps
+  name: web # the name
+`)
+	doc.Normalize(grit.Config{})
+
+	want := `package:
+  name: web # the name
+  dependencies: # deps
+    - core
+targets: # custom
+  build: "npm run build"
+custom_section:
+  keep: me
+`
+	if got := documentString(t, doc); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestLoadDocumentMissingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "grit.yaml")
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Set([]string{"types", "lib", "package_dir"}, "packages/lib"); err != nil {
+		t.Fatal(err)
+	}
+	if err := doc.Save(); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := grit.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Types["lib"].PackageDir != "packages/lib" {
+		t.Errorf("unexpected config: %+v", cfg)
+	}
+}
@@ -0,0 +1,148 @@
+package grit
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// Filter selects packages by their fields. A filter expression is a comma
+// separated list of terms that must all match. A term is key:value with one of
+// the keys below, or a bare value that matches a tag. Prefix a term with ! to
+// negate it.
+//
+//	tag:frontend     has the tag frontend
+//	type:lib         is of type lib
+//	owner:@org/team  is owned by @org/team
+//	name:api-*       name or type/name matches the glob
+//	private:true     is private (or false for public)
+type Filter struct {
+	expr  string
+	terms []filterTerm
+}
+
+type filterTerm struct {
+	key    string
+	value  string
+	negate bool
+}
+
+var filterKeys = map[string]bool{"tag": true, "type": true, "owner": true, "name": true, "private": true}
+
+// ParseFilter parses a filter expression
+func ParseFilter(expr string) (*Filter, error) {
+	filter := &Filter{expr: expr}
+	for _, raw := range strings.Split(expr, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+
+		term := filterTerm{key: "tag"}
+		if strings.HasPrefix(raw, "!") {
+			term.negate = true
+			raw = strings.TrimSpace(raw[1:])
+		}
+		term.value = raw
+		if key, value, found := strings.Cut(raw, ":"); found {
+			term.key, term.value = key, value
+		}
+
+		if !filterKeys[term.key] {
+			return nil, fmt.Errorf("invalid filter %q: unknown key %s", expr, term.key)
+		}
+		if term.value == "" {
+			return nil, fmt.Errorf("invalid filter %q: empty value for %s", expr, term.key)
+		}
+		switch term.key {
+		case "name":
+			if _, err := path.Match(term.value, ""); err != nil {
+				return nil, fmt.Errorf("invalid filter %q: %w", expr, err)
+			}
+		case "private":
+			if _, err := strconv.ParseBool(term.value); err != nil {
+				return nil, fmt.Errorf("invalid filter %q: private must be true or false", expr)
+			}
+		}
+		filter.terms = append(filter.terms, term)
+	}
+
+	if len(filter.terms) == 0 {
+		return nil, fmt.Errorf("invalid filter %q: no terms", expr)
+	}
+	return filter, nil
+}
+
+// ParseFilters parses several filter expressions, as given by repeated flags
+func ParseFilters(exprs []string) ([]*Filter, error) {
+	filters := make([]*Filter, 0, len(exprs))
+	for _, expr := range exprs {
+		filter, err := ParseFilter(expr)
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, filter)
+	}
+	return filters, nil
+}
+
+func (f *Filter) String() string {
+	return f.expr
+}
+
+// Match reports whether the package matches every term of the filter
+func (f *Filter) Match(pkg Package) bool {
+	for _, term := range f.terms {
+		if term.match(pkg) == term.negate {
+			return false
+		}
+	}
+	return true
+}
+
+func (t filterTerm) match(pkg Package) bool {
+	switch t.key {
+	case "tag":
+		return containsValue(pkg.Tags, t.value)
+	case "type":
+		return pkg.Type == t.value
+	case "owner":
+		return containsValue(pkg.Owners, t.value)
+	case "name":
+		nameMatch, _ := path.Match(t.value, pkg.Name)
+		idMatch, _ := path.Match(t.value, pkg.ID())
+		return nameMatch || idMatch
+	case "private":
+		private, _ := strconv.ParseBool(t.value)
+		return pkg.Private == private
+	}
+	return false
+}
+
+// FilterPackages returns the packages matching any of the filters. Without
+// filters every package is returned.
+func FilterPackages(packages []Config, filters []*Filter) []Config {
+	if len(filters) == 0 {
+		return packages
+	}
+	var result []Config
+	for _, cfg := range packages {
+		for _, filter := range filters {
+			if filter.Match(cfg.Package) {
+				result = append(result, cfg)
+				break
+			}
+		}
+	}
+	return result
+}
+
+func containsValue(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
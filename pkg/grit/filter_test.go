@@ -0,0 +1,82 @@
+package grit_test
+
+import (
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestFilterMatch(t *testing.T) {
+	web := grit.Package{Name: "web", Type: "app", Tags: []string{"frontend"}, Owners: []string{"@org/web"}}
+	api := grit.Package{Name: "api-gateway", Type: "service", Tags: []string{"backend"}, Owners: []string{"@org/platform"}, Private: true}
+	utils := grit.Package{Name: "utils", Type: "lib", Tags: []string{"frontend", "backend"}}
+
+	tests := []struct {
+		expr string
+		want []string
+	}{
+		{"frontend", []string{"web", "utils"}},
+		{"tag:backend,!type:lib", []string{"api-gateway"}},
+		{"owner:@org/platform", []string{"api-gateway"}},
+		{"name:api-*", []string{"api-gateway"}},
+		{"name:lib/*", []string{"utils"}},
+		{"private:false", []string{"web", "utils"}},
+		{"!private:true, frontend", []string{"web", "utils"}},
+	}
+	for _, tt := range tests {
+		filter, err := grit.ParseFilter(tt.expr)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.expr, err)
+		}
+		var got []string
+		for _, pkg := range []grit.Package{web, api, utils} {
+			if filter.Match(pkg) {
+				got = append(got, pkg.Name)
+			}
+		}
+		if !equalStrings(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for _, expr := range []string{"", " , ", "color:red", "tag:", "name:[", "private:maybe"} {
+		if _, err := grit.ParseFilter(expr); err == nil {
+			t.Errorf("expected an error for %q", expr)
+		}
+	}
+}
+
+func TestFilterPackages(t *testing.T) {
+	packages := []grit.Config{
+		{Package: grit.Package{Name: "web", Type: "app"}},
+		{Package: grit.Package{Name: "api", Type: "service"}},
+		{Package: grit.Package{Name: "utils", Type: "lib"}},
+	}
+
+	filters, err := grit.ParseFilters([]string{"type:app", "type:lib"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := packageNames(grit.FilterPackages(packages, filters))
+	if !equalStrings(got, []string{"utils", "web"}) {
+		t.Errorf("got %v", got)
+	}
+
+	if len(grit.FilterPackages(packages, nil)) != len(packages) {
+		t.Error("expected every package without filters")
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,188 @@
+package grit
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the grit specific ignore file, read with gitignore semantics
+const IgnoreFileName = ".gritignore"
+
+// DefaultIgnorePatterns are always skipped during package discovery
+var DefaultIgnorePatterns = []string{
+	".git/",
+	".grit/",
+	"node_modules/",
+	"vendor/",
+}
+
+type ignoreRule struct {
+	base    string // directory the rule was defined in, relative to the workspace root
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreMatcher matches workspace relative paths against gitignore style patterns
+type IgnoreMatcher struct {
+	rules []ignoreRule
+}
+
+func NewIgnoreMatcher() *IgnoreMatcher {
+	return &IgnoreMatcher{}
+}
+
+// AddPatterns adds patterns defined in the directory base (slash separated, relative to the workspace root)
+func (m *IgnoreMatcher) AddPatterns(base string, patterns []string) {
+	base = strings.Trim(filepath.ToSlash(base), "/")
+	if base == "." {
+		base = ""
+	}
+	for _, pattern := range patterns {
+		if rule, ok := compileIgnorePattern(base, pattern); ok {
+			m.rules = append(m.rules, rule)
+		}
+	}
+}
+
+// AddFile reads patterns from an ignore file. A missing file is not an error.
+func (m *IgnoreMatcher) AddFile(base string, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer file.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		patterns = append(patterns, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	m.AddPatterns(base, patterns)
+	return nil
+}
+
+// Match reports whether the workspace relative path is ignored. Later rules take
+// precedence over earlier ones, so negated patterns can re-include paths.
+func (m *IgnoreMatcher) Match(rel string, isDir bool) bool {
+	rel = strings.Trim(filepath.ToSlash(rel), "/")
+	ignored := false
+	for _, rule := range m.rules {
+		if rule.dirOnly && !isDir {
+			continue
+		}
+		sub := rel
+		if rule.base != "" {
+			if !strings.HasPrefix(rel, rule.base+"/") {
+				continue
+			}
+			sub = strings.TrimPrefix(rel, rule.base+"/")
+		}
+		if rule.regex.MatchString(sub) {
+			ignored = !rule.negate
+		}
+	}
+	return ignored
+}
+
+func compileIgnorePattern(base string, pattern string) (ignoreRule, bool) {
+	pattern = strings.TrimRight(pattern, " \t\r")
+	if strings.HasSuffix(pattern, "\\") {
+		pattern += " "
+	}
+	if pattern == "" || strings.HasPrefix(pattern, "#") {
+		return ignoreRule{}, false
+	}
+
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(pattern, "!") {
+		rule.negate = true
+		pattern = pattern[1:]
+	} else if strings.HasPrefix(pattern, "\\!") || strings.HasPrefix(pattern, "\\#") {
+		pattern = pattern[1:]
+	}
+
+	if strings.HasSuffix(pattern, "/") {
+		rule.dirOnly = true
+		pattern = strings.TrimRight(pattern, "/")
+	}
+	if pattern == "" {
+		return ignoreRule{}, false
+	}
+
+	// A slash anywhere but the end anchors the pattern to its base directory
+	anchored := strings.Contains(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+
+	expr := globToRegex(pattern)
+	if anchored {
+		expr = "^" + expr + "$"
+	} else {
+		expr = "^(?:.*/)?" + expr + "$"
+	}
+
+	regex, err := regexp.Compile(expr)
+	if err != nil {
+		return ignoreRule{}, false
+	}
+	rule.regex = regex
+	return rule, true
+}
+
+// Translate a gitignore glob into a regular expression
+func globToRegex(pattern string) string {
+	var sb strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			if i+1 < len(pattern) && pattern[i+1] == '*' {
+				atStart := i == 0 || pattern[i-1] == '/'
+				atEnd := i+2 == len(pattern)
+				if atStart && atEnd {
+					sb.WriteString(".*")
+					i++
+					continue
+				}
+				if atStart && pattern[i+2] == '/' {
+					sb.WriteString("(?:.*/)?")
+					i += 2
+					continue
+				}
+			}
+			sb.WriteString("[^/]*")
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			if end < 0 {
+				sb.WriteString(regexp.QuoteMeta(string(c)))
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, "\\", "\\\\") + "]")
+			i += end + 1
+		case '\\':
+			if i+1 < len(pattern) {
+				i++
+				sb.WriteString(regexp.QuoteMeta(string(pattern[i])))
+			}
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return sb.String()
+}
@@ -0,0 +1,43 @@
+package grit_test
+
+import (
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestIgnoreMatcher(t *testing.T) {
+	tests := []struct {
+		name     string
+		base     string
+		patterns []string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{name: "unanchored name matches at any depth", patterns: []string{"node_modules/"}, path: "packages/lib/a/node_modules", isDir: true, want: true},
+		{name: "directory pattern ignores only directories", patterns: []string{"build/"}, path: "packages/lib/build", want: false},
+		{name: "anchored pattern only matches from base", patterns: []string{"/build"}, path: "packages/build", isDir: true, want: false},
+		{name: "anchored pattern matches at base", patterns: []string{"/build"}, path: "build", isDir: true, want: true},
+		{name: "wildcard", patterns: []string{"*.log"}, path: "a/b/debug.log", want: true},
+		{name: "wildcard does not cross directories", patterns: []string{"a/*.log"}, path: "a/b/debug.log", want: false},
+		{name: "double star", patterns: []string{"a/**/tmp"}, path: "a/b/c/tmp", isDir: true, want: true},
+		{name: "leading double star", patterns: []string{"**/fixtures"}, path: "x/fixtures", isDir: true, want: true},
+		{name: "negation re-includes", patterns: []string{"*.yaml", "!grit.yaml"}, path: "pkg/grit.yaml", want: false},
+		{name: "last match wins", patterns: []string{"!grit.yaml", "*.yaml"}, path: "pkg/grit.yaml", want: true},
+		{name: "comments and blanks are skipped", patterns: []string{"# grit.yaml", ""}, path: "grit.yaml", want: false},
+		{name: "nested rules apply below their base", base: "packages/lib", patterns: []string{"gen"}, path: "packages/lib/x/gen", isDir: true, want: true},
+		{name: "nested rules do not apply elsewhere", base: "packages/lib", patterns: []string{"gen"}, path: "packages/app/gen", isDir: true, want: false},
+		{name: "character class", patterns: []string{"tmp[0-9]"}, path: "tmp3", want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := grit.NewIgnoreMatcher()
+			m.AddPatterns(tt.base, tt.patterns)
+			if got := m.Match(tt.path, tt.isDir); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package grit
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	ErrPackageNotFound  = errors.New("package not found")
+	ErrAmbiguousPackage = errors.New("ambiguous package reference")
+)
+
+// Index resolves package references. A reference is either a bare package name
+// or a fully qualified type/name.
+type Index struct {
+	packages []Config
+	byID     map[string]int
+	byName   map[string][]int
+}
+
+func NewIndex(packages []Config) *Index {
+	idx := &Index{
+		byID:   make(map[string]int),
+		byName: make(map[string][]int),
+	}
+	for _, cfg := range packages {
+		if cfg.Package.Name == "" {
+			continue // Skip root config
+		}
+		idx.byID[cfg.Package.ID()] = len(idx.packages)
+		idx.byName[cfg.Package.Name] = append(idx.byName[cfg.Package.Name], len(idx.packages))
+		idx.packages = append(idx.packages, cfg)
+	}
+	return idx
+}
+
+// Packages returns the indexed packages
+func (idx *Index) Packages() []Config {
+	return idx.packages
+}
+
+// Lookup resolves a bare name or type/name reference
+func (idx *Index) Lookup(ref string) (Config, error) {
+	if i, ok := idx.byID[ref]; ok {
+		return idx.packages[i], nil
+	}
+
+	matches := idx.byName[ref]
+	switch len(matches) {
+	case 0:
+		return Config{}, fmt.Errorf("%w: %s", ErrPackageNotFound, ref)
+	case 1:
+		return idx.packages[matches[0]], nil
+	}
+
+	candidates := make([]string, 0, len(matches))
+	for _, i := range matches {
+		candidates = append(candidates, idx.packages[i].Package.ID())
+	}
+	sort.Strings(candidates)
+	return Config{}, fmt.Errorf("%w: %s could be %s", ErrAmbiguousPackage, ref, strings.Join(candidates, ", "))
+}
+
+// Name returns the shortest unambiguous reference for a package: its bare name,
+// or type/name when a package of another type has the same name
+func (idx *Index) Name(pkg Package) string {
+	if len(idx.byName[pkg.Name]) > 1 {
+		return pkg.ID()
+	}
+	return pkg.Name
+}
+
+// DependencyError describes a dependency reference that could not be resolved
+type DependencyError struct {
+	Ref string
+	Err error
+}
+
+func (e *DependencyError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DependencyError) Unwrap() error {
+	return e.Err
+}
+
+// Dependencies resolves the dependencies of a package to package IDs. References
+// that don't resolve are returned separately so callers can decide how to report them.
+func (idx *Index) Dependencies(pkg Package) ([]string, []*DependencyError) {
+	var ids []string
+	var errs []*DependencyError
+	for _, ref := range pkg.Dependencies {
+		dep, err := idx.Lookup(ref)
+		if err != nil {
+			errs = append(errs, &DependencyError{Ref: ref, Err: err})
+			continue
+		}
+		ids = append(ids, dep.Package.ID())
+	}
+	return ids, errs
+}
+
+// Detect packages that share the same type/name
+func checkDuplicatePackages(packages []Config) error {
+	seen := make(map[string]string)
+	var duplicates []string
+	for _, cfg := range packages {
+		if cfg.Package.Name == "" {
+			continue
+		}
+		id := cfg.Package.ID()
+		if first, exists := seen[id]; exists {
+			duplicates = append(duplicates, fmt.Sprintf("%s (%s and %s)", id, first, cfg.Package.Path))
+			continue
+		}
+		seen[id] = cfg.Package.Path
+	}
+
+	if len(duplicates) > 0 {
+		return fmt.Errorf("duplicate package names: %s", strings.Join(duplicates, "; "))
+	}
+	return nil
+}
+
+// TypeForPath returns the type whose package_dir contains the workspace relative
+// directory. The most specific package_dir wins when they are nested.
+func (c *RootConfig) TypeForPath(relDir string) string {
+	relDir = filepath.ToSlash(filepath.Clean(relDir))
+	var match string
+	var matchLen int
+	for typeName, typeConfig := range c.Types {
+		if typeConfig.PackageDir == "" {
+			continue
+		}
+		pkgDir := filepath.ToSlash(filepath.Clean(typeConfig.PackageDir))
+		if pkgDir != "." && relDir != pkgDir && !strings.HasPrefix(relDir, pkgDir+"/") {
+			continue
+		}
+		// The workspace root contains every other package_dir
+		if pkgDir == "." {
+			pkgDir = ""
+		}
+		if match == "" || len(pkgDir) > matchLen || (len(pkgDir) == matchLen && typeName < match) {
+			match = typeName
+			matchLen = len(pkgDir)
+		}
+	}
+	return match
+}
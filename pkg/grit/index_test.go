@@ -0,0 +1,121 @@
+package grit_test
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func testIndex() *grit.Index {
+	return grit.NewIndex([]grit.Config{
+		{Package: grit.Package{Name: "utils", Type: "lib"}},
+		{Package: grit.Package{Name: "utils", Type: "tool"}},
+		{Package: grit.Package{Name: "api", Type: "service", Dependencies: []string{"lib/utils", "utils", "missing"}}},
+	})
+}
+
+func TestIndexLookup(t *testing.T) {
+	idx := testIndex()
+
+	tests := []struct {
+		ref     string
+		wantID  string
+		wantErr error
+	}{
+		{ref: "api", wantID: "service/api"},
+		{ref: "service/api", wantID: "service/api"},
+		{ref: "tool/utils", wantID: "tool/utils"},
+		{ref: "utils", wantErr: grit.ErrAmbiguousPackage},
+		{ref: "nope", wantErr: grit.ErrPackageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			got, err := idx.Lookup(tt.ref)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Lookup(%q) error = %v, want %v", tt.ref, err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Lookup(%q) error = %v", tt.ref, err)
+			}
+			if got.Package.ID() != tt.wantID {
+				t.Errorf("Lookup(%q) = %s, want %s", tt.ref, got.Package.ID(), tt.wantID)
+			}
+		})
+	}
+}
+
+func TestIndexNameAndDependencies(t *testing.T) {
+	idx := testIndex()
+
+	if got := idx.Name(grit.Package{Name: "utils", Type: "lib"}); got != "lib/utils" {
+		t.Errorf("Name() = %s, want lib/utils", got)
+	}
+	if got := idx.Name(grit.Package{Name: "api", Type: "service"}); got != "api" {
+		t.Errorf("Name() = %s, want api", got)
+	}
+
+	api, _ := idx.Lookup("api")
+	ids, errs := idx.Dependencies(api.Package)
+	if len(ids) != 1 || ids[0] != "lib/utils" {
+		t.Errorf("Dependencies() ids = %v, want [lib/utils]", ids)
+	}
+	if len(errs) != 2 || errs[0].Ref != "utils" || errs[1].Ref != "missing" {
+		t.Errorf("Dependencies() errors = %v", errs)
+	}
+}
+
+func TestTypeForPath(t *testing.T) {
+	cfg := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"lib":      {PackageDir: "packages/lib"},
+		"internal": {PackageDir: "packages/lib/internal"},
+		"app":      {PackageDir: "packages/app"},
+	}}
+
+	tests := map[string]string{
+		"packages/lib/foo":          "lib",
+		"packages/lib/internal/bar": "internal",
+		"packages/libs/foo":         "",
+		"packages/app/web":          "app",
+	}
+	for path, want := range tests {
+		if got := cfg.TypeForPath(path); got != want {
+			t.Errorf("TypeForPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoadPackagesDuplicates(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "grit.yaml"), `
+types:
+  lib:
+    package_dir: packages/lib
+  tool:
+    package_dir: packages/tool
+`)
+	writeFile(t, filepath.Join(root, "packages/lib/utils/grit.yaml"), "package:\n  name: utils\n")
+	writeFile(t, filepath.Join(root, "packages/tool/utils/grit.yaml"), "package:\n  name: utils\n")
+
+	packages, err := grit.NewPackageManager(root).LoadPackages()
+	if err != nil {
+		t.Fatalf("same name in different types should load, got %v", err)
+	}
+	for _, cfg := range packages {
+		if cfg.Package.Type == "" {
+			t.Errorf("package %s has no type", cfg.Package.Path)
+		}
+	}
+
+	writeFile(t, filepath.Join(root, "packages/lib/copy/grit.yaml"), "package:\n  name: utils\n")
+	_, err = grit.NewPackageManager(root).LoadPackages()
+	if err == nil || !strings.Contains(err.Error(), "lib/utils") {
+		t.Errorf("LoadPackages() error = %v, want duplicate lib/utils", err)
+	}
+}
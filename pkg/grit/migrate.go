@@ -0,0 +1,228 @@
+package grit
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaVersion is the newest config schema this version of grit understands.
+// Files without a version field are at version 0.
+const SchemaVersion = 1
+
+// ErrNewerSchema is returned for config files written for a newer version of grit
+var ErrNewerSchema = errors.New("config schema is newer than supported")
+
+// SchemaError reports a config file with a schema version grit doesn't support
+type SchemaError struct {
+	Path    string
+	Version int
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("%s uses config schema version %d, but this version of grit supports up to %d; upgrade grit to use this workspace", e.Path, e.Version, SchemaVersion)
+}
+
+func (e *SchemaError) Unwrap() error {
+	return ErrNewerSchema
+}
+
+func checkSchemaVersion(path string, version int) error {
+	if version > SchemaVersion {
+		return &SchemaError{Path: path, Version: version}
+	}
+	return nil
+}
+
+// ConfigKind tells root and package config files apart
+type ConfigKind int
+
+const (
+	RootConfigKind ConfigKind = iota
+	PackageConfigKind
+	RootPackageConfigKind // A root config with a package section, which gets both steps
+)
+
+// Migration upgrades config files to a schema version. Either function may be
+// nil if the files of that kind don't change.
+type Migration struct {
+	Version     int
+	Description string
+	Root        func(doc *Document) error
+	Package     func(doc *Document) error
+}
+
+var migrations []Migration
+
+// RegisterMigration adds a migration step. Steps are applied in version order
+// and there can only be one step per version.
+func RegisterMigration(m Migration) {
+	if m.Version < 1 || m.Version > SchemaVersion {
+		panic(fmt.Sprintf("migration to schema version %d is out of range", m.Version))
+	}
+	for _, existing := range migrations {
+		if existing.Version == m.Version {
+			panic(fmt.Sprintf("migration to schema version %d is already registered", m.Version))
+		}
+	}
+	migrations = append(migrations, m)
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+}
+
+// SchemaVersion of the document, 0 if it has no version field
+func (d *Document) SchemaVersion() (int, error) {
+	node := d.Lookup("version")
+	if node == nil {
+		return 0, nil
+	}
+	version, err := strconv.Atoi(node.Value)
+	if err != nil || node.Kind != yaml.ScalarNode {
+		return 0, fmt.Errorf("%s: invalid schema version %q", d.path, node.Value)
+	}
+	return version, nil
+}
+
+// Migrate upgrades the document to SchemaVersion in place and returns the
+// descriptions of the steps that were applied. A document at a newer version
+// results in a SchemaError.
+func Migrate(doc *Document, kind ConfigKind) ([]string, error) {
+	version, err := doc.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if err := checkSchemaVersion(doc.path, version); err != nil {
+		return nil, err
+	}
+	if version == SchemaVersion {
+		return nil, nil
+	}
+
+	var applied []string
+	for _, m := range migrations {
+		if m.Version <= version {
+			continue
+		}
+		var steps []func(doc *Document) error
+		if kind != PackageConfigKind && m.Root != nil {
+			steps = append(steps, m.Root)
+		}
+		if kind != RootConfigKind && m.Package != nil {
+			steps = append(steps, m.Package)
+		}
+		if len(steps) == 0 {
+			continue
+		}
+		for _, step := range steps {
+			if err := step(doc); err != nil {
+				return nil, fmt.Errorf("migration to schema version %d failed: %w", m.Version, err)
+			}
+		}
+		applied = append(applied, m.Description)
+	}
+
+	doc.setSchemaVersion(SchemaVersion)
+	return applied, nil
+}
+
+// Set the version field, adding it as the first key so it is the first thing
+// read in the file
+func (d *Document) setSchemaVersion(version int) {
+	value := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!int", Value: strconv.Itoa(version)}
+	if node := d.Lookup("version"); node != nil {
+		mergeNode(node, value)
+		return
+	}
+
+	root := d.root.Content[0]
+	key := &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!str", Value: "version"}
+	if len(root.Content) > 0 {
+		// Keep a comment at the top of the file above the new key
+		key.HeadComment, root.Content[0].HeadComment = root.Content[0].HeadComment, ""
+	}
+	root.Content = append([]*yaml.Node{key, value}, root.Content...)
+}
+
+func init() {
+	RegisterMigration(Migration{
+		Version:     1,
+		Description: "remove fields grit no longer reads and empty values",
+		Root:        migrateRootV1,
+		Package:     migratePackageV1,
+	})
+}
+
+// Files written before versioning had every field present, empty or not, and
+// could have targets with a nested mapping instead of a command
+func migrateRootV1(doc *Document) error {
+	if repo := doc.Lookup("repo"); repo != nil {
+		pruneEmpty(repo)
+		if isEmptyNode(repo) {
+			doc.Delete("repo")
+		}
+	}
+
+	if targets := doc.Lookup("targets"); targets != nil && targets.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(targets.Content); {
+			if targets.Content[i+1].Kind != yaml.ScalarNode {
+				targets.Content = append(targets.Content[:i], targets.Content[i+2:]...)
+				continue
+			}
+			i += 2
+		}
+		if isEmptyNode(targets) {
+			doc.Delete("targets")
+		}
+	}
+
+	if types := doc.Lookup("types"); types != nil && types.Kind == yaml.MappingNode {
+		for i := 1; i < len(types.Content); i += 2 {
+			pruneEmpty(types.Content[i])
+		}
+	}
+	return nil
+}
+
+// Package files used to store their path and an empty hash, an empty types
+// section and empty commands for every target
+func migratePackageV1(doc *Document) error {
+	doc.Delete("package", "path")
+	if hash := doc.Lookup("package", "hash"); hash != nil && isEmptyNode(hash) {
+		doc.Delete("package", "hash")
+	}
+	if types := doc.Lookup("types"); types != nil && isEmptyNode(types) {
+		doc.Delete("types")
+	}
+	if targets := doc.Lookup("targets"); targets != nil && targets.Kind == yaml.MappingNode {
+		pruneEmpty(targets)
+	}
+	return nil
+}
+
+// Remove the entries of a mapping whose values are empty
+func pruneEmpty(node *yaml.Node) {
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); {
+		if isEmptyNode(node.Content[i+1]) {
+			node.Content = append(node.Content[:i], node.Content[i+2:]...)
+			continue
+		}
+		i += 2
+	}
+}
+
+func isEmptyNode(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value == "" || node.Tag == "!!null"
+	case yaml.MappingNode, yaml.SequenceNode:
+		return len(node.Content) == 0
+	}
+	return false
+}
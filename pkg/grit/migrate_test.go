@@ -0,0 +1,148 @@
+package grit_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestMigrateRootConfig(t *testing.T) {
+	doc := loadDocument(t, `# Workspace
+types:
+  lib:
+    package_dir: packages/lib
+    build_dir: ""
+    targets: {}
+targets:
+  lib:
+    default_tasks:
+      - build
+  build: make
+repo:
+  name: demo
+  url: ""
+`)
+
+	steps, err := grit.Migrate(doc, grit.RootConfigKind)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 1 {
+		t.Errorf("expected one migration step, got %v", steps)
+	}
+
+	want := `# Workspace
+version: 1
+types:
+  lib:
+    package_dir: packages/lib
+targets:
+  build: make
+repo:
+  name: demo
+`
+	if got := documentString(t, doc); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	// Migrating again is a no-op
+	steps, err = grit.Migrate(doc, grit.RootConfigKind)
+	if err != nil || len(steps) != 0 {
+		t.Errorf("expected no steps, got %v, %v", steps, err)
+	}
+	if got := documentString(t, doc); got != want {
+		t.Errorf("second migration changed the document:\n%s", got)
+	}
+}
+
+func TestMigratePackageConfig(t *testing.T) {
+	doc := loadDocument(t, `targets:
+  build: ""
+  test: go test
+types: {}
+package:
+  name: core
+  version: 0.1.0
+  dependencies: []
+  hash: ""
+  path: packages/lib/core/grit.yaml
+`)
+
+	if _, err := grit.Migrate(doc, grit.PackageConfigKind); err != nil {
+		t.Fatal(err)
+	}
+
+	var cfg grit.Config
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Version != grit.SchemaVersion {
+		t.Errorf("expected version %d, got %d", grit.SchemaVersion, cfg.Version)
+	}
+	if !reflect.DeepEqual(cfg.Targets, map[string]string{"test": "go test"}) {
+		t.Errorf("unexpected targets: %v", cfg.Targets)
+	}
+	for _, path := range [][]string{{"types"}, {"package", "hash"}, {"package", "path"}} {
+		if doc.Lookup(path...) != nil {
+			t.Errorf("expected %v to be removed", path)
+		}
+	}
+}
+
+func TestMigrateRootPackageConfig(t *testing.T) {
+	doc := loadDocument(t, `repo:
+  name: ""
+package:
+  name: utils
+  hash: ""
+types:
+  lib:
+    package_dir: .
+    build_dir: ""
+`)
+
+	if _, err := grit.Migrate(doc, grit.RootPackageConfigKind); err != nil {
+		t.Fatal(err)
+	}
+
+	// The steps of both kinds apply to the one file
+	for _, path := range [][]string{{"repo"}, {"package", "hash"}, {"types", "lib", "build_dir"}} {
+		if doc.Lookup(path...) != nil {
+			t.Errorf("expected %v to be removed", path)
+		}
+	}
+	if doc.Lookup("package", "name") == nil || doc.Lookup("types", "lib", "package_dir") == nil {
+		t.Errorf("expected the package and type to be kept:\n%s", documentString(t, doc))
+	}
+}
+
+func TestNewerSchemaIsRefused(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "grit.yaml")
+	if err := os.WriteFile(path, []byte("version: 99\ntypes: {}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := grit.LoadConfig(path); !errors.Is(err, grit.ErrNewerSchema) {
+		t.Errorf("expected ErrNewerSchema from LoadConfig, got %v", err)
+	}
+
+	doc, err := grit.LoadDocument(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var schemaErr *grit.SchemaError
+	if _, err := grit.Migrate(doc, grit.RootConfigKind); !errors.As(err, &schemaErr) || schemaErr.Version != 99 {
+		t.Errorf("expected a SchemaError for version 99, got %v", err)
+	}
+
+	writeFile(t, filepath.Join(dir, "grit.yaml"), "types:\n  lib:\n    package_dir: packages/lib\n")
+	writeFile(t, filepath.Join(dir, "packages/lib/core/grit.yaml"), "version: 99\npackage:\n  name: core\n")
+	if _, err := grit.NewPackageManager(dir).LoadPackages(); !errors.Is(err, grit.ErrNewerSchema) {
+		t.Errorf("expected ErrNewerSchema from LoadPackages, got %v", err)
+	}
+}
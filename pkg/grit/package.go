@@ -4,16 +4,31 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 
 	"gopkg.in/yaml.v3" // Add this import
 )
 
 type Package struct {
-	Name         string
-	Version      string
-	Dependencies []string
-	Hash         string
-	Path         string // Add this field to store the path to grit.yaml
+	Name         string                 `yaml:"name"`
+	Version      string                 `yaml:"version"`
+	Description  string                 `yaml:"description,omitempty"`
+	Dependencies []string               `yaml:"dependencies"`
+	Tags         []string               `yaml:"tags,omitempty"`
+	Owners       []string               `yaml:"owners,omitempty"`   // Users or teams responsible for the package, e.g. @org/team
+	Private      bool                   `yaml:"private,omitempty"`  // Private packages are not published
+	Metadata     map[string]interface{} `yaml:"metadata,omitempty"` // Free form data for tooling outside grit
+	Hash         string                 `yaml:"hash,omitempty"`
+	Path         string                 `yaml:"-"` // Path to the grit.yaml, set when the package is loaded
+	Type         string                 `yaml:"-"` // Set from the package_dir the package was found in
+}
+
+// ID returns the fully qualified type/name of the package
+func (p Package) ID() string {
+	if p.Type == "" {
+		return p.Name
+	}
+	return p.Type + "/" + p.Name
 }
 
 type PackageManager struct {
@@ -26,27 +41,154 @@ func NewPackageManager(root string) *PackageManager {
 	}
 }
 
+// LoadPackages discovers package grit.yaml files. Discovery is limited to the
+// package_dir of each configured type, falling back to the whole workspace when
+// no types are configured. Ignore files and build output directories are skipped.
 func (pm *PackageManager) LoadPackages() ([]Config, error) {
+	rootConfig, err := LoadConfig(filepath.Join(pm.workspaceRoot, "grit.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	return pm.LoadPackagesWithConfig(rootConfig)
+}
+
+// LoadPackagesWithConfig discovers packages using the types of the given root
+// config instead of the one on disk
+func (pm *PackageManager) LoadPackagesWithConfig(rootConfig *RootConfig) ([]Config, error) {
 	var packages []Config
 
-	err := filepath.Walk(pm.workspaceRoot, func(path string, info os.FileInfo, err error) error {
+	ignore, err := pm.loadIgnoreMatcher(rootConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ignore files apply to everything below their directory, so load them for
+	// each directory on the way down, including the ancestors of a type root
+	loadedIgnores := map[string]bool{".": true}
+	addIgnoreFiles := func(relDir string) error {
+		if loadedIgnores[relDir] {
+			return nil
+		}
+		loadedIgnores[relDir] = true
+		for _, name := range []string{".gitignore", IgnoreFileName} {
+			if err := ignore.AddFile(relDir, filepath.Join(pm.workspaceRoot, relDir, name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	for _, root := range pm.discoveryRoots(rootConfig) {
+		relRoot, err := filepath.Rel(pm.workspaceRoot, root)
 		if err != nil {
-			return err
+			return nil, err
 		}
-		//fmt.Printf("Checking %s\n", path)
-		if info.Name() == "grit.yaml" {
-			//fmt.Printf("Found %s\n", path)
+		var ancestors []string
+		for dir := filepath.Dir(relRoot); dir != "."; dir = filepath.Dir(dir) {
+			ancestors = append([]string{dir}, ancestors...)
+		}
+		for _, dir := range ancestors {
+			if err := addIgnoreFiles(dir); err != nil {
+				return nil, err
+			}
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+
+			relPath, err := filepath.Rel(pm.workspaceRoot, path)
+			if err != nil {
+				return err
+			}
+			if relPath != "." && ignore.Match(relPath, info.IsDir()) {
+				if info.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+
+			if info.IsDir() {
+				return addIgnoreFiles(relPath)
+			}
+
+			// The root grit.yaml is only a package too when a type's
+			// package_dir is the workspace root, as in a repository holding a
+			// single package
+			isRoot := relPath == "grit.yaml"
+			if info.Name() != "grit.yaml" || (isRoot && rootConfig.TypeForPath(".") == "") || seen[path] {
+				return nil
+			}
+			seen[path] = true
+
 			cfg, err := parsePackageFile(path)
 			if err != nil {
 				return fmt.Errorf("error parsing %s: %w", path, err)
 			}
-			//fmt.Printf("Loaded %s\n", cfg.Package.Name)
+			if isRoot && cfg.Package.Name == "" {
+				return nil
+			}
+			cfg.Package.Type = rootConfig.TypeForPath(filepath.Dir(relPath))
 			packages = append(packages, *cfg)
+			return nil
+		})
+		if err != nil {
+			return packages, err
 		}
-		return nil
-	})
+	}
+
+	if err := checkDuplicatePackages(packages); err != nil {
+		return packages, err
+	}
 
-	return packages, err
+	return packages, nil
+}
+
+// Directories walked during package discovery
+func (pm *PackageManager) discoveryRoots(rootConfig *RootConfig) []string {
+	var roots []string
+	for _, typeConfig := range rootConfig.Types {
+		if typeConfig.PackageDir == "" {
+			continue
+		}
+		root := filepath.Join(pm.workspaceRoot, typeConfig.PackageDir)
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			roots = append(roots, root)
+		}
+	}
+
+	if len(rootConfig.Types) == 0 {
+		roots = append(roots, pm.workspaceRoot)
+	}
+
+	sort.Strings(roots)
+	return roots
+}
+
+// Build the ignore matcher from the defaults, type output directories and the
+// ignore files found at the workspace root. Ignore files in subdirectories are
+// picked up while walking.
+func (pm *PackageManager) loadIgnoreMatcher(rootConfig *RootConfig) (*IgnoreMatcher, error) {
+	ignore := NewIgnoreMatcher()
+	ignore.AddPatterns("", DefaultIgnorePatterns)
+
+	for _, typeConfig := range rootConfig.Types {
+		for _, dir := range []string{typeConfig.BuildDir, typeConfig.CoverageDir} {
+			if dir != "" {
+				ignore.AddPatterns("", []string{"/" + filepath.ToSlash(filepath.Clean(dir)) + "/"})
+			}
+		}
+	}
+
+	for _, name := range []string{".gitignore", IgnoreFileName} {
+		if err := ignore.AddFile("", filepath.Join(pm.workspaceRoot, name)); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+	}
+
+	return ignore, nil
 }
 
 func parsePackageFile(path string) (*Config, error) {
@@ -61,6 +203,9 @@ func parsePackageFile(path string) (*Config, error) {
 	if err != nil {
 		return nil, err
 	}
+	if err := checkSchemaVersion(path, cfg.Version); err != nil {
+		return nil, err
+	}
 	//fmt.Printf("Parsed %s\n", cfg)
 	// Set the path to the grit.yaml file
 	cfg.Package.Path = path
@@ -71,8 +216,12 @@ func parsePackageFile(path string) (*Config, error) {
  * The root grit.yaml config file
  */
 type RootConfig struct {
-	Repo    RepoConfig            `yaml:"repo"`
-	Targets map[string]string     `yaml:"targets"`
+	Version int                   `yaml:"version,omitempty"`
+	Repo    RepoConfig            `yaml:"repo,omitempty"`
+	Include []string              `yaml:"include,omitempty"`
+	Targets map[string]string     `yaml:"targets,omitempty"`
+	Commit  CommitConfig          `yaml:"commit,omitempty"`
+	Hooks   HooksConfig           `yaml:"hooks,omitempty"`
 	Types   map[string]TypeConfig `yaml:"types"`
 }
 
@@ -80,28 +229,70 @@ type RootConfig struct {
  * The config for a package type
  */
 type TypeConfig struct {
-	PackageDir  string            `yaml:"package_dir"`
-	BuildDir    string            `yaml:"build_dir"`
-	CoverageDir string            `yaml:"coverage_dir"`
-	Targets     map[string]string `yaml:"targets"`
-	CanDependOn []string          `yaml:"can_depend_on"`
+	Extends     string            `yaml:"extends,omitempty"`
+	PackageDir  string            `yaml:"package_dir,omitempty"`
+	BuildDir    string            `yaml:"build_dir,omitempty"`
+	CoverageDir string            `yaml:"coverage_dir,omitempty"`
+	Targets     map[string]string `yaml:"targets,omitempty"`
+	CanDependOn []string          `yaml:"can_depend_on,omitempty"`
+	Owners      []string          `yaml:"owners,omitempty"` // Default owners of the packages of the type
 }
 
 /**
  * The grit.yaml config file for a package
  */
 type Config struct {
+	Version int                   `yaml:"version,omitempty"`
+	Package Package               `yaml:"package"`
 	Targets map[string]string     `yaml:"targets"`
-	Types   map[string]TypeConfig `yaml:"types"`
+	Types   map[string]TypeConfig `yaml:"types,omitempty"`
+}
+
+/**
+ * The root grit.yaml of a workspace whose root is also a package, as in a
+ * repository split out of a monorepo. Only used to order its keys.
+ */
+type RootPackageConfig struct {
+	Version int                   `yaml:"version,omitempty"`
+	Repo    RepoConfig            `yaml:"repo,omitempty"`
 	Package Package               `yaml:"package"`
+	Include []string              `yaml:"include,omitempty"`
+	Targets map[string]string     `yaml:"targets,omitempty"`
+	Commit  CommitConfig          `yaml:"commit,omitempty"`
+	Hooks   HooksConfig           `yaml:"hooks,omitempty"`
+	Types   map[string]TypeConfig `yaml:"types"`
+}
+
+/**
+ * The commit config section, used by grit commit
+ */
+type CommitConfig struct {
+	Conventional bool     `yaml:"conventional,omitempty"` // Write conventional commit messages with the package as scope
+	Types        []string `yaml:"types,omitempty"`        // Allowed commit types, the conventional commit types by default
+	Pattern      string   `yaml:"pattern,omitempty"`      // Regular expression the first line of a message must match
+}
+
+/**
+ * The hooks config section, used by the git hooks grit hooks install writes
+ */
+type HooksConfig struct {
+	PreCommit []string `yaml:"pre_commit,omitempty"` // Targets run on the packages with staged changes, lint by default
+}
+
+// PreCommitTargets returns the targets the pre-commit hook runs
+func (h HooksConfig) PreCommitTargets() []string {
+	if len(h.PreCommit) > 0 {
+		return h.PreCommit
+	}
+	return []string{"lint"}
 }
 
 /**
  * The repo config section
  */
 type RepoConfig struct {
-	URL     string `yaml:"url"`
-	Name    string `yaml:"name"`
-	License string `yaml:"license"`
-	Owner   string `yaml:"owner"`
+	URL     string `yaml:"url,omitempty"`
+	Name    string `yaml:"name,omitempty"`
+	License string `yaml:"license,omitempty"`
+	Owner   string `yaml:"owner,omitempty"`
 }
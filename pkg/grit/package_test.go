@@ -0,0 +1,104 @@
+package grit_test
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func packageNames(configs []grit.Config) []string {
+	var names []string
+	for _, cfg := range configs {
+		names = append(names, cfg.Package.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestLoadPackagesDiscovery(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "grit.yaml"), `
+types:
+  lib:
+    package_dir: packages/lib
+    build_dir: packages/lib/out
+`)
+	writeFile(t, filepath.Join(root, "packages/lib/foo/grit.yaml"), "package:\n  name: foo\n")
+	writeFile(t, filepath.Join(root, "packages/lib/foo/node_modules/dep/grit.yaml"), "package:\n  name: vendored\n")
+	writeFile(t, filepath.Join(root, "packages/lib/out/foo/grit.yaml"), "package:\n  name: output\n")
+	writeFile(t, filepath.Join(root, "packages/lib/tmp/grit.yaml"), "package:\n  name: gitignored\n")
+	writeFile(t, filepath.Join(root, "packages/lib/bar/grit.yaml"), "package:\n  name: bar\n")
+	writeFile(t, filepath.Join(root, "packages/lib/bar/fixtures/grit.yaml"), "package:\n  name: fixture\n")
+	writeFile(t, filepath.Join(root, "templates/grit.yaml"), "package:\n  name: template\n")
+	writeFile(t, filepath.Join(root, ".gitignore"), "tmp/\n")
+	writeFile(t, filepath.Join(root, "packages/lib/bar/.gritignore"), "fixtures\n")
+
+	packages, err := grit.NewPackageManager(root).LoadPackages()
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	got := packageNames(packages)
+	want := []string{"bar", "foo"}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("LoadPackages() = %v, want %v", got, want)
+	}
+}
+
+func TestLoadPackagesWithoutTypes(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "grit.yaml"), "repo:\n  name: test\n")
+	writeFile(t, filepath.Join(root, "a/grit.yaml"), "package:\n  name: a\n")
+	writeFile(t, filepath.Join(root, ".git/grit.yaml"), "package:\n  name: git\n")
+
+	packages, err := grit.NewPackageManager(root).LoadPackages()
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+
+	if got := packageNames(packages); len(got) != 1 || got[0] != "a" {
+		t.Errorf("LoadPackages() = %v, want [a]", got)
+	}
+}
+
+func TestLoadPackagesRootPackage(t *testing.T) {
+	root := t.TempDir()
+	writeFile(t, filepath.Join(root, "grit.yaml"), "package:\n  name: utils\ntypes:\n  lib:\n    package_dir: .\n")
+	writeFile(t, filepath.Join(root, "examples/grit.yaml"), "package:\n  name: example\n")
+
+	packages, err := grit.NewPackageManager(root).LoadPackages()
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if got := packageNames(packages); len(got) != 2 || got[0] != "example" || got[1] != "utils" {
+		t.Errorf("LoadPackages() = %v, want [example utils]", got)
+	}
+	for _, cfg := range packages {
+		if cfg.Package.Type != "lib" {
+			t.Errorf("%s has type %q, want lib", cfg.Package.Name, cfg.Package.Type)
+		}
+	}
+
+	// Without a type at the root, the root grit.yaml is only the root config
+	writeFile(t, filepath.Join(root, "grit.yaml"), "package:\n  name: utils\ntypes:\n  lib:\n    package_dir: examples\n")
+	packages, err = grit.NewPackageManager(root).LoadPackages()
+	if err != nil {
+		t.Fatalf("LoadPackages() error = %v", err)
+	}
+	if got := packageNames(packages); len(got) != 1 || got[0] != "example" {
+		t.Errorf("LoadPackages() = %v, want [example]", got)
+	}
+}
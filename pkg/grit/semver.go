@@ -0,0 +1,166 @@
+package grit
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidVersion = errors.New("invalid semantic version")
+
+// Version bump levels
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+)
+
+// The grammar from semver.org, without a leading v
+var semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+	`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+
+// Semver is a semantic version as described by https://semver.org
+type Semver struct {
+	Major      int
+	Minor      int
+	Patch      int
+	Prerelease string // Dot separated identifiers after the -, e.g. rc.1
+	Build      string // Build metadata after the +, ignored for precedence
+}
+
+// ParseSemver parses a version such as 1.2.3, 1.0.0-rc.1 or 1.0.0+build.5
+func ParseSemver(s string) (Semver, error) {
+	m := semverPattern.FindStringSubmatch(s)
+	if m == nil {
+		return Semver{}, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+	}
+
+	var v Semver
+	for i, field := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return Semver{}, fmt.Errorf("%w: %q", ErrInvalidVersion, s)
+		}
+		*field = n
+	}
+	v.Prerelease, v.Build = m[4], m[5]
+	return v, nil
+}
+
+func (v Semver) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0 or 1 when v has lower, equal or higher precedence than
+// other. A prerelease has lower precedence than the release it precedes.
+func (v Semver) Compare(other Semver) int {
+	for _, pair := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if pair[0] != pair[1] {
+			return compareInts(pair[0], pair[1])
+		}
+	}
+
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(other.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := comparePrereleaseIdentifiers(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInts(len(a), len(b))
+}
+
+// Numeric identifiers compare numerically and sort before alphanumeric ones
+func comparePrereleaseIdentifiers(a string, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInts(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInts(a int, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Bump returns the next major, minor or patch version. A prerelease is bumped
+// to the release it precedes when that is at the requested level, so
+// 2.0.0-rc.1 becomes 2.0.0 for a major bump. Build metadata is dropped.
+func (v Semver) Bump(level string) (Semver, error) {
+	next := Semver{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+	pre := v.Prerelease != ""
+	switch level {
+	case BumpMajor:
+		if !pre || v.Minor != 0 || v.Patch != 0 {
+			next = Semver{Major: v.Major + 1}
+		}
+	case BumpMinor:
+		if !pre || v.Patch != 0 {
+			next = Semver{Major: v.Major, Minor: v.Minor + 1}
+		}
+	case BumpPatch:
+		if !pre {
+			next.Patch++
+		}
+	default:
+		return Semver{}, fmt.Errorf("unknown bump level %q, expected major, minor or patch", level)
+	}
+	return next, nil
+}
+
+// NextVersion applies a bump level or an explicit version to the current
+// version of a package. An explicit version must be higher than the current one.
+func NextVersion(current string, spec string) (string, error) {
+	v, err := ParseSemver(current)
+	if err != nil {
+		return "", err
+	}
+
+	switch spec {
+	case BumpMajor, BumpMinor, BumpPatch:
+		next, err := v.Bump(spec)
+		if err != nil {
+			return "", err
+		}
+		return next.String(), nil
+	}
+
+	next, err := ParseSemver(spec)
+	if err != nil {
+		return "", fmt.Errorf("%w, expected major, minor, patch or x.y.z", err)
+	}
+	if next.Compare(v) <= 0 {
+		return "", fmt.Errorf("version %s is not higher than %s", spec, current)
+	}
+	return next.String(), nil
+}
@@ -0,0 +1,82 @@
+package grit_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestParseSemver(t *testing.T) {
+	for _, s := range []string{"0.1.0", "1.2.3", "1.0.0-rc.1", "1.0.0-alpha+build.5", "10.20.30+meta"} {
+		v, err := grit.ParseSemver(s)
+		if err != nil {
+			t.Errorf("%s: %v", s, err)
+			continue
+		}
+		if v.String() != s {
+			t.Errorf("%s: round trip gave %s", s, v.String())
+		}
+	}
+
+	for _, s := range []string{"", "1.2", "v1.2.3", "01.2.3", "1.2.3-", "1.2.3-01", "1.2.3+", "1.2.3.4"} {
+		if _, err := grit.ParseSemver(s); !errors.Is(err, grit.ErrInvalidVersion) {
+			t.Errorf("%q: expected ErrInvalidVersion, got %v", s, err)
+		}
+	}
+}
+
+func TestSemverCompare(t *testing.T) {
+	// In increasing order of precedence, from semver.org
+	ordered := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2",
+		"1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := 0; i < len(ordered)-1; i++ {
+		a, _ := grit.ParseSemver(ordered[i])
+		b, _ := grit.ParseSemver(ordered[i+1])
+		if a.Compare(b) != -1 || b.Compare(a) != 1 {
+			t.Errorf("expected %s < %s", a, b)
+		}
+	}
+
+	a, _ := grit.ParseSemver("1.0.0+a")
+	b, _ := grit.ParseSemver("1.0.0+b")
+	if a.Compare(b) != 0 {
+		t.Errorf("build metadata should not affect precedence")
+	}
+}
+
+func TestNextVersion(t *testing.T) {
+	tests := []struct {
+		current string
+		spec    string
+		want    string
+	}{
+		{"0.1.0", "patch", "0.1.1"},
+		{"0.1.5", "minor", "0.2.0"},
+		{"0.1.5", "major", "1.0.0"},
+		{"1.2.3+build", "patch", "1.2.4"},
+		{"2.0.0-rc.1", "major", "2.0.0"},
+		{"2.1.0-rc.1", "major", "3.0.0"},
+		{"2.1.0-rc.1", "minor", "2.1.0"},
+		{"2.1.1-rc.1", "minor", "2.2.0"},
+		{"2.1.1-rc.1", "patch", "2.1.1"},
+		{"1.0.0", "1.5.0-beta", "1.5.0-beta"},
+	}
+	for _, tt := range tests {
+		got, err := grit.NextVersion(tt.current, tt.spec)
+		if err != nil {
+			t.Errorf("%s %s: %v", tt.current, tt.spec, err)
+		} else if got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.current, tt.spec, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{"1.0.0", "0.9.0", "1.0.0-rc.1", "huge"} {
+		if _, err := grit.NextVersion("1.0.0", spec); err == nil {
+			t.Errorf("expected %s to be rejected after 1.0.0", spec)
+		}
+	}
+	if _, err := grit.NextVersion("latest", "patch"); !errors.Is(err, grit.ErrInvalidVersion) {
+		t.Errorf("expected an invalid current version to be rejected, got %v", err)
+	}
+}
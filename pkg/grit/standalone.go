@@ -0,0 +1,61 @@
+package grit
+
+import (
+	"fmt"
+	"strings"
+)
+
+// StandaloneConfig turns the grit.yaml of a package into the root config of a
+// repository that holds only the package. Every target the package resolves
+// through its type and the root config is copied in, the repo section is named
+// after the package and the package section is kept, with the owners of its
+// type filled in and without its dependencies, which point into the monorepo.
+// The type of the package is defined with the repository root as its
+// package_dir, so the root grit.yaml is loaded as the package. Returns a note
+// for each change that loses information.
+func (c *RootConfig) StandaloneConfig(doc *Document, cfg Config) ([]string, error) {
+	var notes []string
+
+	repo := RepoConfig{Name: cfg.Package.Name, License: c.Repo.License, Owner: c.Repo.Owner}
+	if err := doc.Set([]string{"repo"}, repo); err != nil {
+		return nil, err
+	}
+
+	targets := make(map[string]string)
+	for _, target := range c.ResolveTargets(cfg) {
+		targets[target.Name] = target.Command
+	}
+	if len(targets) > 0 {
+		if err := doc.Set([]string{"targets"}, targets); err != nil {
+			return nil, err
+		}
+	}
+
+	// Keep the build and coverage directories the ${type.*} variables of the
+	// targets refer to
+	typeName := cfg.Package.Type
+	if typeName == "" {
+		typeName = "package"
+	}
+	typeConfig := c.Types[cfg.Package.Type]
+	standaloneType := TypeConfig{PackageDir: ".", BuildDir: typeConfig.BuildDir, CoverageDir: typeConfig.CoverageDir}
+	if err := doc.Set([]string{"types"}, map[string]TypeConfig{typeName: standaloneType}); err != nil {
+		return nil, err
+	}
+
+	if len(cfg.Package.Owners) == 0 && len(c.Types[cfg.Package.Type].Owners) > 0 {
+		if err := doc.Set([]string{"package", "owners"}, c.Types[cfg.Package.Type].Owners); err != nil {
+			return nil, err
+		}
+	}
+	if len(cfg.Package.Dependencies) > 0 {
+		doc.Delete("package", "dependencies")
+		notes = append(notes, fmt.Sprintf("removed the dependencies on %s", strings.Join(cfg.Package.Dependencies, ", ")))
+	}
+
+	if err := doc.Set([]string{"version"}, SchemaVersion); err != nil {
+		return nil, err
+	}
+	doc.Normalize(RootPackageConfig{})
+	return notes, nil
+}
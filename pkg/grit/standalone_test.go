@@ -0,0 +1,73 @@
+package grit_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestStandaloneConfig(t *testing.T) {
+	root := &grit.RootConfig{
+		Repo:    grit.RepoConfig{Name: "mono", Owner: "org", License: "MIT"},
+		Targets: map[string]string{"lint": "golangci-lint run"},
+		Types: map[string]grit.TypeConfig{"lib": {
+			BuildDir: "build/lib",
+			Targets:  map[string]string{"build": "go build -o ${type.build_dir}/${package.name}"},
+			Owners:   []string{"@org/libs"},
+		}},
+	}
+	data := "# The utils package\npackage:\n  name: utils\n  version: 1.2.0\n  dependencies: [core]\ntargets:\n  test: go test ./...\n"
+	doc, err := grit.ParseDocument("grit.yaml", []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var cfg grit.Config
+	if err := doc.Decode(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	cfg.Package.Type = "lib"
+
+	notes, err := root.StandaloneConfig(doc, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(notes) != 1 || !strings.Contains(notes[0], "core") {
+		t.Errorf("notes = %v", notes)
+	}
+
+	var standalone grit.RootConfig
+	if err := doc.Decode(&standalone); err != nil {
+		t.Fatal(err)
+	}
+	if standalone.Version != grit.SchemaVersion || standalone.Repo != (grit.RepoConfig{Name: "utils", Owner: "org", License: "MIT"}) {
+		t.Errorf("standalone config = %+v", standalone)
+	}
+	wantTargets := map[string]string{"build": "go build -o ${type.build_dir}/${package.name}", "lint": "golangci-lint run", "test": "go test ./..."}
+	if !reflect.DeepEqual(standalone.Targets, wantTargets) {
+		t.Errorf("targets = %v, want %v", standalone.Targets, wantTargets)
+	}
+	wantTypes := map[string]grit.TypeConfig{"lib": {PackageDir: ".", BuildDir: "build/lib"}}
+	if !reflect.DeepEqual(standalone.Types, wantTypes) {
+		t.Errorf("types = %+v, want %+v", standalone.Types, wantTypes)
+	}
+
+	var pkg grit.Config
+	if err := doc.Decode(&pkg); err != nil {
+		t.Fatal(err)
+	}
+	if pkg.Package.Version != "1.2.0" || len(pkg.Package.Dependencies) != 0 || !reflect.DeepEqual(pkg.Package.Owners, []string{"@org/libs"}) {
+		t.Errorf("package = %+v", pkg.Package)
+	}
+	out, err := doc.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(string(out), "version: 1\n") || !strings.Contains(string(out), "# The utils package\npackage:") {
+		t.Errorf("expected the version first and the comment kept, got\n%s", out)
+	}
+	if repo, pkgKey, types := strings.Index(string(out), "\nrepo:"), strings.Index(string(out), "\npackage:"), strings.Index(string(out), "\ntypes:"); repo > pkgKey || pkgKey > types {
+		t.Errorf("expected repo, package and types in that order, got\n%s", out)
+	}
+}
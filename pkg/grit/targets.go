@@ -0,0 +1,141 @@
+package grit
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// TargetSource is the config level a target was resolved from
+type TargetSource string
+
+const (
+	TargetSourcePackage TargetSource = "package"
+	TargetSourceType    TargetSource = "type"
+	TargetSourceRoot    TargetSource = "root"
+)
+
+// ResolvedTarget is a target command together with the level that defined it
+type ResolvedTarget struct {
+	Name    string
+	Command string
+	Source  TargetSource
+}
+
+// ResolveTarget looks a target up on the package, then on its type and finally
+// in the root config. Empty commands are treated as not defined, so a level can
+// list a target without shadowing the one it inherits.
+func (c *RootConfig) ResolveTarget(cfg Config, target string) (ResolvedTarget, bool) {
+	if command := cfg.Targets[target]; command != "" {
+		return ResolvedTarget{Name: target, Command: command, Source: TargetSourcePackage}, true
+	}
+	if typeConfig, ok := c.Types[cfg.Package.Type]; ok {
+		if command := typeConfig.Targets[target]; command != "" {
+			return ResolvedTarget{Name: target, Command: command, Source: TargetSourceType}, true
+		}
+	}
+	if command := c.Targets[target]; command != "" {
+		return ResolvedTarget{Name: target, Command: command, Source: TargetSourceRoot}, true
+	}
+	return ResolvedTarget{}, false
+}
+
+// ResolveTargets returns every target available to a package
+func (c *RootConfig) ResolveTargets(cfg Config) []ResolvedTarget {
+	names := make(map[string]bool)
+	for name := range c.Targets {
+		names[name] = true
+	}
+	for name := range c.Types[cfg.Package.Type].Targets {
+		names[name] = true
+	}
+	for name := range cfg.Targets {
+		names[name] = true
+	}
+
+	var targets []ResolvedTarget
+	for name := range names {
+		if target, ok := c.ResolveTarget(cfg, name); ok {
+			targets = append(targets, target)
+		}
+	}
+	sort.Slice(targets, func(i, j int) bool {
+		return targets[i].Name < targets[j].Name
+	})
+	return targets
+}
+
+// TargetVars returns the variables available for interpolation in the target
+// commands of a package. Directories are absolute so commands work regardless of
+// the directory they run in. Directories the type doesn't set are left out, so a
+// command using them fails instead of expanding to a path below /.
+func (c *RootConfig) TargetVars(cfg Config, workspaceRoot string) map[string]string {
+	vars := map[string]string{
+		"workspace.root":  workspaceRoot,
+		"repo.name":       c.Repo.Name,
+		"package.name":    cfg.Package.Name,
+		"package.id":      cfg.Package.ID(),
+		"package.version": cfg.Package.Version,
+		"package.type":    cfg.Package.Type,
+		"package.dir":     filepath.Dir(cfg.Package.Path),
+		"type.name":       cfg.Package.Type,
+	}
+
+	typeConfig := c.Types[cfg.Package.Type]
+	for name, dir := range map[string]string{
+		"type.package_dir":  typeConfig.PackageDir,
+		"type.build_dir":    typeConfig.BuildDir,
+		"type.coverage_dir": typeConfig.CoverageDir,
+	} {
+		if dir == "" {
+			continue
+		}
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workspaceRoot, dir)
+		}
+		vars[name] = dir
+	}
+	return vars
+}
+
+var interpolationPattern = regexp.MustCompile(`\$\$\{|\$\{([^}]*)\}`)
+
+// Prefixes of the variables grit defines. References to other names, like
+// ${HOME} or ${1}, are shell parameters.
+var interpolationNamespaces = []string{"package.", "type.", "workspace.", "repo."}
+
+// Interpolate replaces ${name} references in a command. ${env.X} reads the
+// environment variable X, and $${ escapes a literal ${. References outside the
+// package, type, workspace and repo namespaces, like ${HOME}, and plain $VAR
+// references are left for the shell.
+func Interpolate(command string, vars map[string]string) (string, error) {
+	var unknown []string
+	result := interpolationPattern.ReplaceAllStringFunc(command, func(match string) string {
+		if match == "$${" {
+			return "${"
+		}
+
+		name := strings.TrimSpace(match[2 : len(match)-1])
+		if strings.HasPrefix(name, "env.") {
+			return os.Getenv(strings.TrimPrefix(name, "env."))
+		}
+		if value, ok := vars[name]; ok {
+			return value
+		}
+		for _, namespace := range interpolationNamespaces {
+			if strings.HasPrefix(name, namespace) {
+				unknown = append(unknown, name)
+				break
+			}
+		}
+		return match
+	})
+
+	if len(unknown) > 0 {
+		return "", fmt.Errorf("undefined variable(s) in command: %s", strings.Join(unknown, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,90 @@
+package grit_test
+
+import (
+	"testing"
+
+	"github.com/weslien/grit/pkg/grit"
+)
+
+func TestResolveTarget(t *testing.T) {
+	root := &grit.RootConfig{
+		Targets: map[string]string{"build": "root-build", "lint": "root-lint", "test": "root-test"},
+		Types: map[string]grit.TypeConfig{
+			"lib": {Targets: map[string]string{"build": "lib-build", "test": ""}},
+		},
+	}
+	cfg := grit.Config{
+		Package: grit.Package{Name: "foo", Type: "lib"},
+		Targets: map[string]string{"build": "pkg-build", "lint": ""},
+	}
+
+	tests := []struct {
+		target     string
+		wantCmd    string
+		wantSource grit.TargetSource
+		wantOK     bool
+	}{
+		{target: "build", wantCmd: "pkg-build", wantSource: grit.TargetSourcePackage, wantOK: true},
+		{target: "lint", wantCmd: "root-lint", wantSource: grit.TargetSourceRoot, wantOK: true},
+		{target: "test", wantCmd: "root-test", wantSource: grit.TargetSourceRoot, wantOK: true},
+		{target: "publish", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			got, ok := root.ResolveTarget(cfg, tt.target)
+			if ok != tt.wantOK {
+				t.Fatalf("ResolveTarget(%q) ok = %v, want %v", tt.target, ok, tt.wantOK)
+			}
+			if got.Command != tt.wantCmd || got.Source != tt.wantSource {
+				t.Errorf("ResolveTarget(%q) = %+v", tt.target, got)
+			}
+		})
+	}
+
+	cfg.Targets = nil
+	if got, _ := root.ResolveTarget(cfg, "build"); got.Source != grit.TargetSourceType {
+		t.Errorf("ResolveTarget(build) source = %s, want type", got.Source)
+	}
+	if got := root.ResolveTargets(cfg); len(got) != 3 {
+		t.Errorf("ResolveTargets() = %+v, want 3 targets", got)
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("GRIT_TEST_VAR", "from-env")
+
+	root := &grit.RootConfig{Types: map[string]grit.TypeConfig{
+		"lib": {BuildDir: "build/lib"},
+	}}
+	cfg := grit.Config{Package: grit.Package{Name: "foo", Type: "lib", Path: "/ws/packages/lib/foo/grit.yaml"}}
+	vars := root.TargetVars(cfg, "/ws")
+
+	tests := []struct {
+		command string
+		want    string
+		wantErr bool
+	}{
+		{command: "go build -o ${type.build_dir}/${package.name}", want: "go build -o /ws/build/lib/foo"},
+		{command: "cd ${package.dir} && echo ${ package.id }", want: "cd /ws/packages/lib/foo && echo lib/foo"},
+		{command: "echo ${env.GRIT_TEST_VAR} $HOME", want: "echo from-env $HOME"},
+		{command: "echo $${package.name}", want: "echo ${package.name}"},
+		{command: "cd ${HOME} && echo ${1:-default} ${GOPATH}", want: "cd ${HOME} && echo ${1:-default} ${GOPATH}"},
+		{command: "echo ${repo.nope}", wantErr: true},
+		{command: "echo ${package.nope}", wantErr: true},
+		// The type sets no coverage_dir, which must not expand to /foo
+		{command: "rm -rf ${type.coverage_dir}/${package.name}", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			got, err := grit.Interpolate(tt.command, vars)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Interpolate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Interpolate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}